@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"resticm/internal/config"
+	"resticm/internal/restic"
 )
 
 var backendCmd = &cobra.Command{
@@ -94,12 +95,9 @@ func showBackend() error {
 
 	cfg := GetConfig()
 	if cfg != nil {
-		if activeBackend == "primary" {
+		if repo, err := cfg.Resolve(activeBackend); err == nil {
 			fmt.Printf("  Repository:  ")
-			_, _ = cyan.Println(cfg.Repository)
-		} else if backend, ok := cfg.Backends[activeBackend]; ok {
-			fmt.Printf("  Repository:  ")
-			_, _ = cyan.Println(backend.Repository)
+			_, _ = cyan.Println(repo.URL)
 		}
 	}
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
@@ -164,3 +162,93 @@ func listBackends() error {
 
 	return nil
 }
+
+// newExecutor builds the restic executor of a resolved repository with its
+// credentials, environment, extended options, cache directory and limits
+func newExecutor(repo *config.Repository) *restic.Executor {
+	executor := restic.NewExecutor(repo.URL, repo.Password)
+	executor.SetAWSCredentials(repo.AWSAccessKeyID, repo.AWSSecretAccessKey)
+	executor.SetEnv(repo.Env)
+	executor.Options = append(executor.Options, repo.Options...)
+	executor.CacheDir = repo.CacheDir
+	executor.LimitUpload = repo.LimitUpload
+	executor.LimitDownload = repo.LimitDownload
+	executor.Verbose = IsVerbose()
+	return executor
+}
+
+// copyOptions returns the options copying snapshots from one repository to
+// another
+func copyOptions(from, to *config.Repository) restic.CopyOptions {
+	return restic.CopyOptions{
+		FromRepository:         from.URL,
+		FromPassword:           from.Password,
+		FromAWSAccessKeyID:     from.AWSAccessKeyID,
+		FromAWSSecretAccessKey: from.AWSSecretAccessKey,
+		FromEnv:                from.Env,
+		FromOptions:            from.Options,
+		ToRepository:           to.URL,
+		ToPassword:             to.Password,
+		ToAWSAccessKeyID:       to.AWSAccessKeyID,
+		ToAWSSecretAccessKey:   to.AWSSecretAccessKey,
+	}
+}
+
+// forgetOptions returns the options applying the retention policy of a
+// repository, to the snapshots of hostname (all hosts when empty)
+func forgetOptions(repo *config.Repository, hostname string) restic.ForgetOptions {
+	return restic.ForgetOptions{
+		KeepWithin:  repo.Retention.KeepWithin,
+		KeepHourly:  repo.Retention.KeepHourly,
+		KeepDaily:   repo.Retention.KeepDaily,
+		KeepWeekly:  repo.Retention.KeepWeekly,
+		KeepMonthly: repo.Retention.KeepMonthly,
+		KeepYearly:  repo.Retention.KeepYearly,
+		GroupBy:     repo.Retention.GroupBy,
+		Hostname:    hostname,
+	}
+}
+
+// addBackendsFlag registers the --backends selection of a command
+func addBackendsFlag(cmd *cobra.Command) {
+	cmd.Flags().String("backends", "", "Backends to operate on: names, groups, primary, copy or all, comma separated ('!name' excludes)")
+}
+
+// commandBackends returns the backends a command operates on: the --backends
+// selection when set, else the defaults of the command
+func commandBackends(cmd *cobra.Command, cfg *config.Config, defaults func() ([]string, error)) ([]string, error) {
+	if expr, _ := cmd.Flags().GetString("backends"); expr != "" {
+		return cfg.SelectBackends(expr)
+	}
+	return defaults()
+}
+
+// activeBackends returns the active backend; with all, the primary and the
+// copy backends instead
+func activeBackends(cfg *config.Config, all bool) ([]string, error) {
+	if all {
+		return cfg.SelectBackends(config.SelectPrimary + "," + config.SelectCopy)
+	}
+	activeBackend, _ := config.GetActiveBackend()
+	if activeBackend == "" || activeBackend == config.SelectPrimary {
+		return []string{config.SelectPrimary}, nil
+	}
+	if _, ok := cfg.Backends[activeBackend]; !ok {
+		return nil, fmt.Errorf("backend '%s' not found", activeBackend)
+	}
+	return []string{activeBackend}, nil
+}
+
+// syncedBackends returns the backends that maintenance commands keep in
+// sync: the active backend when one is selected, else the primary and,
+// unless primaryOnly, the copy backends
+func syncedBackends(cfg *config.Config, primaryOnly bool) ([]string, error) {
+	activeBackend, _ := config.GetActiveBackend()
+	if activeBackend != "" && activeBackend != config.SelectPrimary {
+		return activeBackends(cfg, false)
+	}
+	if primaryOnly {
+		return []string{config.SelectPrimary}, nil
+	}
+	return activeBackends(cfg, true)
+}
@@ -7,10 +7,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"resticm/internal/config"
 	"resticm/internal/hooks"
 	"resticm/internal/restic"
-	"resticm/internal/security"
 )
 
 var backupCmd = &cobra.Command{
@@ -70,39 +68,18 @@ func runBackup(cmd *cobra.Command) (err error) {
 	}()
 
 	// Acquire lock
-	lock := security.NewLock("")
-	if err = lock.Acquire(); err != nil {
+	lock, err := acquireLock()
+	if err != nil {
 		return err
 	}
 	defer func() { _ = lock.Release() }()
 
-	// Get active backend
-	activeBackend, _ := config.GetActiveBackend()
-
-	var repo, password string
-	var awsKey, awsSecret string
-
-	if activeBackend == "" || activeBackend == "primary" {
-		repo = cfg.Repository
-		password = cfg.GetPassword()
-		awsKey = cfg.GetAWSAccessKeyID()
-		awsSecret = cfg.GetAWSSecretAccessKey()
-	} else {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
-		}
-		repo = backend.Repository
-		password = backend.Password
-		awsKey = backend.AWSAccessKeyID
-		awsSecret = backend.AWSSecretAccessKey
+	repo, err := cfg.ActiveRepository()
+	if err != nil {
+		return err
 	}
-
-	// Create executor
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+	executor := newExecutor(repo)
 	executor.DryRun = IsDryRun()
-	executor.Verbose = IsVerbose()
 
 	// Check restic is installed
 	if err := restic.CheckResticInstalled(); err != nil {
@@ -119,6 +96,7 @@ func runBackup(cmd *cobra.Command) (err error) {
 	if extraTag != "" {
 		tags = append(tags, extraTag)
 	}
+	recordTags(tags)
 
 	// Get hostname
 	hostname, _ := os.Hostname()
@@ -134,19 +112,14 @@ func runBackup(cmd *cobra.Command) (err error) {
 	// Setup hooks
 	var hookRunner *hooks.Runner
 	if !noHooks {
-		hookRunner = hooks.NewRunner()
-		hookRunner.PreBackup = cfg.Hooks.PreBackup
-		hookRunner.PostBackup = cfg.Hooks.PostBackup
-		hookRunner.OnError = cfg.Hooks.OnError
-		hookRunner.OnSuccess = cfg.Hooks.OnSuccess
-		hookRunner.DryRun = IsDryRun()
-		hookRunner.Verbose = IsVerbose()
-		hookRunner.Logger = GetLogger()
+		hookRunner = newHookRunner()
 	}
 
 	// Run pre-backup hook
 	if !noHooks && hookRunner != nil {
+		stepStart := time.Now()
 		if err := hookRunner.RunPreBackup(); err != nil {
+			recordStep("pre_backup_hook", "", stepStart, err)
 			PrintError("Pre-backup hook failed: %v", err)
 			_ = hookRunner.RunOnError(err)
 			_ = notifier.NotifyError(
@@ -155,7 +128,7 @@ func runBackup(cmd *cobra.Command) (err error) {
 				err,
 				map[string]string{
 					"host":       hostname,
-					"repository": repo,
+					"repository": repo.URL,
 				},
 			)
 			return err
@@ -176,7 +149,18 @@ func runBackup(cmd *cobra.Command) (err error) {
 		Hostname:        hostname,
 	}
 
-	if err := executor.Backup(opts); err != nil {
+	backendName := repo.Name
+
+	var summary *restic.BackupSummary
+	err = runStep(hookRunner, "backup", backendName, func() (err error) {
+		summary, err = backupAll(executor, backendName, opts)
+		return err
+	})
+	recordBackup(summary)
+	if stepSkipped(err) {
+		return nil
+	}
+	if err != nil {
 		PrintError("Backup failed: %v", err)
 		if !noHooks && hookRunner != nil {
 			_ = hookRunner.RunPostBackup(false, err)
@@ -188,7 +172,7 @@ func runBackup(cmd *cobra.Command) (err error) {
 			err,
 			map[string]string{
 				"host":       hostname,
-				"repository": repo,
+				"repository": repo.URL,
 			},
 		)
 		return err
@@ -211,7 +195,7 @@ func runBackup(cmd *cobra.Command) (err error) {
 		fmt.Sprintf("resticm backup completed successfully on %s", hostname),
 		map[string]string{
 			"host":       hostname,
-			"repository": repo,
+			"repository": repo.URL,
 		},
 	)
 	return nil
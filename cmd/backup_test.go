@@ -1,11 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"resticm/internal/config"
+	"resticm/internal/fssnap"
+	"resticm/internal/restic"
 )
 
 // TestBackupCommandHooksExecution tests that hooks are executed in the correct order
@@ -59,10 +69,10 @@ exit 0
 	// Set up test configuration
 	cfg = &config.Config{
 		Hooks: config.HookConfig{
-			PreBackup:  preBackupHook,
-			PostBackup: postBackupHook,
-			OnSuccess:  onSuccessHook,
-			OnError:    onErrorHook,
+			PreBackup:  config.Hook{Path: preBackupHook},
+			PostBackup: config.Hook{Path: postBackupHook},
+			OnSuccess:  config.Hook{Path: onSuccessHook},
+			OnError:    config.Hook{Path: onErrorHook},
 		},
 	}
 
@@ -166,3 +176,260 @@ exit 0
 		t.Errorf("Hook file is not executable")
 	}
 }
+
+// TestBackupAllDatabaseFailure tests that a failing database dump does not
+// stop the other sources and is reported
+func TestBackupAllDatabaseFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	snapshots := filepath.Join(tmpDir, "snapshots.log")
+
+	// Fake restic recording the stdin file name of each snapshot it saves
+	fakeRestic := `#!/bin/sh
+name=directories
+while [ $# -gt 0 ]; do
+    [ "$1" = "--stdin-filename" ] && name=$2
+    shift
+done
+[ "$name" != directories ] && cat > /dev/null
+echo "$name" >> ` + snapshots + `
+echo "snapshot 1a2b3c4d saved"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "restic"), []byte(fakeRestic), 0755); err != nil {
+		t.Fatalf("Failed to create fake restic: %v", err)
+	}
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	goodDump := filepath.Join(tmpDir, "good-dump")
+	if err := os.WriteFile(goodDump, []byte("#!/bin/sh\necho 'CREATE TABLE t ();'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	badDump := filepath.Join(tmpDir, "bad-dump")
+	if err := os.WriteFile(badDump, []byte("#!/bin/sh\necho 'connection refused' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = &config.Config{
+		Sources: config.SourcesConfig{
+			Databases: []config.DatabaseSource{
+				{Name: "broken", Type: "postgres", Database: "broken", Command: badDump},
+				{Name: "app", Type: "postgres", Database: "app", Command: goodDump},
+			},
+			Commands: []config.CommandSource{
+				{Name: "ldap", Command: []string{"slapcat"}, Filename: "ldap.ldif"},
+			},
+		},
+	}
+	defer func() { cfg = nil }()
+
+	executor := restic.NewExecutor("/tmp/repo", "secret")
+	executor.Stdout = io.Discard
+	executor.Stderr = io.Discard
+
+	summary, err := backupAll(executor, "primary", restic.BackupOptions{Directories: []string{"/etc"}})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("backupAll() error = %v, want the failed dump", err)
+	}
+	if summary == nil || summary.SnapshotID != "1a2b3c4d" {
+		t.Errorf("summary = %+v, want the directory backup summary", summary)
+	}
+
+	data, _ := os.ReadFile(snapshots)
+	if got, want := string(data), "directories\napp.sql\nldap.ldif\n"; got != want {
+		t.Errorf("saved snapshots = %q, want %q", got, want)
+	}
+}
+
+// TestBackupDockerResumesContainers tests that paused containers are resumed
+// when a volume backup fails
+func TestBackupDockerResumesContainers(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fakeRestic := "#!/bin/sh\necho 'Fatal: unable to open repository' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "restic"), []byte(fakeRestic), 0755); err != nil {
+		t.Fatalf("Failed to create fake restic: %v", err)
+	}
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var mu sync.Mutex
+	var calls []string
+	socket := filepath.Join(tmpDir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/containers/json" {
+			_, _ = w.Write([]byte(`[{"Id":"a1","Names":["/db"],"State":"running",
+				"Labels":{"resticm.enable":"true","resticm.mode":"pause"},
+				"Mounts":[{"Type":"volume","Name":"pgdata","Source":"/var/lib/docker/volumes/pgdata/_data"}]}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	cfg = &config.Config{Sources: config.SourcesConfig{
+		Docker: config.DockerSource{Enabled: true, Socket: socket},
+	}}
+	defer func() { cfg = nil }()
+
+	executor := restic.NewExecutor("/tmp/repo", "secret")
+	executor.Stdout = io.Discard
+	executor.Stderr = io.Discard
+
+	if _, err := backupAll(executor, "primary", restic.BackupOptions{}); err == nil {
+		t.Error("expected the failed volume backup")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "GET /containers/json,POST /containers/a1/pause,POST /containers/a1/unpause"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("docker calls = %s, want %s", got, want)
+	}
+}
+
+// TestBackupDockerResumesContainersOnSignal tests that an interrupt cancels
+// the volume backup and resumes the paused containers
+func TestBackupDockerResumesContainersOnSignal(t *testing.T) {
+	tmpDir := t.TempDir()
+	started := filepath.Join(tmpDir, "started")
+
+	fakeRestic := "#!/bin/sh\ntouch " + started + "\nexec sleep 30\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "restic"), []byte(fakeRestic), 0755); err != nil {
+		t.Fatalf("Failed to create fake restic: %v", err)
+	}
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var mu sync.Mutex
+	var calls []string
+	socket := filepath.Join(tmpDir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/containers/json" {
+			_, _ = w.Write([]byte(`[{"Id":"a1","Names":["/db"],"State":"running",
+				"Labels":{"resticm.enable":"true","resticm.mode":"pause"},
+				"Mounts":[{"Type":"volume","Name":"pgdata","Source":"/var/lib/docker/volumes/pgdata/_data"}]}]`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	cfg = &config.Config{Sources: config.SourcesConfig{
+		Docker: config.DockerSource{Enabled: true, Socket: socket},
+	}}
+	defer func() { cfg = nil }()
+
+	executor := restic.NewExecutor("/tmp/repo", "secret")
+	executor.Stdout = io.Discard
+	executor.Stderr = io.Discard
+
+	done := make(chan error, 1)
+	go func() { done <- backupDocker(executor, restic.BackupOptions{}) }()
+
+	// The signal handler is installed before restic starts
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("restic was not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	self, _ := os.FindProcess(os.Getpid())
+	if err := self.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot interrupt the test process: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errDockerInterrupted) {
+			t.Errorf("backupDocker() error = %v, want %v", err, errDockerInterrupted)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the volume backup was not cancelled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := "GET /containers/json,POST /containers/a1/pause,POST /containers/a1/unpause"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("docker calls = %s, want %s", got, want)
+	}
+}
+
+// fakeSnapshots is a snapshot provider recording its calls
+type fakeSnapshots struct {
+	calls []string
+}
+
+func (f *fakeSnapshots) Create(_ context.Context, dir fssnap.Directory, mountpoint string) error {
+	f.calls = append(f.calls, "create "+dir.Path+" "+mountpoint)
+	return nil
+}
+
+func (f *fakeSnapshots) Remove(_ context.Context, dir fssnap.Directory, _ string) error {
+	f.calls = append(f.calls, "remove "+dir.Path)
+	return nil
+}
+
+// TestBackupDirectoriesFromSnapshot tests that a snapshotted directory is
+// backed up from its mount point and the snapshot removed after a failure
+func TestBackupDirectoriesFromSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	argsFile := filepath.Join(tmpDir, "args")
+
+	fakeRestic := "#!/bin/sh\necho \"$@\" > " + argsFile + "\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "restic"), []byte(fakeRestic), 0755); err != nil {
+		t.Fatalf("Failed to create fake restic: %v", err)
+	}
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	provider := &fakeSnapshots{}
+	snapshotProvider = func(string) (fssnap.SnapshotProvider, error) { return provider, nil }
+	defer func() { snapshotProvider = fssnap.New }()
+
+	mountDir := filepath.Join(tmpDir, "snapshots")
+	cfg = &config.Config{
+		Directories: []string{"/etc", "/srv"},
+		FSSnapshots: config.FSSnapshotConfig{
+			MountDir:    mountDir,
+			Directories: []config.FSSnapshotDirectory{{Path: "/srv", Type: "lvm", Volume: "vg0/srv"}},
+		},
+	}
+	defer func() { cfg = nil }()
+
+	executor := restic.NewExecutor("/tmp/repo", "secret")
+	executor.Stdout = io.Discard
+	executor.Stderr = io.Discard
+
+	if _, err := backupAll(executor, "primary", restic.BackupOptions{Directories: cfg.Directories}); err == nil {
+		t.Error("expected the failed backup")
+	}
+
+	mountpoint := filepath.Join(mountDir, "srv")
+	if got, want := strings.Join(provider.calls, ","), "create /srv "+mountpoint+",remove /srv"; got != want {
+		t.Errorf("provider calls = %s, want %s", got, want)
+	}
+	data, _ := os.ReadFile(argsFile)
+	if args := string(data); !strings.Contains(args, "/etc "+mountpoint) || !strings.Contains(args, "--tag fssnap:/srv") {
+		t.Errorf("restic args = %q, want the mount point and origin tag", args)
+	}
+	if got := backupPaths(); got[1] != mountpoint {
+		t.Errorf("backupPaths() = %v", got)
+	}
+}
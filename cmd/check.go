@@ -10,7 +10,6 @@ import (
 
 	"resticm/internal/config"
 	"resticm/internal/restic"
-	"resticm/internal/security"
 )
 
 var checkCmd = &cobra.Command{
@@ -20,7 +19,8 @@ var checkCmd = &cobra.Command{
 
 By default, this command applies to the primary repository AND all configured
 copy backends to keep them synchronized. Use --primary-only to only affect
-the primary repository.
+the primary repository, or --backends to choose the backends (e.g. 'offsite'
+for a group, 'all,!wasabi').
 
 Modes:
   - Default: metadata check only (fast)
@@ -38,6 +38,7 @@ func init() {
 	checkCmd.Flags().Bool("auto", false, "Automatically run deep check if interval has elapsed")
 	checkCmd.Flags().String("subset", "", "Read a subset of data (e.g., '1/5' for 20%)")
 	checkCmd.Flags().Bool("primary-only", false, "Only apply to primary repository (skip copy backends)")
+	addBackendsFlag(checkCmd)
 }
 
 func runCheck(cmd *cobra.Command) (err error) {
@@ -52,6 +53,7 @@ func runCheck(cmd *cobra.Command) (err error) {
 	auto, _ := cmd.Flags().GetBool("auto")
 	subset, _ := cmd.Flags().GetString("subset")
 	primaryOnly, _ := cmd.Flags().GetBool("primary-only")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
@@ -67,6 +69,9 @@ func runCheck(cmd *cobra.Command) (err error) {
 	if primaryOnly {
 		flagMap["primary-only"] = true
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
 
 	// Log command start with context
 	LogCommandStart(cmd, flagMap)
@@ -76,9 +81,16 @@ func runCheck(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
+	backends, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return syncedBackends(cfg, primaryOnly)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Acquire lock
-	lock := security.NewLock("")
-	if err = lock.Acquire(); err != nil {
+	lock, err := acquireLock(backends...)
+	if err != nil {
 		return err
 	}
 	defer func() { _ = lock.Release() }()
@@ -88,56 +100,37 @@ func runCheck(cmd *cobra.Command) (err error) {
 	hostname, _ := os.Hostname()
 
 	var checkErrors []error
+	for i, name := range backends {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
+			PrintWarning("Backend '%s' not found in configuration, skipping", name)
+			continue
+		}
 
-	// Get active backend (if user explicitly selected one)
-	activeBackend, _ := config.GetActiveBackend()
-
-	// If user explicitly selected a specific backend, only operate on that one
-	if activeBackend != "" && activeBackend != "primary" {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
+		if i > 0 {
+			fmt.Println()
 		}
-		err := checkOnBackend(activeBackend, backend.Repository, backend.Password,
-			backend.AWSAccessKeyID, backend.AWSSecretAccessKey, deep, auto, subset, cfg.DeepCheckIntervalDays)
-		if err != nil {
+		if name == config.SelectPrimary {
+			PrintInfo("🔍 Running check on primary repository...")
+		}
+		err = checkOnBackend(repo, deep, auto, subset, cfg.DeepCheckIntervalDays)
+		if err == nil {
+			continue
+		}
+		if len(backends) == 1 {
 			_ = notifier.NotifyError(
 				"🚨 Repository Check FAILED",
-				fmt.Sprintf("CRITICAL: Repository integrity check failed on %s backend '%s'", hostname, activeBackend),
+				fmt.Sprintf("CRITICAL: Repository integrity check failed on %s backend '%s'", hostname, name),
 				err,
 				map[string]string{
 					"host":       hostname,
-					"backend":    activeBackend,
-					"repository": backend.Repository,
+					"backend":    name,
+					"repository": repo.URL,
 				},
 			)
+			return err
 		}
-		return err
-	}
-
-	// Run check on primary repository
-	PrintInfo("🔍 Running check on primary repository...")
-	if err := checkOnBackend("primary", cfg.Repository, cfg.GetPassword(),
-		cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey(), deep, auto, subset, cfg.DeepCheckIntervalDays); err != nil {
-		checkErrors = append(checkErrors, fmt.Errorf("primary: %w", err))
-	}
-
-	// Apply to copy backends unless --primary-only is set
-	if !primaryOnly && len(cfg.CopyToBackends) > 0 {
-		for _, backendName := range cfg.CopyToBackends {
-			backend, ok := cfg.Backends[backendName]
-			if !ok {
-				PrintWarning("Backend '%s' not found in configuration, skipping", backendName)
-				continue
-			}
-
-			fmt.Println()
-			if err := checkOnBackend(backendName, backend.Repository, backend.Password,
-				backend.AWSAccessKeyID, backend.AWSSecretAccessKey, deep, auto, subset, cfg.DeepCheckIntervalDays); err != nil {
-				PrintError("Check failed on backend '%s': %v", backendName, err)
-				checkErrors = append(checkErrors, fmt.Errorf("%s: %w", backendName, err))
-			}
-		}
+		checkErrors = append(checkErrors, fmt.Errorf("%s: %w", name, err))
 	}
 
 	// Send notification if any check failed
@@ -162,17 +155,16 @@ func runCheck(cmd *cobra.Command) (err error) {
 	return nil
 }
 
-func checkOnBackend(name, repo, password, awsKey, awsSecret string, deep, auto bool, subset string, deepCheckIntervalDays int) error {
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
-	executor.Verbose = IsVerbose()
+func checkOnBackend(repo *config.Repository, deep, auto bool, subset string, deepCheckIntervalDays int) error {
+	name := repo.Name
+	executor := newExecutor(repo)
 
 	// Determine if we should do a deep check
 	doDeepCheck := deep
 
 	// If --auto is set, check if deep check interval has elapsed
 	if auto && !deep {
-		tracker, err := restic.NewDeepCheckTracker(repo)
+		tracker, err := restic.NewDeepCheckTracker(repo.URL)
 		if err == nil && tracker.ShouldRunDeepCheck(deepCheckIntervalDays) {
 			PrintInfo("Deep check interval elapsed for %s, running deep check...", name)
 			doDeepCheck = true
@@ -190,14 +182,20 @@ func checkOnBackend(name, repo, password, awsKey, awsSecret string, deep, auto b
 		PrintInfo("Running metadata check on %s...", name)
 	}
 
-	if err := executor.Check(opts); err != nil {
+	err := runStep(newHookRunner(), "check", name, func() error {
+		return executor.Check(opts)
+	})
+	if stepSkipped(err) {
+		return nil
+	}
+	if err != nil {
 		PrintError("Check failed on %s: %v", name, err)
 		return err
 	}
 
 	// Record deep check if performed
 	if doDeepCheck {
-		if tracker, err := restic.NewDeepCheckTracker(repo); err == nil {
+		if tracker, err := restic.NewDeepCheckTracker(repo.URL); err == nil {
 			tracker.RecordCheck()
 		}
 	}
@@ -8,15 +8,26 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"resticm/internal/config"
 	"resticm/internal/restic"
-	"resticm/internal/security"
 )
 
 var copyCmd = &cobra.Command{
-	Use:   "copy",
+	Use:   "copy [snapshot-id...]",
 	Short: "Copy snapshots to secondary backends",
+	Long: `Copy snapshots from the primary repository to the secondary backends.
+
+By default every snapshot of this host is copied; restic skips those already
+on a backend. Snapshot IDs, --tag, --path, --since and --until restrict the
+snapshots copied, and --missing copies only the snapshots not yet on each
+backend, e.g. to backfill a new backend with the last 90 days:
+
+  resticm copy --to offsite --since 90d --missing
+
+--backends (or --to) selects the destination backends by name or group, e.g.
+'offsite' or 'copy,!wasabi'; the default is copy_to_backends.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runCopy(cmd)
+		return runCopy(cmd, args)
 	},
 }
 
@@ -24,9 +35,15 @@ func init() {
 	rootCmd.AddCommand(copyCmd)
 	copyCmd.Flags().Bool("all", false, "Copy snapshots from all hosts")
 	copyCmd.Flags().StringSlice("to", nil, "Specific backends to copy to")
+	addBackendsFlag(copyCmd)
+	copyCmd.Flags().StringArray("tag", nil, "Only copy snapshots with these tags (tag1,tag2 requires both; repeat for either)")
+	copyCmd.Flags().StringArray("path", nil, "Only copy snapshots including this path")
+	copyCmd.Flags().String("since", "", "Only copy snapshots since a duration (90d) or date (2006-01-02)")
+	copyCmd.Flags().String("until", "", "Only copy snapshots until a duration ago (30d) or date (2006-01-02)")
+	copyCmd.Flags().Bool("missing", false, "Only copy the snapshots not yet present on each backend")
 }
 
-func runCopy(cmd *cobra.Command) (err error) {
+func runCopy(cmd *cobra.Command, snapshotIDs []string) (err error) {
 	startTime := time.Now()
 
 	cfg := GetConfig()
@@ -34,13 +51,14 @@ func runCopy(cmd *cobra.Command) (err error) {
 		return fmt.Errorf("configuration not loaded")
 	}
 
-	if len(cfg.CopyToBackends) == 0 {
-		PrintInfo("No secondary backends configured for copy.")
-		return nil
-	}
-
 	allHosts, _ := cmd.Flags().GetBool("all")
 	toBackends, _ := cmd.Flags().GetStringSlice("to")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
+	tags, _ := cmd.Flags().GetStringArray("tag")
+	paths, _ := cmd.Flags().GetStringArray("path")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	missingOnly, _ := cmd.Flags().GetBool("missing")
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
@@ -50,6 +68,27 @@ func runCopy(cmd *cobra.Command) (err error) {
 	if len(toBackends) > 0 {
 		flagMap["to"] = strings.Join(toBackends, ",")
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
+	if len(snapshotIDs) > 0 {
+		flagMap["snapshots"] = strings.Join(snapshotIDs, ",")
+	}
+	if len(tags) > 0 {
+		flagMap["tag"] = strings.Join(tags, " ")
+	}
+	if len(paths) > 0 {
+		flagMap["path"] = strings.Join(paths, " ")
+	}
+	if since != "" {
+		flagMap["since"] = since
+	}
+	if until != "" {
+		flagMap["until"] = until
+	}
+	if missingOnly {
+		flagMap["missing"] = true
+	}
 
 	// Log command start with context
 	LogCommandStart(cmd, flagMap)
@@ -59,70 +98,139 @@ func runCopy(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
-	// Acquire lock
-	lock := security.NewLock("")
-	if err = lock.Acquire(); err != nil {
-		return err
-	}
-	defer func() { _ = lock.Release() }()
-
 	// Determine which backends to copy to
+	if len(toBackends) > 0 && backendsExpr != "" {
+		return fmt.Errorf("--to and --backends are mutually exclusive")
+	}
+	if len(toBackends) > 0 {
+		backendsExpr = strings.Join(toBackends, ",")
+	}
+	toBackends = cfg.CopyToBackends
+	if backendsExpr != "" {
+		if toBackends, err = cfg.SelectBackends(backendsExpr); err != nil {
+			return err
+		}
+	}
 	if len(toBackends) == 0 {
-		toBackends = cfg.CopyToBackends
+		PrintInfo("No secondary backends configured for copy.")
+		return nil
 	}
 
 	// Validate backends exist
 	for _, name := range toBackends {
+		if name == config.SelectPrimary {
+			return fmt.Errorf("cannot copy the primary repository to itself")
+		}
 		if _, ok := cfg.Backends[name]; !ok {
 			return fmt.Errorf("backend '%s' not found in configuration", name)
 		}
 	}
 
+	// Acquire lock
+	lock, err := acquireLock(append([]string{config.SelectPrimary}, toBackends...)...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	from, err := cfg.Resolve(config.SelectPrimary)
+	if err != nil {
+		return err
+	}
+
 	// Determine hostname filter
 	hostname := ""
 	if !allHosts {
 		hostname, _ = os.Hostname()
 	}
 
-	// Source credentials
-	fromRepo := cfg.Repository
-	fromPass := cfg.GetPassword()
-	fromAWSKey := cfg.GetAWSAccessKeyID()
-	fromAWSSecret := cfg.GetAWSSecretAccessKey()
+	filter := restic.SnapshotFilter{Tags: tags, Host: hostname, Paths: paths}
+	now := time.Now()
+	if since != "" {
+		if filter.Since, err = parseTimeFlag("since", since, now); err != nil {
+			return err
+		}
+	}
+	if until != "" {
+		if filter.Until, err = parseTimeFlag("until", until, now); err != nil {
+			return err
+		}
+	}
+
+	// restic copy filters by host, tag and path itself, but ignores the
+	// filters of explicit snapshot IDs. Snapshot IDs, a time range or --missing
+	// need the snapshot list: the IDs selected with the filters are passed.
+	resolve := len(snapshotIDs) > 0 || !filter.Since.IsZero() || !filter.Until.IsZero() || missingOnly
+	var selected []restic.Snapshot
+	if resolve {
+		source := newExecutor(from)
+		source.NoLock = true
+		selected, err = selectSnapshots(source, filter, snapshotIDs)
+		if err != nil {
+			return fmt.Errorf("failed to list snapshots of the primary: %w", err)
+		}
+		PrintInfo("%d snapshot(s) on the primary match the filters", len(selected))
+		if len(selected) == 0 {
+			return nil
+		}
+	}
 
 	PrintInfo("Copying snapshots to %d backend(s)...", len(toBackends))
 
-	var copyErrors []error
+	hookRunner := newHookRunner()
 
-	for _, backendName := range toBackends {
-		backend := cfg.Backends[backendName]
-
-		fmt.Printf("\n📦 Copying to backend: %s\n", backendName)
-
-		opts := restic.CopyOptions{
-			FromRepository:         fromRepo,
-			FromPassword:           fromPass,
-			FromAWSAccessKeyID:     fromAWSKey,
-			FromAWSSecretAccessKey: fromAWSSecret,
-			ToRepository:           backend.Repository,
-			ToPassword:             backend.Password,
-			ToAWSAccessKeyID:       backend.AWSAccessKeyID,
-			ToAWSSecretAccessKey:   backend.AWSSecretAccessKey,
-			Hostname:               hostname,
-		}
+	runs := forEachBackend(toBackends, copyParallelism(), func(b *backendRun) {
+		b.begin()
+		b.progress("📦 Copying snapshots...")
+
+		opts := copyOptions(from, b.Repo)
+		opts.Hostname = hostname
+		opts.Tags = tags
+		opts.Paths = paths
 
-		executor := restic.NewExecutor(backend.Repository, backend.Password)
-		executor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
-		executor.Verbose = IsVerbose()
-		executor.DryRun = IsDryRun()
+		executor := b.executor()
+
+		if resolve {
+			toCopy := selected
+			if missingOnly {
+				existing, err := executor.ListSnapshots()
+				if err != nil {
+					b.printError("Failed to list snapshots of %s: %v", b.Name, err)
+					b.fail("copy", err)
+					return
+				}
+				toCopy = restic.MissingSnapshots(selected, existing)
+			}
+			if len(toCopy) == 0 {
+				b.printSuccess("%s already has all selected snapshots", b.Name)
+				return
+			}
+			b.progress(fmt.Sprintf("%d snapshot(s) to copy", len(toCopy)))
+			// The IDs were selected with the filters, restic would only warn
+			opts.SnapshotIDs = snapshotIDsOf(toCopy)
+			opts.Hostname, opts.Tags, opts.Paths = "", nil, nil
+		}
 
-		if err := executor.Copy(opts); err != nil {
-			PrintError("Failed to copy to %s: %v", backendName, err)
-			copyErrors = append(copyErrors, fmt.Errorf("%s: %w", backendName, err))
-			continue
+		err := b.step(hookRunner, "copy", func() error {
+			return executor.Copy(opts)
+		})
+		if stepSkipped(err) {
+			return
 		}
+		if err != nil {
+			b.printError("Failed to copy to %s: %v", b.Name, err)
+			return
+		}
+
+		b.printSuccess("Copy to %s completed", b.Name)
+	})
+	printBackendSummary(runs)
 
-		PrintSuccess("Copy to %s completed", backendName)
+	var copyErrors []error
+	for _, b := range runs {
+		for _, err := range b.Errors() {
+			copyErrors = append(copyErrors, fmt.Errorf("%s: %w", b.Name, err))
+		}
 	}
 
 	if len(copyErrors) > 0 {
@@ -132,3 +240,38 @@ func runCopy(cmd *cobra.Command) (err error) {
 	PrintSuccess("All copy operations completed successfully")
 	return nil
 }
+
+// selectSnapshots lists the snapshots of the repository passing the filter,
+// restricted to the given IDs (full or short) when there are any
+func selectSnapshots(executor *restic.Executor, filter restic.SnapshotFilter, ids []string) ([]restic.Snapshot, error) {
+	snapshots, err := executor.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	var selected []restic.Snapshot
+	for _, s := range filter.Filter(snapshots) {
+		if len(ids) == 0 || matchesSnapshotID(s, ids) {
+			selected = append(selected, s)
+		}
+	}
+	return selected, nil
+}
+
+// matchesSnapshotID reports whether one of ids is a prefix of the snapshot ID
+func matchesSnapshotID(s restic.Snapshot, ids []string) bool {
+	for _, id := range ids {
+		if id != "" && strings.HasPrefix(s.ID, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotIDsOf returns the IDs of the snapshots
+func snapshotIDsOf(snapshots []restic.Snapshot) []string {
+	ids := make([]string, len(snapshots))
+	for i, s := range snapshots {
+		ids[i] = s.ID
+	}
+	return ids
+}
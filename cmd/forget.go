@@ -10,7 +10,6 @@ import (
 
 	"resticm/internal/config"
 	"resticm/internal/restic"
-	"resticm/internal/security"
 )
 
 var forgetCmd = &cobra.Command{
@@ -20,7 +19,8 @@ var forgetCmd = &cobra.Command{
 
 By default, this command applies to the primary repository AND all configured
 copy backends to keep them synchronized. Use --primary-only to only affect
-the primary repository.`,
+the primary repository, or --backends to choose the backends (e.g. 'offsite'
+for a group, 'all,!wasabi').`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runForget(cmd)
 	},
@@ -31,6 +31,7 @@ func init() {
 	forgetCmd.Flags().Bool("all-hosts", false, "Process snapshots from all hosts")
 	forgetCmd.Flags().BoolP("prune", "p", false, "Also run prune after forget")
 	forgetCmd.Flags().Bool("primary-only", false, "Only apply to primary repository (skip copy backends)")
+	addBackendsFlag(forgetCmd)
 }
 
 func runForget(cmd *cobra.Command) (err error) {
@@ -44,6 +45,7 @@ func runForget(cmd *cobra.Command) (err error) {
 	allHosts, _ := cmd.Flags().GetBool("all-hosts")
 	prune, _ := cmd.Flags().GetBool("prune")
 	primaryOnly, _ := cmd.Flags().GetBool("primary-only")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
@@ -56,6 +58,9 @@ func runForget(cmd *cobra.Command) (err error) {
 	if primaryOnly {
 		flagMap["primary-only"] = true
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
 
 	// Log command start with context
 	LogCommandStart(cmd, flagMap)
@@ -65,9 +70,16 @@ func runForget(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
+	backends, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return syncedBackends(cfg, primaryOnly)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Acquire lock
-	lock := security.NewLock("")
-	if err = lock.Acquire(); err != nil {
+	lock, err := acquireLock(backends...)
+	if err != nil {
 		return err
 	}
 	defer func() { _ = lock.Release() }()
@@ -83,64 +95,38 @@ func runForget(cmd *cobra.Command) (err error) {
 	currentHost, _ := os.Hostname()
 
 	var forgetErrors []error
+	for i, name := range backends {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
+			PrintWarning("Backend '%s' not found in configuration, skipping", name)
+			continue
+		}
 
-	opts := restic.ForgetOptions{
-		KeepWithin:  cfg.Retention.KeepWithin,
-		KeepHourly:  cfg.Retention.KeepHourly,
-		KeepDaily:   cfg.Retention.KeepDaily,
-		KeepWeekly:  cfg.Retention.KeepWeekly,
-		KeepMonthly: cfg.Retention.KeepMonthly,
-		KeepYearly:  cfg.Retention.KeepYearly,
-		Hostname:    hostname,
-		Prune:       prune,
-	}
-
-	// Get active backend (if user explicitly selected one)
-	activeBackend, _ := config.GetActiveBackend()
-
-	// If user explicitly selected a specific backend, only operate on that one
-	if activeBackend != "" && activeBackend != "primary" {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
+		if i > 0 {
+			fmt.Println()
 		}
-		err := forgetOnBackend(activeBackend, backend.Repository, backend.Password,
-			backend.AWSAccessKeyID, backend.AWSSecretAccessKey, opts)
-		if err != nil {
+		if name == config.SelectPrimary {
+			PrintInfo("🗑️  Running forget on primary repository...")
+		} else {
+			PrintInfo("🗑️  Running forget on backend: %s", name)
+		}
+		// Each backend applies its own retention policy
+		opts := forgetOptions(repo, hostname)
+		opts.Prune = prune
+		err = forgetOnBackend(repo, opts)
+		if err == nil {
+			continue
+		}
+		if len(backends) == 1 {
 			_ = notifier.NotifyError(
 				"❌ Forget Failed",
-				fmt.Sprintf("resticm forget failed on %s backend '%s'", currentHost, activeBackend),
+				fmt.Sprintf("resticm forget failed on %s backend '%s'", currentHost, name),
 				err,
-				map[string]string{"host": currentHost, "backend": activeBackend},
+				map[string]string{"host": currentHost, "backend": name},
 			)
+			return err
 		}
-		return err
-	}
-
-	// Run forget on primary repository
-	PrintInfo("🗑️  Running forget on primary repository...")
-	if err := forgetOnBackend("primary", cfg.Repository, cfg.GetPassword(),
-		cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey(), opts); err != nil {
-		forgetErrors = append(forgetErrors, fmt.Errorf("primary: %w", err))
-	}
-
-	// Apply to copy backends unless --primary-only is set
-	if !primaryOnly && len(cfg.CopyToBackends) > 0 {
-		for _, backendName := range cfg.CopyToBackends {
-			backend, ok := cfg.Backends[backendName]
-			if !ok {
-				PrintWarning("Backend '%s' not found in configuration, skipping", backendName)
-				continue
-			}
-
-			fmt.Println()
-			PrintInfo("🗑️  Running forget on backend: %s", backendName)
-			if err := forgetOnBackend(backendName, backend.Repository, backend.Password,
-				backend.AWSAccessKeyID, backend.AWSSecretAccessKey, opts); err != nil {
-				PrintError("Forget failed on backend '%s': %v", backendName, err)
-				forgetErrors = append(forgetErrors, fmt.Errorf("%s: %w", backendName, err))
-			}
-		}
+		forgetErrors = append(forgetErrors, fmt.Errorf("%s: %w", name, err))
 	}
 
 	// Send notification if any forget failed
@@ -162,13 +148,18 @@ func runForget(cmd *cobra.Command) (err error) {
 	return nil
 }
 
-func forgetOnBackend(name, repo, password, awsKey, awsSecret string, opts restic.ForgetOptions) error {
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+func forgetOnBackend(repo *config.Repository, opts restic.ForgetOptions) error {
+	name := repo.Name
+	executor := newExecutor(repo)
 	executor.DryRun = IsDryRun()
-	executor.Verbose = IsVerbose()
 
-	if err := executor.Forget(opts); err != nil {
+	err := runStep(newHookRunner(), "forget", name, func() error {
+		return executor.Forget(opts)
+	})
+	if stepSkipped(err) {
+		return nil
+	}
+	if err != nil {
 		PrintError("Forget failed on %s: %v", name, err)
 		return err
 	}
@@ -8,9 +8,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"resticm/internal/config"
 	"resticm/internal/hooks"
 	"resticm/internal/restic"
-	"resticm/internal/security"
 )
 
 var fullCmd = &cobra.Command{
@@ -24,7 +24,7 @@ This command ensures all repositories stay synchronized by running:
   3. Prune on primary
   4. Check on primary (with auto deep-check based on interval)
   5. Copy to secondary backends
-  6. Forget on each copy backend (same retention policy)
+  6. Forget on each copy backend (its own retention policy)
   7. Prune on each copy backend
   8. Check on each copy backend`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -76,9 +76,9 @@ func runFull(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
-	// Acquire lock
-	lock := security.NewLock("")
-	if err = lock.Acquire(); err != nil {
+	// Acquire lock on the primary and the copy backends
+	lock, err := acquireLock(append([]string{config.SelectPrimary}, cfg.CopyToBackends...)...)
+	if err != nil {
 		return err
 	}
 	defer func() { _ = lock.Release() }()
@@ -87,29 +87,22 @@ func runFull(cmd *cobra.Command) (err error) {
 		PrintInfo("Skipping all hooks (--no-hooks flag set)")
 	}
 
-	repo := cfg.Repository
-	password := cfg.GetPassword()
-	awsKey := cfg.GetAWSAccessKeyID()
-	awsSecret := cfg.GetAWSSecretAccessKey()
-
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+	primary, err := cfg.Resolve(config.SelectPrimary)
+	if err != nil {
+		return err
+	}
+	executor := newExecutor(primary)
 	executor.DryRun = IsDryRun()
-	executor.Verbose = IsVerbose()
+
+	// Locks left by an interrupted previous run would block this one
+	removeStaleLocks(executor, "primary")
 
 	hostname, _ := os.Hostname()
 
 	// Setup hooks
 	var hookRunner *hooks.Runner
 	if !noHooks {
-		hookRunner = hooks.NewRunner()
-		hookRunner.PreBackup = cfg.Hooks.PreBackup
-		hookRunner.PostBackup = cfg.Hooks.PostBackup
-		hookRunner.OnError = cfg.Hooks.OnError
-		hookRunner.OnSuccess = cfg.Hooks.OnSuccess
-		hookRunner.DryRun = IsDryRun()
-		hookRunner.Verbose = IsVerbose()
-		hookRunner.Logger = GetLogger()
+		hookRunner = newHookRunner()
 	}
 
 	var errors []error
@@ -130,9 +123,17 @@ func runFull(cmd *cobra.Command) (err error) {
 	fmt.Println("📦 STEP 1/5: BACKUP")
 	fmt.Println(separator)
 
+	tags := cfg.DefaultTags
+	if extraTag != "" {
+		tags = append(tags, extraTag)
+	}
+	recordTags(tags)
+
 	// Run pre-backup hook
 	if !noHooks && hookRunner != nil {
+		stepStart := time.Now()
 		if err := hookRunner.RunPreBackup(); err != nil {
+			recordStep("pre_backup_hook", "", stepStart, err)
 			PrintError("Pre-backup hook failed: %v", err)
 			errors = append(errors, err)
 			_ = hookRunner.RunOnError(err)
@@ -149,11 +150,6 @@ func runFull(cmd *cobra.Command) (err error) {
 		}
 	}
 
-	tags := cfg.DefaultTags
-	if extraTag != "" {
-		tags = append(tags, extraTag)
-	}
-
 	backupOpts := restic.BackupOptions{
 		Directories:     cfg.Directories,
 		Tags:            tags,
@@ -162,14 +158,22 @@ func runFull(cmd *cobra.Command) (err error) {
 		Hostname:        hostname,
 	}
 
-	if err := executor.Backup(backupOpts); err != nil {
-		PrintError("Backup failed: %v", err)
-		errors = append(errors, err)
+	var summary *restic.BackupSummary
+	backupErr := runStep(hookRunner, "backup", "primary", func() (err error) {
+		summary, err = backupAll(executor, "primary", backupOpts)
+		return err
+	})
+	recordBackup(summary)
+	switch {
+	case stepSkipped(backupErr):
+	case backupErr != nil:
+		PrintError("Backup failed: %v", backupErr)
+		errors = append(errors, backupErr)
 		if !noHooks && hookRunner != nil {
-			_ = hookRunner.RunPostBackup(false, err)
-			_ = hookRunner.RunOnError(err)
+			_ = hookRunner.RunPostBackup(false, backupErr)
+			_ = hookRunner.RunOnError(backupErr)
 		}
-	} else {
+	default:
 		PrintSuccess("Backup completed")
 		if !noHooks && hookRunner != nil {
 			_ = hookRunner.RunPostBackup(true, nil)
@@ -186,20 +190,15 @@ func runFull(cmd *cobra.Command) (err error) {
 		forgetHostname = ""
 	}
 
-	forgetOpts := restic.ForgetOptions{
-		KeepWithin:  cfg.Retention.KeepWithin,
-		KeepHourly:  cfg.Retention.KeepHourly,
-		KeepDaily:   cfg.Retention.KeepDaily,
-		KeepWeekly:  cfg.Retention.KeepWeekly,
-		KeepMonthly: cfg.Retention.KeepMonthly,
-		KeepYearly:  cfg.Retention.KeepYearly,
-		Hostname:    forgetHostname,
-	}
-
-	if err := executor.Forget(forgetOpts); err != nil {
-		PrintError("Forget failed: %v", err)
-		errors = append(errors, err)
-	} else {
+	forgetErr := runStep(hookRunner, "forget", "primary", func() error {
+		return executor.Forget(forgetOptions(primary, forgetHostname))
+	})
+	switch {
+	case stepSkipped(forgetErr):
+	case forgetErr != nil:
+		PrintError("Forget failed: %v", forgetErr)
+		errors = append(errors, forgetErr)
+	default:
 		PrintSuccess("Forget completed")
 	}
 
@@ -208,10 +207,13 @@ func runFull(cmd *cobra.Command) (err error) {
 	fmt.Println("🧹 STEP 3/5: PRUNE")
 	fmt.Println(separator)
 
-	if err := executor.Prune(); err != nil {
-		PrintError("Prune failed: %v", err)
-		errors = append(errors, err)
-	} else {
+	pruneErr := runStep(hookRunner, "prune", "primary", executor.Prune)
+	switch {
+	case stepSkipped(pruneErr):
+	case pruneErr != nil:
+		PrintError("Prune failed: %v", pruneErr)
+		errors = append(errors, pruneErr)
+	default:
 		PrintSuccess("Prune completed")
 	}
 
@@ -222,20 +224,25 @@ func runFull(cmd *cobra.Command) (err error) {
 
 	shouldDeep := deep
 	if !shouldDeep && cfg.DeepCheckIntervalDays > 0 {
-		if tracker, err := restic.NewDeepCheckTracker(repo); err == nil {
+		if tracker, err := restic.NewDeepCheckTracker(primary.URL); err == nil {
 			shouldDeep = tracker.ShouldRunDeepCheck(cfg.DeepCheckIntervalDays)
 		}
 	}
 
 	checkOpts := restic.CheckOptions{ReadData: shouldDeep}
 
-	if err := executor.Check(checkOpts); err != nil {
-		PrintError("Check failed: %v", err)
-		errors = append(errors, err)
-	} else {
+	checkErr := runStep(hookRunner, "check", "primary", func() error {
+		return executor.Check(checkOpts)
+	})
+	switch {
+	case stepSkipped(checkErr):
+	case checkErr != nil:
+		PrintError("Check failed: %v", checkErr)
+		errors = append(errors, checkErr)
+	default:
 		PrintSuccess("Check passed")
 		if shouldDeep {
-			if tracker, err := restic.NewDeepCheckTracker(repo); err == nil {
+			if tracker, err := restic.NewDeepCheckTracker(primary.URL); err == nil {
 				_ = tracker.RecordCheck()
 			}
 		}
@@ -254,77 +261,88 @@ func runFull(cmd *cobra.Command) (err error) {
 			copyHostname = ""
 		}
 
-		for _, backendName := range cfg.CopyToBackends {
-			backend := cfg.Backends[backendName]
+		runs := forEachBackend(cfg.CopyToBackends, copyParallelism(), func(b *backendRun) {
+			if b.Repo == nil {
+				b.locked(func() { PrintWarning("Backend '%s' not found, skipping", b.Name) })
+				return
+			}
 
-			fmt.Printf("\n  ┌─ Backend: %s\n", backendName)
+			b.begin()
 
 			// 5a. COPY to this backend
-			fmt.Println("  │ 📦 Copying snapshots...")
-			copyOpts := restic.CopyOptions{
-				FromRepository:         repo,
-				FromPassword:           password,
-				FromAWSAccessKeyID:     awsKey,
-				FromAWSSecretAccessKey: awsSecret,
-				ToRepository:           backend.Repository,
-				ToPassword:             backend.Password,
-				ToAWSAccessKeyID:       backend.AWSAccessKeyID,
-				ToAWSSecretAccessKey:   backend.AWSSecretAccessKey,
-				Hostname:               copyHostname,
+			b.progress("📦 Copying snapshots...")
+			copyOpts := copyOptions(primary, b.Repo)
+			copyOpts.Hostname = copyHostname
+
+			destExecutor := b.executor()
+
+			copyErr := b.step(hookRunner, "copy", func() error {
+				return destExecutor.Copy(copyOpts)
+			})
+			if stepSkipped(copyErr) {
+				b.end("⏭️  Skipping maintenance, copy was skipped")
+				return
 			}
-
-			destExecutor := restic.NewExecutor(backend.Repository, backend.Password)
-			destExecutor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
-			destExecutor.Verbose = IsVerbose()
-			destExecutor.DryRun = IsDryRun()
-
-			if err := destExecutor.Copy(copyOpts); err != nil {
-				PrintError("Copy to %s failed: %v", backendName, err)
-				errors = append(errors, err)
-				fmt.Println("  └─ ❌ Skipping maintenance due to copy failure")
-				continue
+			if copyErr != nil {
+				b.printError("Copy to %s failed: %v", b.Name, copyErr)
+				b.end("❌ Skipping maintenance due to copy failure")
+				return
 			}
-			PrintSuccess("Copy to %s completed", backendName)
+			b.printSuccess("Copy to %s completed", b.Name)
 
 			// 5b. FORGET on this backend
-			fmt.Println("  │ 🗑️  Applying retention policy...")
-			if err := destExecutor.Forget(forgetOpts); err != nil {
-				PrintError("Forget on %s failed: %v", backendName, err)
-				errors = append(errors, err)
-			} else {
-				PrintSuccess("Forget on %s completed", backendName)
+			b.progress("🗑️  Applying retention policy...")
+			forgetErr := b.step(hookRunner, "forget", func() error {
+				return destExecutor.Forget(forgetOptions(b.Repo, forgetHostname))
+			})
+			switch {
+			case stepSkipped(forgetErr):
+			case forgetErr != nil:
+				b.printError("Forget on %s failed: %v", b.Name, forgetErr)
+			default:
+				b.printSuccess("Forget on %s completed", b.Name)
 			}
 
 			// 5c. PRUNE on this backend
-			fmt.Println("  │ 🧹 Pruning unused data...")
-			if err := destExecutor.Prune(); err != nil {
-				PrintError("Prune on %s failed: %v", backendName, err)
-				errors = append(errors, err)
-			} else {
-				PrintSuccess("Prune on %s completed", backendName)
+			b.progress("🧹 Pruning unused data...")
+			pruneErr := b.step(hookRunner, "prune", destExecutor.Prune)
+			switch {
+			case stepSkipped(pruneErr):
+			case pruneErr != nil:
+				b.printError("Prune on %s failed: %v", b.Name, pruneErr)
+			default:
+				b.printSuccess("Prune on %s completed", b.Name)
 			}
 
 			// 5d. CHECK on this backend
-			fmt.Println("  │ 🔍 Checking integrity...")
+			b.progress("🔍 Checking integrity...")
 			backendShouldDeep := deep
 			if !backendShouldDeep && cfg.DeepCheckIntervalDays > 0 {
-				if tracker, err := restic.NewDeepCheckTracker(backend.Repository); err == nil {
+				if tracker, err := restic.NewDeepCheckTracker(b.Repo.URL); err == nil {
 					backendShouldDeep = tracker.ShouldRunDeepCheck(cfg.DeepCheckIntervalDays)
 				}
 			}
 			backendCheckOpts := restic.CheckOptions{ReadData: backendShouldDeep}
-			if err := destExecutor.Check(backendCheckOpts); err != nil {
-				PrintError("Check on %s failed: %v", backendName, err)
-				errors = append(errors, err)
-			} else {
-				PrintSuccess("Check on %s passed", backendName)
+			backendCheckErr := b.step(hookRunner, "check", func() error {
+				return destExecutor.Check(backendCheckOpts)
+			})
+			switch {
+			case stepSkipped(backendCheckErr):
+			case backendCheckErr != nil:
+				b.printError("Check on %s failed: %v", b.Name, backendCheckErr)
+			default:
+				b.printSuccess("Check on %s passed", b.Name)
 				if backendShouldDeep {
-					if tracker, err := restic.NewDeepCheckTracker(backend.Repository); err == nil {
+					if tracker, err := restic.NewDeepCheckTracker(b.Repo.URL); err == nil {
 						_ = tracker.RecordCheck()
 					}
 				}
 			}
-			fmt.Println("  └─ ✅ Backend synchronized")
+			b.end("✅ Backend synchronized")
+		})
+		printBackendSummary(runs)
+		for _, b := range runs {
+			errors = append(errors, b.Errors()...)
 		}
 	}
 
@@ -338,6 +356,7 @@ func runFull(cmd *cobra.Command) (err error) {
 		var staleLockRepos []string
 
 		// Check primary repository
+		removeStaleLocks(executor, "primary")
 		if result, err := executor.VerifyNoStaleLocks(hostname); err != nil {
 			PrintWarning("Could not verify locks on primary: %v", err)
 		} else {
@@ -364,10 +383,13 @@ func runFull(cmd *cobra.Command) (err error) {
 
 		// Check copy backends
 		for _, backendName := range cfg.CopyToBackends {
-			backend := cfg.Backends[backendName]
-			backendExecutor := restic.NewExecutor(backend.Repository, backend.Password)
-			backendExecutor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
+			backend, err := cfg.Resolve(backendName)
+			if err != nil {
+				continue
+			}
+			backendExecutor := newExecutor(backend)
 
+			removeStaleLocks(backendExecutor, backendName)
 			if result, err := backendExecutor.VerifyNoStaleLocks(hostname); err != nil {
 				PrintWarning("Could not verify locks on %s: %v", backendName, err)
 			} else {
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"resticm/internal/config"
+	"resticm/internal/history"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [run-id]",
+	Short: "Show past resticm runs",
+	Long: `Show past resticm runs recorded in the local history.
+
+Every command records its start and end time, flags, per-step status and
+duration, the backup summary and any error. The history is stored in
+/var/lib/resticm/history.jsonl (root) or ~/.config/resticm/history.jsonl.
+
+Pass a run ID (or a unique prefix) to show the details of a single run.
+
+Examples:
+  resticm history
+  resticm history --command backup --status failed
+  resticm history --since 7d --limit 50
+  resticm history 3f2a1b9c --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHistory(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("command", "", "Only show runs of this command (e.g. backup, full)")
+	historyCmd.Flags().String("status", "", "Only show runs with this status (success, failed)")
+	historyCmd.Flags().String("since", "", "Only show runs since a duration (24h, 7d) or date (2006-01-02)")
+	historyCmd.Flags().Int("limit", 20, "Maximum number of runs to show (0 for all)")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	store := history.NewStore(config.StateDir())
+
+	if len(args) == 1 {
+		run, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if run == nil {
+			return fmt.Errorf("run '%s' not found in history", args[0])
+		}
+		return showRun(run)
+	}
+
+	commandFilter, _ := cmd.Flags().GetString("command")
+	status, _ := cmd.Flags().GetString("status")
+	since, _ := cmd.Flags().GetString("since")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	filter := history.Filter{
+		Command: commandFilter,
+		Status:  status,
+		Limit:   limit,
+	}
+	if since != "" {
+		t, err := parseTimeFlag("since", since, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = t
+	}
+
+	runs, err := store.List(filter)
+	if err != nil {
+		return err
+	}
+
+	if IsJSONOutput() {
+		if runs == nil {
+			runs = []*history.Run{}
+		}
+		output, _ := json.MarshalIndent(runs, "", "  ")
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(runs) == 0 {
+		PrintInfo("No runs found in %s", store.Path())
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("%-10s %-17s %-22s %-8s %-9s %-10s %s\n", "ID", "STARTED", "COMMAND", "STATUS", "DURATION", "SNAPSHOT", "ADDED")
+	fmt.Println("──────────────────────────────────────────────────────────────────────────────────────────")
+
+	for _, r := range runs {
+		snapshot, added := "-", "-"
+		if r.Backup != nil {
+			if r.Backup.SnapshotID != "" {
+				snapshot = r.Backup.SnapshotID
+			}
+			added = formatBytes(r.Backup.DataAdded)
+		}
+		status := r.Status
+		if r.DryRun {
+			status += "*"
+		}
+		fmt.Printf("%-10s %-17s %-22s %-8s %-9s %-10s %s\n",
+			shortRunID(r.ID),
+			r.Start.Local().Format("2006-01-02 15:04"),
+			r.Command,
+			status,
+			r.Duration.Round(time.Second),
+			snapshot,
+			added,
+		)
+	}
+
+	fmt.Println()
+	PrintInfo("Showing %d run(s) (* = dry run)", len(runs))
+	return nil
+}
+
+// showRun prints the details of a single run
+func showRun(r *history.Run) error {
+	if IsJSONOutput() {
+		output, _ := json.MarshalIndent(r, "", "  ")
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Run:      %s\n", r.ID)
+	fmt.Printf("Command:  %s\n", r.Command)
+	fmt.Printf("Host:     %s\n", r.Hostname)
+	fmt.Printf("Started:  %s\n", r.Start.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Duration: %s\n", r.Duration.Round(time.Second))
+	fmt.Printf("Status:   %s\n", r.Status)
+	if r.DryRun {
+		fmt.Println("Dry run:  yes")
+	}
+	if len(r.Flags) > 0 {
+		var flags []string
+		for k, v := range r.Flags {
+			flags = append(flags, fmt.Sprintf("%s=%v", k, v))
+		}
+		fmt.Printf("Flags:    %s\n", strings.Join(flags, " "))
+	}
+	if r.Error != "" {
+		fmt.Printf("Error:    %s\n", r.Error)
+	}
+
+	if r.Backup != nil {
+		b := r.Backup
+		fmt.Println("\nBackup:")
+		fmt.Printf("  Snapshot:  %s\n", b.SnapshotID)
+		fmt.Printf("  Files:     %d new, %d changed, %d unmodified\n", b.FilesNew, b.FilesChanged, b.FilesUnmodified)
+		fmt.Printf("  Dirs:      %d new, %d changed, %d unmodified\n", b.DirsNew, b.DirsChanged, b.DirsUnmodified)
+		fmt.Printf("  Added:     %s\n", formatBytes(b.DataAdded))
+		fmt.Printf("  Processed: %d files, %s\n", b.TotalFilesProcessed, formatBytes(b.TotalBytesProcessed))
+	}
+
+	if len(r.Steps) > 0 {
+		fmt.Println("\nSteps:")
+		fmt.Printf("  %-16s %-12s %-8s %-9s %s\n", "STEP", "BACKEND", "STATUS", "DURATION", "ERROR")
+		for _, s := range r.Steps {
+			backend := s.Backend
+			if backend == "" {
+				backend = "-"
+			}
+			fmt.Printf("  %-16s %-12s %-8s %-9s %s\n", s.Name, backend, s.Status, s.Duration.Round(time.Second), s.Error)
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+// shortRunID returns the first 8 characters of a run ID
+func shortRunID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// parseTimeFlag parses the value of a time flag: a duration before now (30m,
+// 24h, 7d) or a date (2006-01-02)
+func parseTimeFlag(flag, s string, now time.Time) (time.Time, error) {
+	if d, err := config.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s value '%s' (use e.g. 24h, 7d or 2006-01-02)", flag, s)
+}
@@ -3,12 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"resticm/internal/config"
+	"resticm/internal/docker"
+	"resticm/internal/sources"
 )
 
 var infoCmd = &cobra.Command{
@@ -109,6 +112,17 @@ func runInfo() error {
 			_, _ = yellow.Println("not set (check environment)")
 		}
 	}
+	if creds := cfg.Credentials.Describe(); len(creds) > 0 {
+		fmt.Println("  Credentials:")
+		for _, line := range creds {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	if primary, err := cfg.Resolve(config.SelectPrimary); err == nil {
+		for _, line := range describeRepository(primary) {
+			fmt.Printf("  %s\n", line)
+		}
+	}
 	fmt.Println()
 
 	// Directories
@@ -122,6 +136,9 @@ func runInfo() error {
 			if _, err := os.Stat(dir); os.IsNotExist(err) {
 				_, _ = red.Printf("  • %s ", dir)
 				_, _ = gray.Println("(not found)")
+			} else if snap := fsSnapshotOf(dir); snap != nil {
+				fmt.Printf("  • %s ", dir)
+				_, _ = gray.Printf("(from %s snapshot)\n", snap.Type)
 			} else {
 				fmt.Printf("  • %s\n", dir)
 			}
@@ -129,6 +146,39 @@ func runInfo() error {
 	}
 	fmt.Println()
 
+	// Database and command sources
+	if len(cfg.Sources.Databases) > 0 || len(cfg.Sources.Commands) > 0 || cfg.Sources.Docker.Enabled {
+		bold.Println("🗄️  Backup Sources")
+		fmt.Println("────────────────────────────────────────────────────────────────────")
+		for _, source := range cfg.Sources.Databases {
+			db := databaseFromConfig(source)
+			target := source.Database
+			if db.Type == sources.TypeSQLite {
+				target = source.Path
+			}
+			fmt.Printf("  • %s ", db.Name)
+			_, _ = gray.Printf("(%s %s via %s, tag %s)\n", db.Type, target, db.DumpCommand(), db.Tag())
+		}
+		for _, source := range cfg.Sources.Commands {
+			src := commandFromConfig(source)
+			fmt.Printf("  • %s ", src.Name)
+			_, _ = gray.Printf("(output of '%s' as %s, tag %s)\n", strings.Join(src.Argv, " "), src.File(), src.Tag())
+		}
+		if dc := cfg.Sources.Docker; dc.Enabled {
+			label := dc.Label
+			if label == "" {
+				label = docker.LabelEnable + "=true"
+			}
+			mode := dc.Mode
+			if mode == "" {
+				mode = docker.ModeNone
+			}
+			fmt.Printf("  • docker ")
+			_, _ = gray.Printf("(volumes of containers labelled %s, default mode %s)\n", label, mode)
+		}
+		fmt.Println()
+	}
+
 	// Exclusions
 	bold.Println("🚫 Exclusions")
 	fmt.Println("────────────────────────────────────────────────────────────────────")
@@ -199,6 +249,17 @@ func runInfo() error {
 				fmt.Printf("  • %s\n", name)
 			}
 			gray.Printf("    %s\n", backend.Repository)
+			for _, line := range backend.Credentials.Describe() {
+				gray.Printf("    %s\n", line)
+			}
+			if repo, err := cfg.Resolve(name); err == nil {
+				for _, line := range describeRepository(repo) {
+					gray.Printf("    %s\n", line)
+				}
+			}
+			if backend.Retention != nil {
+				gray.Println("    retention: own policy")
+			}
 		}
 	}
 
@@ -231,22 +292,48 @@ func runInfo() error {
 	bold.Println("🪝 Hooks")
 	fmt.Println("────────────────────────────────────────────────────────────────────")
 	hasHooks := false
-	if cfg.Hooks.PreBackup != "" {
+	if cfg.Hooks.PreBackup.IsSet() {
 		fmt.Printf("  Pre-backup:  %s\n", cfg.Hooks.PreBackup)
 		hasHooks = true
 	}
-	if cfg.Hooks.PostBackup != "" {
+	if cfg.Hooks.PostBackup.IsSet() {
 		fmt.Printf("  Post-backup: %s\n", cfg.Hooks.PostBackup)
 		hasHooks = true
 	}
-	if cfg.Hooks.OnError != "" {
+	if cfg.Hooks.OnError.IsSet() {
 		fmt.Printf("  On error:    %s\n", cfg.Hooks.OnError)
 		hasHooks = true
 	}
-	if cfg.Hooks.OnSuccess != "" {
+	if cfg.Hooks.OnSuccess.IsSet() {
 		fmt.Printf("  On success:  %s\n", cfg.Hooks.OnSuccess)
 		hasHooks = true
 	}
+	for _, step := range config.HookSteps {
+		h, ok := cfg.Hooks.Steps[step]
+		if !ok {
+			continue
+		}
+		pre, post, policy := h.Pre.String(), h.Post.String(), h.OnPreFailure
+		if pre == "" {
+			pre = "-"
+		}
+		if post == "" {
+			post = "-"
+		}
+		if policy == "" {
+			policy = "abort"
+		}
+		fmt.Printf("  Step %-7s pre: %s, post: %s (on pre failure: %s)\n", step+":", pre, post, policy)
+		if len(h.Backends) > 0 {
+			var backends []string
+			for backend := range h.Backends {
+				backends = append(backends, backend)
+			}
+			sort.Strings(backends)
+			fmt.Printf("    Overridden for: %s\n", strings.Join(backends, ", "))
+		}
+		hasHooks = true
+	}
 	if !hasHooks {
 		gray.Println("  No hooks configured")
 	}
@@ -311,3 +398,19 @@ func runInfo() error {
 
 	return nil
 }
+
+// describeRepository returns the cache directory and bandwidth limits of a
+// resolved repository for display
+func describeRepository(repo *config.Repository) []string {
+	var lines []string
+	if repo.CacheDir != "" {
+		lines = append(lines, "cache_dir: "+repo.CacheDir)
+	}
+	if repo.LimitUpload > 0 {
+		lines = append(lines, fmt.Sprintf("limit_upload: %d KiB/s", repo.LimitUpload))
+	}
+	if repo.LimitDownload > 0 {
+		lines = append(lines, fmt.Sprintf("limit_download: %d KiB/s", repo.LimitDownload))
+	}
+	return lines
+}
@@ -71,12 +71,15 @@ func runInit(cmd *cobra.Command) (err error) {
 }
 
 func initPrimary(cfg *config.Config) error {
+	repo, err := cfg.Resolve(config.SelectPrimary)
+	if err != nil {
+		return err
+	}
+
 	PrintInfo("Initializing primary repository...")
-	fmt.Printf("  Repository: %s\n", cfg.Repository)
+	fmt.Printf("  Repository: %s\n", repo.URL)
 
-	executor := restic.NewExecutor(cfg.Repository, cfg.GetPassword())
-	executor.SetAWSCredentials(cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey())
-	executor.Verbose = IsVerbose()
+	executor := newExecutor(repo)
 
 	if executor.IsInitialized() {
 		PrintSuccess("Repository already initialized")
@@ -93,24 +96,21 @@ func initPrimary(cfg *config.Config) error {
 }
 
 func initBackend(cfg *config.Config, name string) error {
-	backend, ok := cfg.Backends[name]
-	if !ok {
+	repo, err := cfg.Resolve(name)
+	if err != nil || repo.IsPrimary() {
 		return fmt.Errorf("backend '%s' not found in configuration", name)
 	}
 
 	PrintInfo("Initializing backend: %s", name)
-	fmt.Printf("  Repository: %s\n", backend.Repository)
+	fmt.Printf("  Repository: %s\n", repo.URL)
 
-	password := backend.Password
-	if password == "" {
-		password = generatePassword()
+	if repo.Password == "" {
+		repo.Password = generatePassword()
 		PrintWarning("No password configured for backend '%s'", name)
-		fmt.Printf("\n  Generated password: %s\n\n", password)
+		fmt.Printf("\n  Generated password: %s\n\n", repo.Password)
 	}
 
-	executor := restic.NewExecutor(backend.Repository, password)
-	executor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
-	executor.Verbose = IsVerbose()
+	executor := newExecutor(repo)
 
 	if executor.IsInitialized() {
 		PrintSuccess("Backend '%s' already initialized", name)
@@ -128,12 +128,13 @@ func initBackend(cfg *config.Config, name string) error {
 
 	if isCopyTarget && cfg.Repository != "" {
 		PrintInfo("Copying chunker parameters from primary repository for optimal deduplication...")
+		from, _ := cfg.Resolve(config.SelectPrimary)
 		opts := restic.InitOptions{
-			FromRepository:     cfg.Repository,
-			FromPassword:       cfg.GetPassword(),
+			FromRepository:     from.URL,
+			FromPassword:       from.Password,
 			CopyChunkerParams:  true,
-			FromAWSAccessKeyID: cfg.GetAWSAccessKeyID(),
-			FromAWSSecret:      cfg.GetAWSSecretAccessKey(),
+			FromAWSAccessKeyID: from.AWSAccessKeyID,
+			FromAWSSecret:      from.AWSSecretAccessKey,
 		}
 		if err := executor.InitWithOptions(opts); err != nil {
 			PrintError("Failed to initialize backend '%s': %v", name, err)
@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"resticm/internal/config"
+	"resticm/internal/redact"
+	"resticm/internal/restic"
+)
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the keys (passwords) of the repositories",
+	Long: `Manage the keys of the restic repositories.
+
+Each command applies to the active backend (the primary by default); use
+--backends to choose others, e.g. 'all' or 'primary,offsite'.`,
+}
+
+var keyListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the keys of the repositories",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runKeyList(cmd)
+	},
+}
+
+var keyAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a key to the repositories",
+	Long: `Add a key to the repositories, e.g. for another administrator. The
+configuration is not changed: resticm keeps using its own key.
+
+Without --new-password-file a strong password is generated and printed once.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runKeyAdd(cmd)
+	},
+}
+
+var keyRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace the key resticm uses with a new password",
+	Long: `Replace the key resticm uses with a new password, for each repository:
+
+  1. add a key with the new password (generated unless --new-password-file)
+  2. verify the new password opens the repository
+  3. update the password in the configuration file, keeping its comments
+  4. remove the old key
+
+When a step fails the old key is kept, and a key added before the
+configuration could be updated is removed again, so the configuration always
+holds a working password.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runKeyRotate(cmd)
+	},
+}
+
+var keyRemoveCmd = &cobra.Command{
+	Use:   "remove <key-id>",
+	Short: "Remove a key from a repository",
+	Long: `Remove a key from a repository by ID (or unique ID prefix, as shown by
+'resticm key list'). The key resticm uses cannot be removed; replace it with
+'resticm key rotate' instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runKeyRemove(cmd, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keyCmd)
+	keyCmd.AddCommand(keyListCmd, keyAddCmd, keyRotateCmd, keyRemoveCmd)
+	for _, c := range []*cobra.Command{keyListCmd, keyAddCmd, keyRotateCmd, keyRemoveCmd} {
+		addBackendsFlag(c)
+	}
+	for _, c := range []*cobra.Command{keyAddCmd, keyRotateCmd} {
+		c.Flags().String("new-password-file", "", "Read the new password from a file instead of generating one")
+	}
+	keyAddCmd.Flags().String("user", "", "Username recorded in the new key")
+	keyAddCmd.Flags().String("host", "", "Hostname recorded in the new key")
+}
+
+// keyRepositories resolves the repositories a key command operates on
+func keyRepositories(cmd *cobra.Command, cfg *config.Config) ([]*config.Repository, error) {
+	names, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return activeBackends(cfg, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]*config.Repository, 0, len(names))
+	for _, name := range names {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// repositoryNames returns the backend names of the repositories
+func repositoryNames(repos []*config.Repository) []string {
+	names := make([]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.Name
+	}
+	return names
+}
+
+// newKeyPassword returns the password of a new key: the content of the
+// --new-password-file, else a generated one
+func newKeyPassword(cmd *cobra.Command) (password string, generated bool, err error) {
+	file, _ := cmd.Flags().GetString("new-password-file")
+	if file == "" {
+		return generatePassword(), true, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read new password: %w", err)
+	}
+	password = strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", false, fmt.Errorf("new password file %s is empty", file)
+	}
+	return password, false, nil
+}
+
+func runKeyList(cmd *cobra.Command) (err error) {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	repos, err := keyRepositories(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	all := make(map[string][]restic.Key)
+	var failed int
+	for _, repo := range repos {
+		executor := newExecutor(repo)
+		executor.NoLock = true
+		keys, err := executor.ListKeys()
+		if err != nil {
+			PrintError("Failed to list keys of %s: %v", repo.Name, err)
+			failed++
+			continue
+		}
+		if IsJSONOutput() {
+			all[repo.Name] = keys
+			continue
+		}
+
+		fmt.Printf("\n═══ %s ═══\n", repo.Name)
+		fmt.Printf("  %-10s %-12s %-20s %s\n", "ID", "USER", "HOST", "CREATED")
+		for _, k := range keys {
+			marker := " "
+			if k.Current {
+				marker = "*"
+			}
+			fmt.Printf("%s %-10s %-12s %-20s %s\n", marker, shortKeyID(k.ID), k.UserName, k.HostName, k.Created)
+		}
+	}
+
+	if IsJSONOutput() {
+		output, _ := json.MarshalIndent(all, "", "  ")
+		fmt.Println(string(output))
+	} else {
+		fmt.Println("\n* key used by resticm")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to list keys of %d repository(ies)", failed)
+	}
+	return nil
+}
+
+func runKeyAdd(cmd *cobra.Command) (err error) {
+	startTime := time.Now()
+
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	user, _ := cmd.Flags().GetString("user")
+	host, _ := cmd.Flags().GetString("host")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
+
+	// Build flag map for logging
+	flagMap := make(map[string]interface{})
+	if user != "" {
+		flagMap["user"] = user
+	}
+	if host != "" {
+		flagMap["host"] = host
+	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
+
+	// Log command start with context
+	LogCommandStart(cmd, flagMap)
+
+	// Ensure we log command end
+	defer func() {
+		LogCommandEnd(cmd, startTime, err)
+	}()
+
+	repos, err := keyRepositories(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Acquire lock
+	lock, err := acquireLock(repositoryNames(repos)...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	var failed int
+	for _, repo := range repos {
+		password, generated, err := newKeyPassword(cmd)
+		if err != nil {
+			return err
+		}
+
+		if IsDryRun() {
+			PrintInfo("[DRY RUN] Would add a key to %s", repo.Name)
+			continue
+		}
+
+		PrintInfo("Adding a key to %s...", repo.Name)
+		if err := newExecutor(repo).AddKey(password, restic.KeyOptions{User: user, Host: host}); err != nil {
+			PrintError("Failed to add a key to %s: %v", repo.Name, err)
+			failed++
+			continue
+		}
+		if generated {
+			fmt.Printf("\n  Generated password for %s: %s\n\n", repo.Name, password)
+		}
+		redact.AddSecret(password)
+		PrintSuccess("Key added to %s", repo.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to add a key to %d repository(ies)", failed)
+	}
+	return nil
+}
+
+func runKeyRotate(cmd *cobra.Command) (err error) {
+	startTime := time.Now()
+
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	backendsExpr, _ := cmd.Flags().GetString("backends")
+
+	// Build flag map for logging
+	flagMap := make(map[string]interface{})
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
+
+	// Log command start with context
+	LogCommandStart(cmd, flagMap)
+
+	// Ensure we log command end
+	defer func() {
+		LogCommandEnd(cmd, startTime, err)
+	}()
+
+	configPath := config.GetLoadedConfigPath()
+	if configPath == "" {
+		return fmt.Errorf("configuration file path unknown")
+	}
+
+	repos, err := keyRepositories(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Acquire lock
+	lock, err := acquireLock(repositoryNames(repos)...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	var failed int
+	for _, repo := range repos {
+		password, _, err := newKeyPassword(cmd)
+		if err != nil {
+			return err
+		}
+		redact.AddSecret(password)
+
+		PrintInfo("🔑 Rotating the key of %s...", repo.Name)
+		if err := rotateKey(repo, configPath, password); err != nil {
+			PrintError("Key rotation failed on %s: %v", repo.Name, err)
+			failed++
+			continue
+		}
+		if !IsDryRun() {
+			PrintSuccess("Key of %s rotated, %s updated", repo.Name, configPath)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("key rotation failed on %d repository(ies)", failed)
+	}
+	return nil
+}
+
+// rotateKey replaces the key resticm uses for a repository with a key of the
+// new password. The old key is only removed once the configuration file
+// holds the new password.
+func rotateKey(repo *config.Repository, configPath, password string) error {
+	if repo.IsPrimary() && os.Getenv("RESTIC_PASSWORD") != "" {
+		return fmt.Errorf("the password is read from RESTIC_PASSWORD: add a key with 'resticm key add' and update the variable instead")
+	}
+
+	executor := newExecutor(repo)
+	old, err := executor.CurrentKey()
+	if err != nil {
+		return fmt.Errorf("failed to read the current key: %w", err)
+	}
+
+	if IsDryRun() {
+		PrintInfo("[DRY RUN] Would add a new key, update %s and remove key %s", configPath, shortKeyID(old.ID))
+		return nil
+	}
+
+	// 1. Add the new key
+	if err := executor.AddKey(password, restic.KeyOptions{}); err != nil {
+		return fmt.Errorf("failed to add the new key: %w", err)
+	}
+
+	// 2. Verify the new password opens the repository
+	rotated := executor.WithPassword(password)
+	added, err := rotated.CurrentKey()
+	if err != nil {
+		return fmt.Errorf("the new password does not open the repository, old key kept: %w", err)
+	}
+	if added.ID == old.ID {
+		return fmt.Errorf("the new password opens the old key %s, old key kept", shortKeyID(old.ID))
+	}
+
+	// 3. Update the configuration, removing the new key again on failure
+	if err := config.SetPassword(configPath, repo.Name, password); err != nil {
+		if rmErr := executor.RemoveKey(added.ID); rmErr != nil {
+			return fmt.Errorf("failed to update the config file: %w (new key %s could not be removed: %v)", err, shortKeyID(added.ID), rmErr)
+		}
+		return fmt.Errorf("failed to update the config file, new key removed: %w", err)
+	}
+
+	// 4. Remove the old key
+	if err := rotated.RemoveKey(old.ID); err != nil {
+		return fmt.Errorf("config updated, but the old key %s could not be removed: %w", shortKeyID(old.ID), err)
+	}
+	return nil
+}
+
+func runKeyRemove(cmd *cobra.Command, id string) (err error) {
+	startTime := time.Now()
+
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	backendsExpr, _ := cmd.Flags().GetString("backends")
+
+	// Build flag map for logging
+	flagMap := map[string]interface{}{"key": id}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
+
+	// Log command start with context
+	LogCommandStart(cmd, flagMap)
+
+	// Ensure we log command end
+	defer func() {
+		LogCommandEnd(cmd, startTime, err)
+	}()
+
+	repos, err := keyRepositories(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Acquire lock
+	lock, err := acquireLock(repositoryNames(repos)...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+	if len(repos) != 1 {
+		return fmt.Errorf("a key belongs to one repository: select a single backend")
+	}
+	repo := repos[0]
+
+	executor := newExecutor(repo)
+	keys, err := executor.ListKeys()
+	if err != nil {
+		return fmt.Errorf("failed to list keys of %s: %w", repo.Name, err)
+	}
+	key, err := findKey(keys, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", repo.Name, err)
+	}
+	if key.Current {
+		return fmt.Errorf("key %s is the key resticm uses for %s; replace it with 'resticm key rotate'", shortKeyID(key.ID), repo.Name)
+	}
+
+	if IsDryRun() {
+		PrintInfo("[DRY RUN] Would remove key %s from %s", shortKeyID(key.ID), repo.Name)
+		return nil
+	}
+
+	if err := executor.RemoveKey(key.ID); err != nil {
+		return fmt.Errorf("failed to remove key %s from %s: %w", shortKeyID(key.ID), repo.Name, err)
+	}
+	PrintSuccess("Key %s removed from %s", shortKeyID(key.ID), repo.Name)
+	return nil
+}
+
+// findKey returns the key whose ID starts with id
+func findKey(keys []restic.Key, id string) (*restic.Key, error) {
+	var found *restic.Key
+	for i := range keys {
+		if id == "" || !strings.HasPrefix(keys[i].ID, id) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("key ID '%s' is ambiguous", id)
+		}
+		found = &keys[i]
+	}
+	if found == nil {
+		return nil, fmt.Errorf("key '%s' not found", id)
+	}
+	return found, nil
+}
+
+// shortKeyID returns the 8 character form of a key ID, as restic shows it
+func shortKeyID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"resticm/internal/config"
+	"resticm/internal/restic"
+)
+
+// fakeKeyRestic installs a fake restic keeping each key as a file holding
+// its password, and returns the key directory
+func fakeKeyRestic(t *testing.T, password string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake restic script requires a POSIX shell")
+	}
+
+	binDir := t.TempDir()
+	keyDir := filepath.Join(binDir, "keys")
+	if err := os.Mkdir(keyDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(keyDir, "oldkey01"), []byte(password), 0600); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+keys=` + keyDir + `
+case "$1 $2" in
+  "key list")
+    out=""; found=""
+    for f in "$keys"/*; do
+      id=$(basename "$f"); current=false
+      if [ "$(cat "$f")" = "$RESTIC_PASSWORD" ]; then current=true; found=1; fi
+      out="$out${out:+,}{\"id\":\"$id\",\"userName\":\"root\",\"hostName\":\"vm\",\"created\":\"2026-10-18 09:00:00\",\"current\":$current}"
+    done
+    [ -z "$found" ] && exit 12
+    echo "[$out]" ;;
+  "key add") cp "$4" "$keys/newkey$(ls "$keys" | wc -l | tr -d ' ')" ;;
+  "key remove") rm "$keys/$3" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(binDir, "restic"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake restic: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("RESTIC_PASSWORD", "")
+	return keyDir
+}
+
+func keyFiles(t *testing.T, keyDir string) map[string]string {
+	t.Helper()
+	entries, _ := os.ReadDir(keyDir)
+	keys := make(map[string]string)
+	for _, e := range entries {
+		data, _ := os.ReadFile(filepath.Join(keyDir, e.Name()))
+		keys[e.Name()] = string(data)
+	}
+	return keys
+}
+
+func TestRotateKey(t *testing.T) {
+	keyDir := fakeKeyRestic(t, "old-password")
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := "# main repository\nrepository: \"/tmp/repo\"\npassword: \"old-password\" # keep quoted\n"
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	repo := &config.Repository{Name: config.SelectPrimary, URL: "/tmp/repo", Password: "old-password"}
+
+	if err := rotateKey(repo, configPath, "new-password"); err != nil {
+		t.Fatalf("rotateKey() error = %v", err)
+	}
+
+	keys := keyFiles(t, keyDir)
+	if len(keys) != 1 || keys["oldkey01"] != "" {
+		t.Errorf("keys after rotation = %v, want only the new key", keys)
+	}
+	data, _ := os.ReadFile(configPath)
+	want := "# main repository\nrepository: \"/tmp/repo\"\npassword: \"new-password\" # keep quoted\n"
+	if string(data) != want {
+		t.Errorf("config = %q, want %q", data, want)
+	}
+}
+
+func TestRotateKeyKeepsOldKeyOnConfigFailure(t *testing.T) {
+	keyDir := fakeKeyRestic(t, "old-password")
+
+	// The password is not in the config file: it cannot be updated
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("repository: \"/tmp/repo\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	repo := &config.Repository{Name: config.SelectPrimary, URL: "/tmp/repo", Password: "old-password"}
+
+	err := rotateKey(repo, configPath, "new-password")
+	if err == nil || !strings.Contains(err.Error(), "new key removed") {
+		t.Fatalf("rotateKey() error = %v, want the new key removed", err)
+	}
+	if keys := keyFiles(t, keyDir); len(keys) != 1 || keys["oldkey01"] != "old-password" {
+		t.Errorf("keys = %v, want only the old key", keys)
+	}
+}
+
+func TestFindKey(t *testing.T) {
+	keys := []restic.Key{{ID: "4f3a9c1e77"}, {ID: "4f3b0000aa"}, {ID: "9d0e1f2a3b"}}
+
+	if k, err := findKey(keys, "9d0e"); err != nil || k.ID != "9d0e1f2a3b" {
+		t.Errorf("findKey(9d0e) = %v, %v", k, err)
+	}
+	if _, err := findKey(keys, "4f3"); err == nil {
+		t.Error("findKey(4f3) should be ambiguous")
+	}
+	if _, err := findKey(keys, "ffff"); err == nil {
+		t.Error("findKey(ffff) should not be found")
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"resticm/internal/security"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Inspect resticm lock files",
+	Long: `Inspect the local lock files preventing concurrent resticm runs.
+
+The scope of the lock is set with lock_scope in the configuration: one lock
+for the whole machine (global), one per config file (config) or one per
+repository (repository).`,
+}
+
+var lockStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the resticm locks on this machine",
+	Long: `List the resticm lock files of the system and of the current user, with
+the process holding each lock and its scope. A lock file not held by any
+process was left by a crashed run and can be removed with 'resticm unlock'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLockStatus()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	lockCmd.AddCommand(lockStatusCmd)
+}
+
+func runLockStatus() error {
+	locks := security.ListLocks(security.LockDirs()...)
+
+	if IsJSONOutput() {
+		if locks == nil {
+			locks = []security.LockStatus{}
+		}
+		output, _ := json.MarshalIndent(locks, "", "  ")
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if len(locks) == 0 {
+		PrintInfo("No resticm locks on this machine")
+		return nil
+	}
+
+	gray := color.New(color.FgHiBlack)
+	fmt.Println()
+	for _, lock := range locks {
+		if lock.Held {
+			fmt.Printf("🔒 %s\n", lock.Path)
+		} else {
+			fmt.Printf("💤 %s ", lock.Path)
+			_, _ = gray.Println("(stale, not held)")
+		}
+		h := lock.Holder
+		scope := h.Scope
+		if h.Key != "" {
+			scope += " " + h.Key
+		}
+		fmt.Printf("   Scope:   %s\n", scope)
+		if h.PID != 0 {
+			fmt.Printf("   PID:     %d\n", h.PID)
+		}
+		if h.Command != "" {
+			fmt.Printf("   Command: %s\n", h.Command)
+		}
+		if !h.Started.IsZero() {
+			fmt.Printf("   Started: %s\n", h.Started.Format("2006-01-02 15:04:05"))
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"resticm/internal/config"
 	"resticm/internal/restic"
+	"resticm/internal/security"
 )
 
 // LockCheckResult contains the result of a lock verification
@@ -35,8 +37,8 @@ func VerifyNoStaleLocks(cfg *config.Config, checkBackends bool) *LockCheckResult
 	PrintInfo("🔐 Verifying no stale locks remain...")
 
 	// Check primary repository
-	executor := restic.NewExecutor(cfg.Repository, cfg.GetPassword())
-	executor.SetAWSCredentials(cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey())
+	primary, _ := cfg.Resolve(config.SelectPrimary)
+	executor := newExecutor(primary)
 
 	if lockResult, err := executor.VerifyNoStaleLocks(hostname); err != nil {
 		PrintWarning("Could not verify locks on primary: %v", err)
@@ -60,13 +62,12 @@ func VerifyNoStaleLocks(cfg *config.Config, checkBackends bool) *LockCheckResult
 	// Check copy backends if requested
 	if checkBackends && len(cfg.CopyToBackends) > 0 {
 		for _, backendName := range cfg.CopyToBackends {
-			backend, ok := cfg.Backends[backendName]
-			if !ok {
+			backend, err := cfg.Resolve(backendName)
+			if err != nil {
 				continue
 			}
 
-			backendExecutor := restic.NewExecutor(backend.Repository, backend.Password)
-			backendExecutor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
+			backendExecutor := newExecutor(backend)
 
 			if lockResult, err := backendExecutor.VerifyNoStaleLocks(hostname); err != nil {
 				PrintWarning("Could not verify locks on %s: %v", backendName, err)
@@ -118,3 +119,45 @@ func VerifyNoStaleLocksSingleRepo(executor *restic.Executor, repoName string) *L
 
 	return result
 }
+
+// removeStaleLocks removes the locks this host left on a repository from
+// processes that are no longer running, when auto_remove_stale_locks is set.
+// Locks of other hosts are never removed. Every removal is logged and
+// notified, since it means a restic process died holding its lock.
+func removeStaleLocks(executor *restic.Executor, repoName string) {
+	if cfg == nil || !cfg.AutoRemoveStaleLocks || IsDryRun() {
+		return
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return
+	}
+
+	removed, err := executor.RemoveStaleLocks(hostname, security.ProcessAlive)
+	var lines []string
+	for _, lock := range removed {
+		line := fmt.Sprintf("PID %d (%s) from %s", lock.PID, lock.Username, lock.Time.Format("2006-01-02 15:04:05"))
+		lines = append(lines, line)
+		PrintWarning("Removed stale lock of %s on %s", line, repoName)
+		if logger != nil {
+			logger.Warn("Removed stale restic lock on %s: host %s, %s", repoName, hostname, line)
+		}
+	}
+	if err != nil {
+		PrintWarning("Could not remove stale locks on %s: %v", repoName, err)
+		if logger != nil {
+			logger.Warn("Could not remove stale locks on %s: %v", repoName, err)
+		}
+	}
+
+	if len(removed) > 0 {
+		_ = GetNotifier(false).NotifyError(
+			"⚠️ Stale Lock Removed",
+			fmt.Sprintf("resticm removed %d stale lock(s) left by dead processes of this host on %s:\n%s\n\n"+
+				"A restic process was interrupted; check the previous run.",
+				len(removed), repoName, strings.Join(lines, "\n")),
+			err,
+			map[string]string{"repository": repoName, "host": hostname},
+		)
+	}
+}
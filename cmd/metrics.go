@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"resticm/internal/config"
+	"resticm/internal/history"
+	"resticm/internal/metrics"
+	"resticm/internal/restic"
+	"resticm/internal/status"
+)
+
+// defaultMetricsListen is the address served when metrics.listen is not set
+const defaultMetricsListen = ":9947"
+
+// defaultMetricsCacheTTL is how long served metrics are reused between scrapes
+const defaultMetricsCacheTTL = 5 * time.Minute
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Export Prometheus metrics",
+	Long: `Export Prometheus metrics about runs and repositories.
+
+Run metrics (last run time and result per workflow, backup duration, bytes
+added, files processed) come from the run history. Repository metrics
+(snapshot count, latest snapshot age per host, size, locks, days since the
+last deep check) are queried from the primary and every copy backend.
+
+When metrics.textfile is configured, the file is rewritten atomically at the
+end of each run for the node_exporter textfile collector. The repositories
+are not queried then: their metrics are those of the last status or metrics
+collection.
+
+Examples:
+  resticm metrics                                  # Print metrics
+  resticm metrics --textfile /var/lib/node_exporter/textfile_collector/resticm.prom
+  resticm metrics serve --listen :9947             # Serve /metrics`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMetrics(cmd)
+	},
+}
+
+var metricsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve metrics over HTTP on /metrics",
+	Long: `Serve metrics over HTTP on /metrics until interrupted.
+
+Repositories are queried at most once per metrics.cache_ttl (default 5m),
+so frequent scrapes do not put load on the backends.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMetricsServe(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+	metricsCmd.AddCommand(metricsServeCmd)
+	metricsCmd.Flags().String("textfile", "", "Write metrics to this file instead of stdout")
+	metricsServeCmd.Flags().String("listen", "", "Address to listen on (default: metrics.listen or "+defaultMetricsListen+")")
+}
+
+func runMetrics(cmd *cobra.Command) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	if err := restic.CheckResticInstalled(); err != nil {
+		return err
+	}
+
+	set, err := collectMetrics(cfg)
+	if err != nil {
+		return err
+	}
+
+	textfile, _ := cmd.Flags().GetString("textfile")
+	if textfile == "" {
+		_, err = set.WriteTo(os.Stdout)
+		return err
+	}
+
+	if err := metrics.WriteTextfile(textfile, set); err != nil {
+		return err
+	}
+	PrintSuccess("Metrics written to %s", textfile)
+	return nil
+}
+
+func runMetricsServe(cmd *cobra.Command) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	if err := restic.CheckResticInstalled(); err != nil {
+		return err
+	}
+
+	listen, _ := cmd.Flags().GetString("listen")
+	if listen == "" {
+		listen = cfg.Metrics.Listen
+	}
+	if listen == "" {
+		listen = defaultMetricsListen
+	}
+
+	ttl := defaultMetricsCacheTTL
+	if cfg.Metrics.CacheTTL != "" {
+		d, err := config.ParseDuration(cfg.Metrics.CacheTTL)
+		if err != nil {
+			return fmt.Errorf("metrics.cache_ttl: %w", err)
+		}
+		ttl = d
+	}
+
+	handler := metrics.NewHandler(func() (*metrics.Set, error) {
+		if logger != nil {
+			logger.Debug("Collecting metrics")
+		}
+		return collectMetrics(cfg)
+	}, ttl)
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           metrics.NewServeMux(handler),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	PrintInfo("Serving metrics on http://%s/metrics", listen)
+	if logger != nil {
+		logger.Info("Serving metrics on %s", listen)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("metrics server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// collectMetrics gathers run metrics from the history and repository metrics
+// from every backend
+func collectMetrics(cfg *config.Config) (*metrics.Set, error) {
+	set, err := historyMetrics(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := collectReport(cfg, "", cfg.Metrics.NoSize)
+	metrics.AddBackends(set, report.Backends, time.Now())
+
+	return set, nil
+}
+
+// historyMetrics returns a set with the run metrics of the history. current
+// is included when history is disabled.
+func historyMetrics(cfg *config.Config, current *history.Run) (*metrics.Set, error) {
+	set := metrics.NewSet()
+
+	var runs []*history.Run
+	if cfg.History.Enabled {
+		var err error
+		runs, err = history.NewStore(config.StateDir()).List(history.Filter{})
+		if err != nil {
+			return nil, err
+		}
+	} else if current != nil {
+		runs = []*history.Run{current}
+	}
+	metrics.AddRuns(set, runs)
+	return set, nil
+}
+
+// writeMetricsTextfile refreshes the configured textfile after a run. The
+// repositories are not queried again: their metrics come from the report
+// collected by this run, else from the last report cached by status or
+// metrics, so the end of a run never waits on a slow backend.
+func writeMetricsTextfile(run *history.Run) {
+	if cfg == nil || cfg.Metrics.Textfile == "" || run.DryRun {
+		return
+	}
+
+	set, err := historyMetrics(cfg, run)
+	if err == nil {
+		if backends := reportedBackends(); backends != nil {
+			metrics.AddBackends(set, backends, time.Now())
+		}
+		err = metrics.WriteTextfile(cfg.Metrics.Textfile, set)
+	}
+	if err != nil && logger != nil {
+		logger.Warn("Failed to write metrics textfile: %v", err)
+	}
+}
+
+// metricsReportFile caches the last repository report for the textfile
+// written after each run, in the state directory
+const metricsReportFile = "metrics-report.json"
+
+// cachedBackend is a backend status as cached in the metrics report file
+type cachedBackend struct {
+	*status.BackendStatus
+	SizeKnown bool `json:"size_known"`
+}
+
+var (
+	reportMu sync.Mutex
+	// lastReport is the repository report collected by this process
+	lastReport *status.Report
+)
+
+// rememberReport keeps a report of every backend for the metrics textfile
+// and caches it for later runs
+func rememberReport(report *status.Report) {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	lastReport = report
+
+	cached := make([]cachedBackend, len(report.Backends))
+	for i, b := range report.Backends {
+		cached[i] = cachedBackend{BackendStatus: b, SizeKnown: b.SizeKnown}
+	}
+	data, err := json.Marshal(cached)
+	if err == nil {
+		dir := config.StateDir()
+		if err = os.MkdirAll(dir, 0700); err == nil {
+			err = os.WriteFile(filepath.Join(dir, metricsReportFile), data, 0600)
+		}
+	}
+	if err != nil && logger != nil {
+		logger.Debug("Failed to cache the repository report: %v", err)
+	}
+}
+
+// reportedBackends returns the backends of the report collected by this
+// process, else of the cached report; nil when there is none
+func reportedBackends() []*status.BackendStatus {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	if lastReport != nil {
+		return lastReport.Backends
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.StateDir(), metricsReportFile))
+	if err != nil {
+		return nil
+	}
+	var cached []cachedBackend
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	var backends []*status.BackendStatus
+	for _, c := range cached {
+		if c.BackendStatus == nil {
+			continue
+		}
+		c.BackendStatus.SizeKnown = c.SizeKnown
+		backends = append(backends, c.BackendStatus)
+	}
+	return backends
+}
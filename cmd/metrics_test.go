@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"resticm/internal/config"
+	"resticm/internal/history"
+	"resticm/internal/status"
+)
+
+func TestWriteMetricsTextfileDoesNotQueryRepositories(t *testing.T) {
+	// No restic in PATH: the textfile must be written from the run and the
+	// report already collected
+	t.Setenv("PATH", t.TempDir())
+
+	textfile := filepath.Join(t.TempDir(), "resticm.prom")
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	cfg = &config.Config{
+		Repository: "/nonexistent/repo",
+		Metrics:    config.MetricsConfig{Textfile: textfile},
+	}
+
+	reportMu.Lock()
+	oldReport := lastReport
+	lastReport = &status.Report{Backends: []*status.BackendStatus{
+		{Name: "primary", SnapshotCount: 4},
+	}}
+	reportMu.Unlock()
+	defer func() {
+		reportMu.Lock()
+		lastReport = oldReport
+		reportMu.Unlock()
+	}()
+
+	run := history.NewRun("test", "backup", nil)
+	run.Finish(nil)
+	writeMetricsTextfile(run)
+
+	data, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatalf("textfile not written: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`resticm_last_run_success{workflow="default"} 1`,
+		`resticm_snapshots{backend="primary"} 4`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("textfile missing %q:\n%s", want, out)
+		}
+	}
+}
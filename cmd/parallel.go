@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"resticm/internal/config"
+	"resticm/internal/hooks"
+	"resticm/internal/restic"
+)
+
+// Outcomes of a backend step in the summary
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// outputMu keeps the lines of backends running in parallel from interleaving
+var outputMu sync.Mutex
+
+// backendStep is the outcome of one step run on a copy backend
+type backendStep struct {
+	Step     string
+	Status   string
+	Duration time.Duration
+	Err      error
+}
+
+// backendRun runs the steps of one copy backend. When backends run in
+// parallel, its messages and the restic output are prefixed with the backend
+// name so the interleaved logs stay readable.
+type backendRun struct {
+	Name  string
+	Repo  *config.Repository // nil when the backend is not configured
+	Steps []backendStep
+
+	prefix string // "[name] " when running in parallel
+}
+
+// copyParallelism returns how many copy backends are synchronized at once
+func copyParallelism() int {
+	if cfg == nil || cfg.CopyParallelism < 1 {
+		return 1
+	}
+	return cfg.CopyParallelism
+}
+
+// forEachBackend runs fn for each backend, at most parallelism at a time, and
+// returns the runs in backend order. With a parallelism of 1 the backends
+// run one after the other with the framed output.
+func forEachBackend(names []string, parallelism int, fn func(b *backendRun)) []*backendRun {
+	runs := make([]*backendRun, len(names))
+	for i, name := range names {
+		runs[i] = &backendRun{Name: name}
+		if cfg != nil {
+			runs[i].Repo, _ = cfg.Resolve(name)
+		}
+	}
+	if parallelism <= 1 || len(names) <= 1 {
+		for _, b := range runs {
+			fn(b)
+		}
+		return runs
+	}
+
+	PrintInfo("Synchronizing %d backends, %d at a time", len(names), parallelism)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, b := range runs {
+		b.prefix = "[" + b.Name + "] "
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(b *backendRun) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(b)
+		}(b)
+	}
+	wg.Wait()
+	return runs
+}
+
+// executor returns an executor for the backend, its output prefixed when
+// running in parallel
+func (b *backendRun) executor() *restic.Executor {
+	e := newExecutor(b.Repo)
+	e.DryRun = IsDryRun()
+	if b.prefix != "" {
+		e.Stdout = &prefixWriter{w: os.Stdout, prefix: b.prefix}
+		e.Stderr = &prefixWriter{w: os.Stderr, prefix: b.prefix}
+	}
+	return e
+}
+
+// step runs a workflow step on the backend through runStep and records its
+// outcome for the summary
+func (b *backendRun) step(hookRunner *hooks.Runner, name string, fn func() error) error {
+	if hookRunner != nil && b.prefix != "" {
+		// The hook output is prefixed like the restic output of the backend
+		r := *hookRunner
+		r.Stdout = &prefixWriter{w: os.Stdout, prefix: b.prefix}
+		hookRunner = &r
+	}
+	start := time.Now()
+	err := runStepWarn(hookRunner, name, b.Name, b.printWarning, fn)
+	status := statusOK
+	switch {
+	case stepSkipped(err):
+		status = statusSkipped
+	case err != nil:
+		status = statusFailed
+	}
+	b.Steps = append(b.Steps, backendStep{Step: name, Status: status, Duration: time.Since(start), Err: err})
+	return err
+}
+
+// fail records a step that failed before it could run
+func (b *backendRun) fail(name string, err error) {
+	recordStep(name, b.Name, time.Now(), err)
+	b.Steps = append(b.Steps, backendStep{Step: name, Status: statusFailed, Err: err})
+}
+
+// Errors returns the errors of the failed steps
+func (b *backendRun) Errors() []error {
+	var errs []error
+	for _, s := range b.Steps {
+		if s.Status == statusFailed {
+			errs = append(errs, s.Err)
+		}
+	}
+	return errs
+}
+
+// begin opens the framed output of the backend
+func (b *backendRun) begin() {
+	if b.prefix == "" {
+		fmt.Printf("\n  ┌─ Backend: %s\n", b.Name)
+	}
+}
+
+// progress prints a step of the backend
+func (b *backendRun) progress(msg string) {
+	b.print("  │ ", msg)
+}
+
+// end closes the framed output of the backend
+func (b *backendRun) end(msg string) {
+	b.print("  └─ ", msg)
+}
+
+// print prints msg after the frame, or after the backend prefix in parallel
+func (b *backendRun) print(frame, msg string) {
+	if b.prefix == "" {
+		fmt.Println(frame + msg)
+		return
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Println(b.prefix + msg)
+}
+
+// printError prints an error of the backend
+func (b *backendRun) printError(format string, a ...interface{}) {
+	b.locked(func() { PrintError("%s%s", b.prefix, fmt.Sprintf(format, a...)) })
+}
+
+// printWarning prints a warning of the backend
+func (b *backendRun) printWarning(format string, a ...interface{}) {
+	b.locked(func() { PrintWarning("%s%s", b.prefix, fmt.Sprintf(format, a...)) })
+}
+
+// printSuccess prints a success of the backend
+func (b *backendRun) printSuccess(format string, a ...interface{}) {
+	b.locked(func() { PrintSuccess("%s%s", b.prefix, fmt.Sprintf(format, a...)) })
+}
+
+// locked runs fn holding the output lock when running in parallel
+func (b *backendRun) locked(fn func()) {
+	if b.prefix != "" {
+		outputMu.Lock()
+		defer outputMu.Unlock()
+	}
+	fn()
+}
+
+// printBackendSummary prints a table of the steps run on each backend
+func printBackendSummary(runs []*backendRun) {
+	width := len("BACKEND")
+	steps := 0
+	for _, b := range runs {
+		width = max(width, len(b.Name))
+		steps += len(b.Steps)
+	}
+	if steps == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("  %-*s  %-7s  %-9s  %s\n", width, "BACKEND", "STEP", "STATUS", "DURATION")
+	for _, b := range runs {
+		for _, s := range b.Steps {
+			icon := "✅"
+			switch s.Status {
+			case statusFailed:
+				icon = "❌"
+			case statusSkipped:
+				icon = "⏭️ "
+			}
+			fmt.Printf("  %-*s  %-7s  %s %-7s  %s\n", width, b.Name, s.Step, icon, s.Status, s.Duration.Round(time.Second))
+		}
+	}
+}
+
+// prefixWriter prefixes each line written to w. Each write is emitted at
+// once under outputMu, so lines of parallel backends do not mix.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	midLine bool
+}
+
+// Write implements io.Writer
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	var buf bytes.Buffer
+	for rest := data; len(rest) > 0; {
+		if !p.midLine {
+			buf.WriteString(p.prefix)
+		}
+		i := bytes.IndexAny(rest, "\r\n")
+		if i < 0 {
+			buf.Write(rest)
+			p.midLine = true
+			break
+		}
+		buf.Write(rest[:i+1])
+		p.midLine = false
+		rest = rest[i+1:]
+	}
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
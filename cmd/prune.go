@@ -9,8 +9,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"resticm/internal/config"
-	"resticm/internal/restic"
-	"resticm/internal/security"
 )
 
 var pruneCmd = &cobra.Command{
@@ -20,7 +18,8 @@ var pruneCmd = &cobra.Command{
 
 By default, this command applies to the primary repository AND all configured
 copy backends to keep them synchronized. Use --primary-only to only affect
-the primary repository.`,
+the primary repository, or --backends to choose the backends (e.g. 'offsite'
+for a group, 'all,!wasabi').`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runPrune(cmd)
 	},
@@ -29,6 +28,7 @@ the primary repository.`,
 func init() {
 	rootCmd.AddCommand(pruneCmd)
 	pruneCmd.Flags().Bool("primary-only", false, "Only apply to primary repository (skip copy backends)")
+	addBackendsFlag(pruneCmd)
 }
 
 func runPrune(cmd *cobra.Command) (err error) {
@@ -40,12 +40,16 @@ func runPrune(cmd *cobra.Command) (err error) {
 	}
 
 	primaryOnly, _ := cmd.Flags().GetBool("primary-only")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
 	if primaryOnly {
 		flagMap["primary-only"] = true
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
 
 	// Log command start with context
 	LogCommandStart(cmd, flagMap)
@@ -55,9 +59,16 @@ func runPrune(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
+	backends, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return syncedBackends(cfg, primaryOnly)
+	})
+	if err != nil {
+		return err
+	}
+
 	// Acquire lock
-	lock := security.NewLock("")
-	if err = lock.Acquire(); err != nil {
+	lock, err := acquireLock(backends...)
+	if err != nil {
 		return err
 	}
 	defer func() { _ = lock.Release() }()
@@ -67,53 +78,35 @@ func runPrune(cmd *cobra.Command) (err error) {
 	hostname, _ := os.Hostname()
 
 	var pruneErrors []error
+	for i, name := range backends {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
+			PrintWarning("Backend '%s' not found in configuration, skipping", name)
+			continue
+		}
 
-	// Get active backend (if user explicitly selected one)
-	activeBackend, _ := config.GetActiveBackend()
-
-	// If user explicitly selected a specific backend, only operate on that one
-	if activeBackend != "" && activeBackend != "primary" {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
+		if i > 0 {
+			fmt.Println()
 		}
-		err := pruneOnBackend(activeBackend, backend.Repository, backend.Password,
-			backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
-		if err != nil {
+		if name == config.SelectPrimary {
+			PrintInfo("🧹 Running prune on primary repository...")
+		} else {
+			PrintInfo("🧹 Running prune on backend: %s", name)
+		}
+		err = pruneOnBackend(repo)
+		if err == nil {
+			continue
+		}
+		if len(backends) == 1 {
 			_ = notifier.NotifyError(
 				"❌ Prune Failed",
-				fmt.Sprintf("resticm prune failed on %s backend '%s'", hostname, activeBackend),
+				fmt.Sprintf("resticm prune failed on %s backend '%s'", hostname, name),
 				err,
-				map[string]string{"host": hostname, "backend": activeBackend},
+				map[string]string{"host": hostname, "backend": name},
 			)
+			return err
 		}
-		return err
-	}
-
-	// Run prune on primary repository
-	PrintInfo("🧹 Running prune on primary repository...")
-	if err := pruneOnBackend("primary", cfg.Repository, cfg.GetPassword(),
-		cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey()); err != nil {
-		pruneErrors = append(pruneErrors, fmt.Errorf("primary: %w", err))
-	}
-
-	// Apply to copy backends unless --primary-only is set
-	if !primaryOnly && len(cfg.CopyToBackends) > 0 {
-		for _, backendName := range cfg.CopyToBackends {
-			backend, ok := cfg.Backends[backendName]
-			if !ok {
-				PrintWarning("Backend '%s' not found in configuration, skipping", backendName)
-				continue
-			}
-
-			fmt.Println()
-			PrintInfo("🧹 Running prune on backend: %s", backendName)
-			if err := pruneOnBackend(backendName, backend.Repository, backend.Password,
-				backend.AWSAccessKeyID, backend.AWSSecretAccessKey); err != nil {
-				PrintError("Prune failed on backend '%s': %v", backendName, err)
-				pruneErrors = append(pruneErrors, fmt.Errorf("%s: %w", backendName, err))
-			}
-		}
+		pruneErrors = append(pruneErrors, fmt.Errorf("%s: %w", name, err))
 	}
 
 	// Send notification if any prune failed
@@ -135,13 +128,16 @@ func runPrune(cmd *cobra.Command) (err error) {
 	return nil
 }
 
-func pruneOnBackend(name, repo, password, awsKey, awsSecret string) error {
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+func pruneOnBackend(repo *config.Repository) error {
+	name := repo.Name
+	executor := newExecutor(repo)
 	executor.DryRun = IsDryRun()
-	executor.Verbose = IsVerbose()
 
-	if err := executor.Prune(); err != nil {
+	err := runStep(newHookRunner(), "prune", name, executor.Prune)
+	if stepSkipped(err) {
+		return nil
+	}
+	if err != nil {
 		PrintError("Prune failed on %s: %v", name, err)
 		return err
 	}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"resticm/internal/config"
+	"resticm/internal/redact"
+	"resticm/internal/restic"
+	"resticm/internal/status"
+)
+
+var replicationCmd = &cobra.Command{
+	Use:   "replication",
+	Short: "Inspect the replication to the copy backends",
+}
+
+var replicationStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Compare the snapshots of the primary and each copy backend",
+	Long: `Compare the snapshots of the primary repository with those of every
+backend in copy_to_backends, or of the backends selected with --backends.
+
+Snapshots are matched by their original ID, which restic copy records on each
+copied snapshot. For each backend and host it reports the snapshots not
+replicated, the newest replicated snapshot and the lag: how long the oldest
+snapshot newer than the newest replicated one has been waiting.
+
+The lag is compared against replication_lag_warn and replication_lag_crit in
+the 'status' section of the configuration (default 26h and 50h). Like
+'resticm status', the exit code follows the Nagios/Icinga plugin convention
+(0 OK, 1 WARN, 2 CRIT, 3 UNKNOWN), and a notification is sent when a backend
+is not OK.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runReplicationStatus(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replicationCmd)
+	replicationCmd.AddCommand(replicationStatusCmd)
+	replicationStatusCmd.Flags().String("host", "", "Only compare snapshots from this host")
+	addBackendsFlag(replicationStatusCmd)
+}
+
+func runReplicationStatus(cmd *cobra.Command) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	host, _ := cmd.Flags().GetString("host")
+	backends, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return cfg.CopyToBackends, nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(backends) == 0 {
+		PrintInfo("No secondary backends configured for copy.")
+		return nil
+	}
+
+	thresholds, err := statusThresholds(cfg)
+	if err != nil {
+		return &ExitError{Code: status.Unknown.ExitCode(), Err: err}
+	}
+
+	if err := restic.CheckResticInstalled(); err != nil {
+		return &ExitError{Code: status.Unknown.ExitCode(), Err: err}
+	}
+
+	report := collectReplication(cfg, backends, host)
+	report.Evaluate(thresholds, time.Now())
+
+	if logger != nil {
+		logger.Info("Replication status: %s - %s", report.State, report.Summary())
+	}
+
+	if IsJSONOutput() {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(redact.String(string(output)))
+	} else {
+		printReplicationReport(report)
+	}
+
+	if report.State != status.OK {
+		hostname, _ := os.Hostname()
+		_ = GetNotifier(false).NotifyError(
+			"⚠️ Replication Lag",
+			fmt.Sprintf("resticm replication on %s is %s: %s", hostname, report.State, report.Summary()),
+			fmt.Errorf("replication %s", report.State),
+			map[string]string{
+				"host":       hostname,
+				"repository": cfg.Repository,
+				"state":      report.State.String(),
+			},
+		)
+		return &ExitError{Code: report.State.ExitCode()}
+	}
+	return nil
+}
+
+// collectReplication lists the snapshots of the primary and each backend and
+// compares them
+func collectReplication(cfg *config.Config, backends []string, host string) *status.ReplicationReport {
+	report := &status.ReplicationReport{}
+
+	primaryRepo, _ := cfg.Resolve(config.SelectPrimary)
+	primarySnapshots, err := replicationSnapshots(newExecutor(primaryRepo), host)
+	if err != nil {
+		report.PrimaryError = err.Error()
+		return report
+	}
+
+	now := time.Now()
+	for _, backendName := range backends {
+		replica := &status.Replica{Name: backendName, Hosts: []status.ReplicaHost{}}
+		report.Backends = append(report.Backends, replica)
+
+		backend, err := cfg.Resolve(backendName)
+		if err != nil {
+			replica.Error = "backend not found in configuration"
+			continue
+		}
+		replica.Repository = backend.URL
+
+		snapshots, err := replicationSnapshots(newExecutor(backend), host)
+		if err != nil {
+			replica.Error = err.Error()
+			continue
+		}
+		replica.Hosts = status.CompareReplica(primarySnapshots, snapshots, now)
+	}
+	return report
+}
+
+// replicationSnapshots lists the snapshots of a repository, restricted to
+// host when set
+func replicationSnapshots(executor *restic.Executor, host string) ([]status.Snapshot, error) {
+	executor.Verbose = IsVerbose()
+	executor.NoLock = true
+
+	snapshots, err := executor.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	var result []status.Snapshot
+	for _, s := range snapshots {
+		if host != "" && s.Hostname != host {
+			continue
+		}
+		result = append(result, status.Snapshot{
+			ID:       s.ID,
+			Original: s.Original,
+			Hostname: s.Hostname,
+			Time:     s.Time,
+		})
+	}
+	return result, nil
+}
+
+// printReplicationReport prints the human-readable report
+func printReplicationReport(report *status.ReplicationReport) {
+	fmt.Println()
+	fmt.Println("═══════════════════════════════════════════════════")
+	fmt.Println(" RESTICM REPLICATION")
+	fmt.Println("═══════════════════════════════════════════════════")
+
+	if report.PrimaryError != "" {
+		fmt.Println()
+		PrintError("Primary repository not accessible: %s", report.PrimaryError)
+		return
+	}
+
+	const maxMissing = 10
+	for _, b := range report.Backends {
+		fmt.Printf("\n  ┌─ %s [%s]\n", b.Name, b.State)
+		if b.Repository != "" {
+			fmt.Printf("  │ Repository:  %s\n", redact.String(b.Repository))
+		}
+		for i, c := range b.Checks {
+			fmt.Printf("  │ %s %s\n", stateIcon(c.State), redact.String(c.Message))
+			if b.Error != "" || i >= len(b.Hosts) {
+				continue
+			}
+			h := b.Hosts[i]
+			fmt.Printf("  │    %d/%d replicated", h.Replicated, h.Snapshots)
+			if !h.ReplicatedLatest.IsZero() {
+				fmt.Printf(", newest %s", h.ReplicatedLatest.Local().Format("2006-01-02 15:04"))
+			}
+			fmt.Printf(" (primary %s)\n", h.PrimaryLatest.Local().Format("2006-01-02 15:04"))
+			if len(h.Missing) > 0 {
+				missing := h.Missing
+				more := ""
+				if len(missing) > maxMissing {
+					more = fmt.Sprintf(" (+%d more)", len(missing)-maxMissing)
+					missing = missing[:maxMissing]
+				}
+				fmt.Printf("  │    missing: %s%s\n", strings.Join(missing, ", "), more)
+			}
+		}
+		fmt.Println("  └─")
+	}
+
+	fmt.Println()
+	switch report.State {
+	case status.OK:
+		PrintSuccess("Replication: %s", report.Summary())
+	case status.Warn:
+		PrintWarning("Replication: %s - %s", report.State, report.Summary())
+	default:
+		PrintError("Replication: %s - %s", report.State, report.Summary())
+	}
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"resticm/internal/restic"
+	"resticm/internal/sources"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [snapshot]",
+	Short: "Restore files or a database dump",
+	Long: `Restore a snapshot of the backed up directories, or the dump of a
+database source.
+
+The snapshot defaults to "latest": the newest snapshot of this host containing
+the configured directories, or with --database the newest dump of that
+database (tagged db:<name>). Use --host to restore the backup of another
+machine.
+
+Without --output, --database prints the snapshot it would use and the
+commands to load the dump back into the database. Nothing is changed.
+
+Examples:
+  resticm restore --target /tmp/restore                  # Latest snapshot
+  resticm restore 3f2a1b9c --target /tmp/restore --include /etc/nginx
+  resticm restore --database app                         # Show instructions
+  resticm restore --database app --output app.sql        # Write the dump
+  resticm restore --database app --output - | psql app   # Stream the dump`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotID := "latest"
+		if len(args) == 1 {
+			snapshotID = args[0]
+		}
+		return runRestore(cmd, snapshotID)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().String("target", "", "Directory to restore files into")
+	restoreCmd.Flags().StringArray("include", nil, "Restore only paths matching this pattern (repeatable)")
+	restoreCmd.Flags().String("database", "", "Restore the dump of this database source")
+	restoreCmd.Flags().StringP("output", "o", "", "Write the database dump to this file ('-' for stdout)")
+	restoreCmd.Flags().String("host", "", "Select snapshots of this host (default: this machine)")
+}
+
+func runRestore(cmd *cobra.Command, snapshotID string) (err error) {
+	startTime := time.Now()
+
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	target, _ := cmd.Flags().GetString("target")
+	includes, _ := cmd.Flags().GetStringArray("include")
+	database, _ := cmd.Flags().GetString("database")
+	output, _ := cmd.Flags().GetString("output")
+	host, _ := cmd.Flags().GetString("host")
+
+	if database == "" && target == "" {
+		return fmt.Errorf("--target is required (or --database to restore a database dump)")
+	}
+	if database != "" && (target != "" || len(includes) > 0) {
+		return fmt.Errorf("--target and --include cannot be used with --database")
+	}
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	// stdout carries the dump, keep console logging out of it
+	if output == "-" && logger != nil {
+		logger = logger.WithoutConsole()
+	}
+
+	// Build flag map for logging
+	flagMap := map[string]interface{}{"snapshot": snapshotID}
+	if target != "" {
+		flagMap["target"] = target
+	}
+	if database != "" {
+		flagMap["database"] = database
+	}
+	if output != "" {
+		flagMap["output"] = output
+	}
+
+	// Log command start with context
+	LogCommandStart(cmd, flagMap)
+
+	// Ensure we log command end
+	defer func() {
+		LogCommandEnd(cmd, startTime, err)
+	}()
+
+	repo, err := cfg.ActiveRepository()
+	if err != nil {
+		return err
+	}
+	executor := newExecutor(repo)
+	executor.DryRun = IsDryRun()
+
+	if err := restic.CheckResticInstalled(); err != nil {
+		return err
+	}
+
+	if database != "" {
+		db, ok := findDatabase(database)
+		if !ok {
+			return fmt.Errorf("database source '%s' not found", database)
+		}
+		return restoreDatabase(executor, db, snapshotID, host, output)
+	}
+
+	filter := restic.SnapshotFilter{Host: host}
+	if snapshotID == "latest" {
+		// Database dumps are separate snapshots; only consider directory backups
+		filter.Paths = backupPaths()
+	}
+
+	if IsDryRun() {
+		PrintInfo("Restoring %s to %s (DRY RUN - no files will be written)...", snapshotID, target)
+	} else {
+		PrintInfo("Restoring %s to %s...", snapshotID, target)
+	}
+	if err := executor.Restore(snapshotID, restic.RestoreOptions{
+		Target:   target,
+		Includes: includes,
+		Filter:   filter,
+	}); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	PrintSuccess("Restored %s to %s", snapshotID, target)
+	return nil
+}
+
+// restoreDatabase writes a database dump to output, or prints how to restore
+// it when output is empty
+func restoreDatabase(executor *restic.Executor, db sources.Database, snapshotID, host, output string) error {
+	filter := restic.SnapshotFilter{Tags: []string{db.Tag()}, Host: host}
+	path := "/" + db.Filename()
+
+	if output == "" {
+		printDatabaseRestore(db, snapshotID, host)
+		return nil
+	}
+
+	if IsDryRun() {
+		fmt.Fprintf(os.Stderr, "ℹ️  [DRY-RUN] Would write %s from snapshot %s (tag %s, host %s) to %s\n",
+			path, snapshotID, db.Tag(), host, output)
+		return nil
+	}
+
+	if output == "-" {
+		return executor.Dump(snapshotID, path, filter, os.Stdout)
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := executor.Dump(snapshotID, path, filter, f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(output)
+		return fmt.Errorf("failed to dump %s: %w", db.Name, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	PrintSuccess("Database %s written to %s", db.Name, output)
+	return nil
+}
+
+// printDatabaseRestore prints the steps to restore a database dump
+func printDatabaseRestore(db sources.Database, snapshotID, host string) {
+	selector := ""
+	if snapshotID != "latest" {
+		selector = " " + snapshotID
+	}
+	hostFlag := ""
+	if current, _ := os.Hostname(); host != current {
+		hostFlag = " --host " + host
+	}
+	base := "resticm restore" + selector + " --database " + db.Name + hostFlag
+
+	fmt.Printf("\n🗄️  Database %s (%s)\n", db.Name, db.Type)
+	fmt.Printf("   Snapshot: %s tagged %s from host %s\n", snapshotID, db.Tag(), host)
+	fmt.Printf("   File:     /%s\n\n", db.Filename())
+
+	if db.Type == sources.TypeSQLite {
+		fmt.Println("To restore, write the database copy next to the original:")
+		fmt.Printf("   %s --output %s.restored\n", base, db.Path)
+		fmt.Println("then stop the application using it and replace the file:")
+		fmt.Printf("   mv %s.restored %s\n\n", db.Path, db.Path)
+		return
+	}
+
+	fmt.Println("To restore, stream the dump into the database:")
+	fmt.Printf("   %s --output - | %s\n", base, db.RestoreCommand())
+	fmt.Println("or write it to a file first:")
+	fmt.Printf("   %s --output %s\n", base, db.Filename())
+	if db.Type == sources.TypePostgres {
+		fmt.Println("\nThe dump is plain SQL; restore into an empty database (createdb first) to avoid conflicts.")
+	} else {
+		fmt.Println("\nThe dump drops and recreates each table it contains.")
+	}
+	fmt.Println()
+}
@@ -1,21 +1,32 @@
 package cmd
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"resticm/internal/config"
+	"resticm/internal/history"
 	"resticm/internal/hooks"
 	"resticm/internal/logging"
 	"resticm/internal/notify"
+	"resticm/internal/redact"
 	"resticm/internal/restic"
 	"resticm/internal/security"
+	"resticm/internal/status"
 )
 
 // Version information (set by ldflags)
@@ -27,10 +38,12 @@ var (
 
 // Global flags
 var (
-	cfgFile    string
-	verbose    bool
-	dryRun     bool
-	jsonOutput bool
+	cfgFile     string
+	verbose     bool
+	dryRun      bool
+	jsonOutput  bool
+	lockWait    bool
+	lockTimeout string
 )
 
 // Global config instance
@@ -39,6 +52,18 @@ var cfg *config.Config
 // Global logger instance
 var logger *logging.Logger
 
+// runID identifies the current invocation in logs
+var runID = newRunID()
+
+// currentRun records the command being executed in the run history
+var currentRun *history.Run
+
+// runResultFile is the JSON file describing currentRun to hooks
+var runResultFile string
+
+// runResultMu serializes the writes of the run result file
+var runResultMu sync.Mutex
+
 // Color outputs
 var (
 	colorError   = color.New(color.FgRed, color.Bold)
@@ -63,11 +88,13 @@ Default behavior (no subcommand): runs backup + forget + copy`,
 	SilenceErrors: true, // We handle errors ourselves
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Skip config loading for commands that don't need it or handle it themselves
-		if cmd.Name() == "version" || cmd.Name() == "help" || cmd.Name() == "completion" || cmd.Name() == "run" {
+		if cmd.Name() == "version" || cmd.Name() == "help" || cmd.Name() == "completion" || cmd.Name() == "run" ||
+			cmd.Name() == "history" {
 			return nil
 		}
-		// Skip for context commands that manage config themselves
-		if cmd.Parent() != nil && cmd.Parent().Name() == "context" {
+		// Skip for context commands that manage config themselves, and lock
+		// commands that look at the whole machine
+		if cmd.Parent() != nil && (cmd.Parent().Name() == "context" || cmd.Parent().Name() == "lock") {
 			return nil
 		}
 
@@ -75,7 +102,11 @@ Default behavior (no subcommand): runs backup + forget + copy`,
 		var err error
 		cfg, err = config.Load(cfgFile)
 		if err != nil {
-			return fmt.Errorf("failed to load configuration: %w", err)
+			err = fmt.Errorf("failed to load configuration: %w", err)
+			if isMonitoringCheck(cmd) {
+				return &ExitError{Code: status.Unknown.ExitCode(), Err: err}
+			}
+			return err
 		}
 
 		// Warn if running backup commands without root privileges
@@ -111,12 +142,21 @@ Default behavior (no subcommand): runs backup + forget + copy`,
 			Level:     cfg.Logging.Level,
 			Console:   cfg.Logging.Console,
 			JSON:      cfg.Logging.JSON,
+			Syslog: logging.SyslogConfig{
+				Enabled:  cfg.Logging.Syslog.Enabled,
+				Network:  cfg.Logging.Syslog.Network,
+				Address:  cfg.Logging.Syslog.Address,
+				Tag:      cfg.Logging.Syslog.Tag,
+				Facility: cfg.Logging.Syslog.Facility,
+			},
+			Journald: cfg.Logging.Journald,
 		})
 		if err != nil {
 			// Log to stderr but don't fail
 			fmt.Fprintf(os.Stderr, "Warning: failed to configure logging: %v\n", err)
 			logger = logging.NewLogger(logging.INFO)
 		}
+		logger = logger.With(slog.String(logging.KeyRunID, runID))
 
 		return nil
 	},
@@ -126,16 +166,45 @@ Default behavior (no subcommand): runs backup + forget + copy`,
 	},
 }
 
+// isMonitoringCheck reports whether cmd is a monitoring plugin whose exit
+// code follows the Nagios convention, so its failures must exit UNKNOWN
+func isMonitoringCheck(cmd *cobra.Command) bool {
+	return cmd == statusCmd || cmd == replicationStatusCmd
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) && exitErr.Err == nil {
+			// The command already reported its result
+			os.Exit(exitErr.Code)
+		}
 		// Print error in red
-		colorError.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		colorError.Fprintf(os.Stderr, "Error: %v\n", redact.Error(err))
+		os.Exit(exitCodeFor(err))
 	}
 	return nil
 }
 
+// ExitError makes resticm exit with a specific code. When Err is nil the
+// command has already printed its result and nothing more is shown.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 func init() {
 	// Disable usage display on all errors
 	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
@@ -148,6 +217,8 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "perform a trial run with no changes made")
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
+	rootCmd.PersistentFlags().BoolVar(&lockWait, "wait", false, "wait for a running instance to finish instead of failing")
+	rootCmd.PersistentFlags().StringVar(&lockTimeout, "lock-timeout", "", "wait at most this long for a running instance (e.g. 30m, implies --wait)")
 
 	// Root command flags (for default mode)
 	rootCmd.Flags().BoolP("prune", "p", false, "also run prune after forget")
@@ -178,11 +249,134 @@ func IsDryRun() bool {
 	return dryRun
 }
 
+// instanceLocks returns the local locks of a run according to lock_scope.
+// With the repository scope there is one lock per repository of the named
+// backends (the active backend when none is given), sorted by repository so
+// that concurrent runs take them in the same order.
+func instanceLocks(backends ...string) ([]*security.Lock, error) {
+	if cfg == nil {
+		return []*security.Lock{security.NewLock("")}, nil
+	}
+
+	var keys []string
+	switch cfg.LockScope {
+	case security.ScopeConfig:
+		path := config.GetLoadedConfigPath()
+		if path == "" {
+			return nil, fmt.Errorf("lock_scope config: no config file loaded")
+		}
+		key, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("lock_scope config: %w", err)
+		}
+		keys = []string{key}
+	case security.ScopeRepository:
+		if len(backends) == 0 {
+			_, url := activeBackendRepository()
+			keys = []string{url}
+		}
+		for _, name := range backends {
+			// Unknown backends are reported by the command itself
+			if repo, err := cfg.Resolve(name); err == nil {
+				keys = append(keys, repo.URL)
+			}
+		}
+		sort.Strings(keys)
+		keys = slices.Compact(keys)
+	default:
+		keys = []string{""}
+	}
+
+	locks := make([]*security.Lock, 0, len(keys))
+	for _, key := range keys {
+		lock, err := security.NewScopedLock(cfg.LockScope, key)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+// heldLocks are the instance locks taken by a run
+type heldLocks []*security.Lock
+
+// Release releases the locks in the reverse order of acquisition
+func (h heldLocks) Release() error {
+	var firstErr error
+	for i := len(h) - 1; i >= 0; i-- {
+		if err := h[i].Release(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// acquireLock takes the instance locks of a run touching the named backends
+// (the active backend when none is given). With --wait or --lock-timeout it
+// queues behind a running instance instead of failing.
+func acquireLock(backends ...string) (heldLocks, error) {
+	locks, err := instanceLocks(backends...)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if lockTimeout != "" {
+		if timeout, err = config.ParseDuration(lockTimeout); err != nil {
+			return nil, fmt.Errorf("invalid --lock-timeout: %w", err)
+		}
+	}
+	deadline := time.Now().Add(timeout)
+
+	var held heldLocks
+	for _, lock := range locks {
+		wait := timeout
+		if timeout > 0 {
+			// The timeout bounds the wait for all the locks together
+			wait = max(time.Until(deadline), time.Millisecond)
+		}
+		if err := acquireInstanceLock(lock, wait); err != nil {
+			_ = held.Release()
+			return nil, err
+		}
+		held = append(held, lock)
+	}
+	return held, nil
+}
+
+// acquireInstanceLock takes one instance lock, waiting at most timeout (zero
+// waits forever) with --wait or --lock-timeout
+func acquireInstanceLock(lock *security.Lock, timeout time.Duration) error {
+	if !lockWait && lockTimeout == "" {
+		err := lock.Acquire()
+		if errors.Is(err, security.ErrLocked) {
+			if holder, herr := lock.Holder(); herr == nil {
+				return fmt.Errorf("%w, held by %s (use --wait to queue)", err, holder)
+			}
+		}
+		return err
+	}
+
+	return lock.AcquireWait(timeout, func(holder security.Holder) {
+		if timeout > 0 {
+			PrintInfo("Waiting up to %s for %s to release the lock...", timeout, holder)
+		} else {
+			PrintInfo("Waiting for %s to release the lock...", holder)
+		}
+	})
+}
+
 // IsJSONOutput returns true if JSON output mode is enabled
 func IsJSONOutput() bool {
 	return jsonOutput
 }
 
+// GetRunID returns the identifier of the current invocation
+func GetRunID() string {
+	return runID
+}
+
 // GetLogger returns the global logger instance
 func GetLogger() *logging.Logger {
 	return logger
@@ -190,55 +384,56 @@ func GetLogger() *logging.Logger {
 
 // PrintError prints an error message in red and logs it
 func PrintError(format string, a ...interface{}) {
-	colorError.Fprintf(os.Stderr, "❌ "+format+"\n", a...)
+	msg := redact.String(fmt.Sprintf(format, a...))
+	colorError.Fprintln(os.Stderr, "❌ "+msg)
 	if logger != nil {
-		logger.Error(format, a...)
+		logger.Error("%s", msg)
 	}
 }
 
 // PrintSuccess prints a success message in green and logs it
 func PrintSuccess(format string, a ...interface{}) {
-	colorSuccess.Printf("✅ "+format+"\n", a...)
+	msg := redact.String(fmt.Sprintf(format, a...))
+	colorSuccess.Println("✅ " + msg)
 	if logger != nil {
-		logger.Info(format, a...)
+		logger.Info("%s", msg)
 	}
 }
 
 // PrintWarning prints a warning message in yellow and logs it
 func PrintWarning(format string, a ...interface{}) {
-	colorWarning.Printf("⚠️  "+format+"\n", a...)
+	msg := redact.String(fmt.Sprintf(format, a...))
+	colorWarning.Println("⚠️  " + msg)
 	if logger != nil {
-		logger.Warn(format, a...)
+		logger.Warn("%s", msg)
 	}
 }
 
 // PrintInfo prints an info message in cyan and logs it
 func PrintInfo(format string, a ...interface{}) {
-	colorInfo.Printf("ℹ️  "+format+"\n", a...)
+	msg := redact.String(fmt.Sprintf(format, a...))
+	colorInfo.Println("ℹ️  " + msg)
 	if logger != nil {
-		logger.Info(format, a...)
+		logger.Info("%s", msg)
 	}
 }
 
 // PrintVerbose prints a message only if verbose mode is enabled
 func PrintVerbose(format string, a ...interface{}) {
 	if verbose {
-		fmt.Printf(format+"\n", a...)
+		fmt.Println(redact.String(fmt.Sprintf(format, a...)))
 	}
 }
 
 // runDefaultWorkflow executes the default workflow: backup + forget + copy
-func runDefaultWorkflow(cmd *cobra.Command) error {
+func runDefaultWorkflow(cmd *cobra.Command) (err error) {
+	startTime := time.Now()
+
 	cfg := GetConfig()
 	if cfg == nil {
 		return fmt.Errorf("configuration not loaded")
 	}
 
-	// Log start
-	if logger != nil {
-		logger.Info("Starting default workflow")
-	}
-
 	// Parse flags
 	noBackup, _ := cmd.Flags().GetBool("no-backup")
 	noForget, _ := cmd.Flags().GetBool("no-forget")
@@ -252,6 +447,20 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 	copyAll, _ := cmd.Flags().GetBool("copy-all")
 	notifySuccess, _ := cmd.Flags().GetBool("notify-success")
 
+	// Build flag map for logging
+	flagMap := make(map[string]interface{})
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flagMap[f.Name] = f.Value.String()
+	})
+
+	// Log command start with context
+	LogCommandStart(cmd, flagMap)
+
+	// Ensure we log command end
+	defer func() {
+		LogCommandEnd(cmd, startTime, err)
+	}()
+
 	// Setup notifier
 	notifier := notify.NewNotifier(notify.Config{
 		Enabled:         cfg.Notifications.Enabled,
@@ -260,23 +469,24 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		Providers:       convertProviders(cfg.Notifications.Providers),
 	})
 
-	// Acquire lock
-	lock := security.NewLock("")
-	if err := lock.Acquire(); err != nil {
+	// Acquire lock on the primary and, unless skipped, the copy backends
+	lockBackends := []string{config.SelectPrimary}
+	if !noCopy {
+		lockBackends = append(lockBackends, cfg.CopyToBackends...)
+	}
+	lock, err := acquireLock(lockBackends...)
+	if err != nil {
 		return err
 	}
 	defer func() { _ = lock.Release() }()
 
 	// Setup executor for primary repository
-	repo := cfg.Repository
-	password := cfg.GetPassword()
-	awsKey := cfg.GetAWSAccessKeyID()
-	awsSecret := cfg.GetAWSSecretAccessKey()
-
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+	primary, err := cfg.Resolve(config.SelectPrimary)
+	if err != nil {
+		return err
+	}
+	executor := newExecutor(primary)
 	executor.DryRun = IsDryRun()
-	executor.Verbose = IsVerbose()
 
 	// Check restic is installed
 	if err := restic.CheckResticInstalled(); err != nil {
@@ -288,19 +498,15 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		return fmt.Errorf("repository is not initialized. Run 'resticm init' first")
 	}
 
+	// Locks left by an interrupted previous run would block this one
+	removeStaleLocks(executor, "primary")
+
 	hostname, _ := os.Hostname()
 	var errors []error
 	separator := strings.Repeat("━", 50)
 
 	// Setup hooks
-	hookRunner := hooks.NewRunner()
-	hookRunner.PreBackup = cfg.Hooks.PreBackup
-	hookRunner.PostBackup = cfg.Hooks.PostBackup
-	hookRunner.OnError = cfg.Hooks.OnError
-	hookRunner.OnSuccess = cfg.Hooks.OnSuccess
-	hookRunner.DryRun = IsDryRun()
-	hookRunner.Verbose = IsVerbose()
-	hookRunner.Logger = logger
+	hookRunner := newHookRunner()
 
 	// Banner
 	fmt.Println()
@@ -314,17 +520,20 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		fmt.Println("📦 BACKUP")
 		fmt.Println(separator)
 
+		tags := cfg.DefaultTags
+		if extraTag != "" {
+			tags = append(tags, extraTag)
+		}
+		recordTags(tags)
+
 		// Run pre-backup hook
+		stepStart := time.Now()
 		if err := hookRunner.RunPreBackup(); err != nil {
+			recordStep("pre_backup_hook", "", stepStart, err)
 			PrintError("Pre-backup hook failed: %v", err)
 			errors = append(errors, err)
 			_ = hookRunner.RunOnError(err)
 		} else {
-			tags := cfg.DefaultTags
-			if extraTag != "" {
-				tags = append(tags, extraTag)
-			}
-
 			backupOpts := restic.BackupOptions{
 				Directories:     cfg.Directories,
 				Tags:            tags,
@@ -333,12 +542,20 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 				Hostname:        hostname,
 			}
 
-			if err := executor.Backup(backupOpts); err != nil {
+			var summary *restic.BackupSummary
+			err := runStep(hookRunner, "backup", "primary", func() (err error) {
+				summary, err = backupAll(executor, "primary", backupOpts)
+				return err
+			})
+			recordBackup(summary)
+			switch {
+			case stepSkipped(err):
+			case err != nil:
 				PrintError("Backup failed: %v", err)
 				errors = append(errors, err)
 				_ = hookRunner.RunPostBackup(false, err)
 				_ = hookRunner.RunOnError(err)
-			} else {
+			default:
 				PrintSuccess("Backup completed")
 				_ = hookRunner.RunPostBackup(true, nil)
 			}
@@ -351,20 +568,15 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		fmt.Println("🗑️  FORGET")
 		fmt.Println(separator)
 
-		forgetOpts := restic.ForgetOptions{
-			KeepWithin:  cfg.Retention.KeepWithin,
-			KeepHourly:  cfg.Retention.KeepHourly,
-			KeepDaily:   cfg.Retention.KeepDaily,
-			KeepWeekly:  cfg.Retention.KeepWeekly,
-			KeepMonthly: cfg.Retention.KeepMonthly,
-			KeepYearly:  cfg.Retention.KeepYearly,
-			Hostname:    hostname,
-		}
-
-		if err := executor.Forget(forgetOpts); err != nil {
+		err := runStep(hookRunner, "forget", "primary", func() error {
+			return executor.Forget(forgetOptions(primary, hostname))
+		})
+		switch {
+		case stepSkipped(err):
+		case err != nil:
 			PrintError("Forget failed: %v", err)
 			errors = append(errors, err)
-		} else {
+		default:
 			PrintSuccess("Forget completed")
 		}
 	}
@@ -375,10 +587,13 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		fmt.Println("🧹 PRUNE")
 		fmt.Println(separator)
 
-		if err := executor.Prune(); err != nil {
+		err := runStep(hookRunner, "prune", "primary", executor.Prune)
+		switch {
+		case stepSkipped(err):
+		case err != nil:
 			PrintError("Prune failed: %v", err)
 			errors = append(errors, err)
-		} else {
+		default:
 			PrintSuccess("Prune completed")
 		}
 	}
@@ -390,10 +605,15 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		fmt.Println(separator)
 
 		checkOpts := restic.CheckOptions{ReadData: deep}
-		if err := executor.Check(checkOpts); err != nil {
+		err := runStep(hookRunner, "check", "primary", func() error {
+			return executor.Check(checkOpts)
+		})
+		switch {
+		case stepSkipped(err):
+		case err != nil:
 			PrintError("Check failed: %v", err)
 			errors = append(errors, err)
-		} else {
+		default:
 			PrintSuccess("Check passed")
 		}
 	}
@@ -409,91 +629,88 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 			copyHostname = ""
 		}
 
-		for _, backendName := range cfg.CopyToBackends {
-			backend, ok := cfg.Backends[backendName]
-			if !ok {
-				PrintWarning("Backend '%s' not found, skipping", backendName)
+		runs := forEachBackend(cfg.CopyToBackends, copyParallelism(), func(b *backendRun) {
+			if b.Repo == nil {
+				b.locked(func() { PrintWarning("Backend '%s' not found, skipping", b.Name) })
 				if logger != nil {
-					logger.Warn("Backend '%s' not found, skipping", backendName)
+					logger.Warn("Backend '%s' not found, skipping", b.Name)
 				}
-				continue
+				return
 			}
 
-			fmt.Printf("\n  ┌─ Backend: %s\n", backendName)
+			b.begin()
 
 			// 5a. COPY
-			fmt.Println("  │ 📦 Copying snapshots...")
+			b.progress("📦 Copying snapshots...")
 			if logger != nil {
-				logger.Info("Copying to backend: %s", backendName)
+				logger.Info("Copying to backend: %s", b.Name)
 			}
 
-			copyOpts := restic.CopyOptions{
-				FromRepository:         repo,
-				FromPassword:           password,
-				FromAWSAccessKeyID:     awsKey,
-				FromAWSSecretAccessKey: awsSecret,
-				ToRepository:           backend.Repository,
-				ToPassword:             backend.Password,
-				ToAWSAccessKeyID:       backend.AWSAccessKeyID,
-				ToAWSSecretAccessKey:   backend.AWSSecretAccessKey,
-				Hostname:               copyHostname,
-			}
+			copyOpts := copyOptions(primary, b.Repo)
+			copyOpts.Hostname = copyHostname
 
-			destExecutor := restic.NewExecutor(backend.Repository, backend.Password)
-			destExecutor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
-			destExecutor.Verbose = IsVerbose()
-			destExecutor.DryRun = IsDryRun()
+			destExecutor := b.executor()
 
-			if err := destExecutor.Copy(copyOpts); err != nil {
-				PrintError("Copy to %s failed: %v", backendName, err)
-				errors = append(errors, err)
-				fmt.Println("  └─ ❌ Skipping maintenance due to copy failure")
-				continue
-			}
-			PrintSuccess("Copy to %s completed", backendName)
-
-			// 5b. FORGET on this backend (same retention policy)
-			fmt.Println("  │ 🗑️  Applying retention policy...")
-			forgetOpts := restic.ForgetOptions{
-				KeepWithin:  cfg.Retention.KeepWithin,
-				KeepHourly:  cfg.Retention.KeepHourly,
-				KeepDaily:   cfg.Retention.KeepDaily,
-				KeepWeekly:  cfg.Retention.KeepWeekly,
-				KeepMonthly: cfg.Retention.KeepMonthly,
-				KeepYearly:  cfg.Retention.KeepYearly,
-				Hostname:    hostname,
+			if err := b.step(hookRunner, "copy", func() error {
+				return destExecutor.Copy(copyOpts)
+			}); err != nil {
+				if stepSkipped(err) {
+					b.end("⏭️  Skipping maintenance, copy was skipped")
+					return
+				}
+				b.printError("Copy to %s failed: %v", b.Name, err)
+				b.end("❌ Skipping maintenance due to copy failure")
+				return
 			}
-			if err := destExecutor.Forget(forgetOpts); err != nil {
-				PrintError("Forget on %s failed: %v", backendName, err)
-				errors = append(errors, err)
-			} else {
-				PrintSuccess("Forget on %s completed", backendName)
+			b.printSuccess("Copy to %s completed", b.Name)
+
+			// 5b. FORGET on this backend (its own retention policy)
+			b.progress("🗑️  Applying retention policy...")
+			err := b.step(hookRunner, "forget", func() error {
+				return destExecutor.Forget(forgetOptions(b.Repo, hostname))
+			})
+			switch {
+			case stepSkipped(err):
+			case err != nil:
+				b.printError("Forget on %s failed: %v", b.Name, err)
+			default:
+				b.printSuccess("Forget on %s completed", b.Name)
 			}
 
 			// 5c. PRUNE on this backend (if requested)
 			if doPrune && !noPrune {
-				fmt.Println("  │ 🧹 Pruning unused data...")
-				if err := destExecutor.Prune(); err != nil {
-					PrintError("Prune on %s failed: %v", backendName, err)
-					errors = append(errors, err)
-				} else {
-					PrintSuccess("Prune on %s completed", backendName)
+				b.progress("🧹 Pruning unused data...")
+				err := b.step(hookRunner, "prune", destExecutor.Prune)
+				switch {
+				case stepSkipped(err):
+				case err != nil:
+					b.printError("Prune on %s failed: %v", b.Name, err)
+				default:
+					b.printSuccess("Prune on %s completed", b.Name)
 				}
 			}
 
 			// 5d. CHECK on this backend (if requested)
 			if (doCheck || deep) && !noCheck {
-				fmt.Println("  │ 🔍 Checking integrity...")
+				b.progress("🔍 Checking integrity...")
 				checkOpts := restic.CheckOptions{ReadData: deep}
-				if err := destExecutor.Check(checkOpts); err != nil {
-					PrintError("Check on %s failed: %v", backendName, err)
-					errors = append(errors, err)
-				} else {
-					PrintSuccess("Check on %s passed", backendName)
+				err := b.step(hookRunner, "check", func() error {
+					return destExecutor.Check(checkOpts)
+				})
+				switch {
+				case stepSkipped(err):
+				case err != nil:
+					b.printError("Check on %s failed: %v", b.Name, err)
+				default:
+					b.printSuccess("Check on %s passed", b.Name)
 				}
 			}
 
-			fmt.Println("  └─ ✅ Backend synchronized")
+			b.end("✅ Backend synchronized")
+		})
+		printBackendSummary(runs)
+		for _, b := range runs {
+			errors = append(errors, b.Errors()...)
 		}
 	}
 
@@ -511,6 +728,7 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 		var staleLockRepos []string
 
 		// Check primary repository
+		removeStaleLocks(executor, "primary")
 		if result, err := executor.VerifyNoStaleLocks(hostname); err != nil {
 			PrintWarning("Could not verify locks on primary: %v", err)
 			if logger != nil {
@@ -543,10 +761,13 @@ func runDefaultWorkflow(cmd *cobra.Command) error {
 
 		// Check copy backends
 		for _, backendName := range cfg.CopyToBackends {
-			backend := cfg.Backends[backendName]
-			backendExecutor := restic.NewExecutor(backend.Repository, backend.Password)
-			backendExecutor.SetAWSCredentials(backend.AWSAccessKeyID, backend.AWSSecretAccessKey)
+			backend, err := cfg.Resolve(backendName)
+			if err != nil {
+				continue
+			}
+			backendExecutor := newExecutor(backend)
 
+			removeStaleLocks(backendExecutor, backendName)
 			if result, err := backendExecutor.VerifyNoStaleLocks(hostname); err != nil {
 				PrintWarning("Could not verify locks on %s: %v", backendName, err)
 				if logger != nil {
@@ -663,22 +884,18 @@ func GetNotifier(notifySuccess bool) *notify.Notifier {
 }
 
 // LogCommandStart logs the command execution start with full context
+// and starts recording the run in the history
 func LogCommandStart(cmd *cobra.Command, extraFlags map[string]interface{}) {
+	currentRun = history.NewRun(runID, cmd.CommandPath(), extraFlags)
+	currentRun.DryRun = dryRun
+
 	if logger == nil {
 		return
 	}
 
-	// Build command string
-	cmdPath := cmd.CommandPath()
-
-	// Log command with context
-	logger.Info("Command started: %s", cmdPath)
-
-	// Log hostname
 	hostname, _ := os.Hostname()
-	logger.Info("Hostname: %s", hostname)
 
-	// Log user
+	// Resolve user
 	user := "root"
 	if !config.IsRoot() {
 		user = os.Getenv("USER")
@@ -686,22 +903,18 @@ func LogCommandStart(cmd *cobra.Command, extraFlags map[string]interface{}) {
 			user = "non-root"
 		}
 	}
-	logger.Info("User: %s", user)
 
-	// Log active backend
-	activeBackend, _ := config.GetActiveBackend()
-	if activeBackend == "" {
-		activeBackend = "primary"
+	attrs := []slog.Attr{
+		slog.String(logging.KeyCommand, cmd.CommandPath()),
+		slog.String("hostname", hostname),
+		slog.String("user", user),
 	}
-	logger.Info("Backend: %s", activeBackend)
-
-	// Log config file
-	configPath := config.GetLoadedConfigPath()
-	if configPath != "" {
-		logger.Info("Config: %s", configPath)
+	attrs = append(attrs, backendAttrs()...)
+	if configPath := config.GetLoadedConfigPath(); configPath != "" {
+		attrs = append(attrs, slog.String("config", configPath))
 	}
 
-	// Log global flags if set
+	// Global flags if set
 	flags := make(map[string]interface{})
 	if verbose {
 		flags["verbose"] = true
@@ -718,30 +931,292 @@ func LogCommandStart(cmd *cobra.Command, extraFlags map[string]interface{}) {
 		flags[k] = v
 	}
 
-	// Log flags if any
 	if len(flags) > 0 {
-		flagStrs := []string{}
-		for k, v := range flags {
-			flagStrs = append(flagStrs, fmt.Sprintf("%s=%v", k, v))
+		keys := make([]string, 0, len(flags))
+		for k := range flags {
+			keys = append(keys, k)
 		}
-		logger.Info("Flags: %s", strings.Join(flagStrs, ", "))
+		sort.Strings(keys)
+
+		flagAttrs := make([]any, 0, len(keys))
+		for _, k := range keys {
+			flagAttrs = append(flagAttrs, slog.Any(k, flags[k]))
+		}
+		attrs = append(attrs, slog.Group("flags", flagAttrs...))
 	}
+
+	logger.LogAttrs(logging.INFO, "Command started", attrs...)
 }
 
 // LogCommandEnd logs the command execution end with duration
+// and saves the run to the history
 func LogCommandEnd(cmd *cobra.Command, startTime time.Time, err error) {
+	saveRun(err)
+
 	if logger == nil {
 		return
 	}
 
-	cmdPath := cmd.CommandPath()
-	duration := time.Since(startTime)
+	duration := time.Since(startTime).Round(time.Second)
+
+	attrs := []slog.Attr{
+		slog.String(logging.KeyCommand, cmd.CommandPath()),
+	}
+	attrs = append(attrs, backendAttrs()...)
+	attrs = append(attrs,
+		slog.Duration(logging.KeyDuration, duration),
+		slog.Int(logging.KeyExitCode, exitCodeFor(err)),
+	)
 
 	if err != nil {
-		logger.Error("Command failed: %s (duration: %s, error: %v)", cmdPath, duration.Round(time.Second), err)
+		attrs = append(attrs, slog.String(logging.KeyError, err.Error()))
+		logger.LogAttrs(logging.ERROR, "Command failed", attrs...)
+	} else {
+		logger.LogAttrs(logging.INFO, "Command completed", attrs...)
+	}
+}
+
+// saveRun finishes the current run, appends it to the history file and
+// refreshes the metrics textfile
+func saveRun(err error) {
+	if currentRun == nil {
+		return
+	}
+	run := currentRun
+	currentRun = nil
+	run.Finish(err)
+
+	if runResultFile != "" {
+		_ = os.Remove(runResultFile)
+		runResultFile = ""
+	}
+
+	if cfg == nil {
+		return
+	}
+	if cfg.History.Enabled {
+		store := history.NewStore(config.StateDir())
+		store.SetMaxRuns(cfg.History.MaxRuns)
+		if err := store.Append(run); err != nil && logger != nil {
+			logger.Warn("Failed to record run history: %v", err)
+		}
+	}
+	writeMetricsTextfile(run)
+}
+
+// recordStep adds a finished step to the current run
+func recordStep(name, backend string, start time.Time, err error) {
+	if currentRun != nil {
+		currentRun.AddStep(name, backend, start, err)
+	}
+}
+
+// newHookRunner creates a hook runner from the configuration
+func newHookRunner() *hooks.Runner {
+	runner := hooks.NewRunner()
+	runner.PreBackup = hookFromConfig(cfg.Hooks.PreBackup)
+	runner.PostBackup = hookFromConfig(cfg.Hooks.PostBackup)
+	runner.OnError = hookFromConfig(cfg.Hooks.OnError)
+	runner.OnSuccess = hookFromConfig(cfg.Hooks.OnSuccess)
+	runner.Timeout, _ = config.ParseDuration(cfg.Hooks.Timeout)
+	runner.DryRun = IsDryRun()
+	runner.Verbose = IsVerbose()
+	runner.RunInfo = hookRunInfo
+	if l := GetLogger(); l != nil {
+		runner.Logger = l
+	}
+	return runner
+}
+
+// hookRunInfo describes the current run to a hook and refreshes the run
+// result file it points to
+func hookRunInfo() hooks.RunInfo {
+	backend, repository := activeBackendRepository()
+	info := hooks.RunInfo{
+		RunID:      runID,
+		Repository: redact.String(repository),
+		Backend:    backend,
+		DryRun:     IsDryRun(),
+	}
+	if configPath := config.GetLoadedConfigPath(); configPath != "" {
+		// Hooks may run in another working directory
+		info.ConfigPath, _ = filepath.Abs(configPath)
+	}
+
+	run := currentRun
+	if run == nil {
+		return info
+	}
+	info.Command = run.Command
+	info.Hostname = run.Hostname
+	info.Tags = run.Tags
+	info.SnapshotID = run.SnapshotID()
+	info.Duration = time.Since(run.Start)
+
+	if err := writeRunResult(run); err != nil {
+		if logger != nil {
+			logger.Warn("Failed to write run result file: %v", err)
+		}
 	} else {
-		logger.Info("Command completed: %s (duration: %s)", cmdPath, duration.Round(time.Second))
+		info.ResultFile = runResultFile
+	}
+	return info
+}
+
+// writeRunResult writes the run as JSON to the run result file, creating it
+// on first use. The file is removed when the run is saved.
+func writeRunResult(run *history.Run) error {
+	// Hooks of backends synchronized in parallel refresh it concurrently
+	runResultMu.Lock()
+	defer runResultMu.Unlock()
+
+	data, err := run.JSON()
+	if err != nil {
+		return err
+	}
+
+	if runResultFile == "" {
+		f, err := os.CreateTemp("", "resticm-run-*.json")
+		if err != nil {
+			return err
+		}
+		runResultFile = f.Name()
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(runResultFile, append(data, '\n'), 0600)
+}
+
+// hookFromConfig converts a configured hook; durations are checked by Validate
+func hookFromConfig(h config.Hook) hooks.Hook {
+	timeout, _ := config.ParseDuration(h.Timeout)
+	return hooks.Hook{
+		Path:    h.Path,
+		Command: h.Command,
+		Argv:    h.Argv,
+		Timeout: timeout,
+		Workdir: h.Workdir,
+		User:    h.User,
+
+		ContinueOnError: h.ContinueOnError,
+	}
+}
+
+// runStep runs a workflow step between its configured pre and post hooks and
+// records it in the run history. A nil hookRunner runs the step without hooks.
+// When a failed pre hook skips the step, the returned error satisfies stepSkipped.
+func runStep(hookRunner *hooks.Runner, name, backend string, fn func() error) error {
+	return runStepWarn(hookRunner, name, backend, PrintWarning, fn)
+}
+
+// runStepWarn runs a step like runStep, printing its warnings with warn
+func runStepWarn(hookRunner *hooks.Runner, name, backend string, warn func(format string, a ...interface{}), fn func() error) error {
+	var stepHook hooks.StepHook
+	var repository string
+	if hookRunner != nil && cfg != nil {
+		// Hooks of a step on a copy backend see its repository, not the active one
+		if repo, err := cfg.Resolve(backend); err == nil {
+			repository = redact.String(repo.URL)
+		}
+		h := cfg.Hooks.StepHooks(name, backend)
+		stepHook = hooks.StepHook{
+			Pre:          hookFromConfig(h.Pre),
+			Post:         hookFromConfig(h.Post),
+			OnPreFailure: h.OnPreFailure,
+		}
+
+		hookStart := time.Now()
+		if err := hookRunner.RunPreStep(name, backend, repository, stepHook); err != nil {
+			if stepSkipped(err) {
+				warn("Skipping %s on %s: pre-%s hook failed", name, backend, name)
+				if currentRun != nil {
+					currentRun.SkipStep(name, backend, err.Error())
+				}
+				return err
+			}
+			recordStep(name, backend, hookStart, err)
+			return err
+		}
+	}
+
+	stepStart := time.Now()
+	err := fn()
+	recordStep(name, backend, stepStart, err)
+
+	if hookRunner != nil {
+		if hookErr := hookRunner.RunPostStep(name, backend, repository, stepHook, err); hookErr != nil {
+			warn("Post-%s hook failed on %s: %v", name, backend, hookErr)
+		}
+	}
+	return err
+}
+
+// stepSkipped reports whether runStep skipped a step because its pre hook failed
+func stepSkipped(err error) bool {
+	return errors.Is(err, hooks.ErrStepSkipped)
+}
+
+// recordTags records the snapshot tags of the current run
+func recordTags(tags []string) {
+	if currentRun != nil {
+		currentRun.SetTags(tags)
+	}
+}
+
+// recordBackup attaches a backup summary to the current run
+func recordBackup(summary *restic.BackupSummary) {
+	if currentRun != nil && summary != nil {
+		currentRun.SetBackup(summary)
+	}
+}
+
+// backendAttrs returns the backend and repository attributes for the active backend
+func backendAttrs() []slog.Attr {
+	activeBackend, repository := activeBackendRepository()
+	attrs := []slog.Attr{slog.String(logging.KeyBackend, activeBackend)}
+
+	if cfg != nil {
+		attrs = append(attrs, slog.String(logging.KeyRepository, repository))
+	}
+	return attrs
+}
+
+// activeBackendRepository returns the active backend name and its repository
+func activeBackendRepository() (string, string) {
+	activeBackend, _ := config.GetActiveBackend()
+	if activeBackend == "" {
+		activeBackend = "primary"
+	}
+	if cfg == nil {
+		return activeBackend, ""
+	}
+
+	if repo, err := cfg.Resolve(activeBackend); err == nil {
+		return activeBackend, repo.URL
+	}
+	return activeBackend, cfg.Repository
+}
+
+// exitCodeFor returns the process exit code resticm uses for a command result
+func exitCodeFor(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// newRunID returns a short random identifier for the current invocation
+func newRunID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
 	}
+	return hex.EncodeToString(b)
 }
 
 // needsRootForFullAccess returns true if the command benefits from root privileges
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"resticm/internal/config"
+	"resticm/internal/security"
+	"resticm/internal/status"
 )
 
 // TestDefaultWorkflowHooksExecution tests that hooks are executed in default workflow
@@ -128,25 +130,25 @@ func TestDefaultWorkflowHooksNotConfigured(t *testing.T) {
 	// Set up configuration with no hooks
 	cfg = &config.Config{
 		Hooks: config.HookConfig{
-			PreBackup:  "",
-			PostBackup: "",
-			OnSuccess:  "",
-			OnError:    "",
+			PreBackup:  config.Hook{},
+			PostBackup: config.Hook{},
+			OnSuccess:  config.Hook{},
+			OnError:    config.Hook{},
 		},
 	}
 	
 	// This verifies that empty hook paths are handled gracefully
 	// The actual workflow execution would require a test repository
-	if cfg.Hooks.PreBackup != "" {
+	if cfg.Hooks.PreBackup.IsSet() {
 		t.Error("PreBackup should be empty")
 	}
-	if cfg.Hooks.PostBackup != "" {
+	if cfg.Hooks.PostBackup.IsSet() {
 		t.Error("PostBackup should be empty")
 	}
-	if cfg.Hooks.OnSuccess != "" {
+	if cfg.Hooks.OnSuccess.IsSet() {
 		t.Error("OnSuccess should be empty")
 	}
-	if cfg.Hooks.OnError != "" {
+	if cfg.Hooks.OnError.IsSet() {
 		t.Error("OnError should be empty")
 	}
 }
@@ -204,3 +206,68 @@ exit 0
 		t.Error("on-error hook is not executable")
 	}
 }
+
+func TestInstanceLocksRepositoryScope(t *testing.T) {
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	cfg = &config.Config{
+		Repository: "s3:https://s3.example.com/primary",
+		LockScope:  security.ScopeRepository,
+		Backends: map[string]config.Backend{
+			"b2":     {Repository: "b2:bucket:/repo"},
+			"mirror": {Repository: "s3:https://s3.example.com/primary"},
+		},
+	}
+
+	locks, err := instanceLocks(config.SelectPrimary, "mirror", "b2", "unknown")
+	if err != nil {
+		t.Fatalf("instanceLocks() error = %v", err)
+	}
+
+	// One lock per repository, sorted by repository
+	var want []string
+	for _, url := range []string{"b2:bucket:/repo", "s3:https://s3.example.com/primary"} {
+		lock, _ := security.NewScopedLock(security.ScopeRepository, url)
+		want = append(want, lock.Path())
+	}
+	if len(locks) != len(want) {
+		t.Fatalf("got %d locks, want %d", len(locks), len(want))
+	}
+	for i, lock := range locks {
+		if lock.Path() != want[i] {
+			t.Errorf("lock %d = %s, want %s", i, lock.Path(), want[i])
+		}
+	}
+}
+
+func TestInstanceLocksConfigScopeWithoutConfigFile(t *testing.T) {
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	cfg = &config.Config{LockScope: security.ScopeConfig}
+
+	if config.GetLoadedConfigPath() != "" {
+		t.Skip("a config file was loaded by another test")
+	}
+	if _, err := instanceLocks(); err == nil {
+		t.Error("expected an error without a config file to scope the lock to")
+	}
+}
+
+func TestStatusConfigLoadFailureExitsUnknown(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("repository: [unterminated\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	oldCfg, oldCfgFile := cfg, cfgFile
+	defer func() { cfg, cfgFile = oldCfg, oldCfgFile }()
+	cfgFile = configPath
+
+	err := rootCmd.PersistentPreRunE(statusCmd, nil)
+	if err == nil {
+		t.Fatal("expected a configuration error")
+	}
+	if got := exitCodeFor(err); got != status.Unknown.ExitCode() {
+		t.Errorf("exit code = %d, want %d", got, status.Unknown.ExitCode())
+	}
+}
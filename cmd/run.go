@@ -7,7 +7,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"resticm/internal/config"
-	"resticm/internal/restic"
 )
 
 var runCmd = &cobra.Command{
@@ -78,31 +77,11 @@ func runResticCommand(configFile string, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	// Get active backend
-	activeBackend, _ := config.GetActiveBackend()
-
-	var repo, password string
-	var awsKey, awsSecret string
-
-	if activeBackend == "" || activeBackend == "primary" {
-		repo = cfg.Repository
-		password = cfg.GetPassword()
-		awsKey = cfg.GetAWSAccessKeyID()
-		awsSecret = cfg.GetAWSSecretAccessKey()
-	} else {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
-		}
-		repo = backend.Repository
-		password = backend.Password
-		awsKey = backend.AWSAccessKeyID
-		awsSecret = backend.AWSSecretAccessKey
+	repo, err := cfg.ActiveRepository()
+	if err != nil {
+		return err
 	}
-
-	// Create executor
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+	executor := newExecutor(repo)
 
 	return executor.RunWithStreaming(args...)
 }
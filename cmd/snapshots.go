@@ -17,7 +17,8 @@ var snapshotsCmd = &cobra.Command{
 	Long: `List repository snapshots.
 
 By default, shows snapshots from the active backend only.
-Use --all-backends to show snapshots from all configured backends.`,
+Use --all-backends to show snapshots from the primary and copy backends, or
+--backends to choose the backends (e.g. 'offsite' for a group).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runSnapshots(cmd)
 	},
@@ -28,6 +29,7 @@ func init() {
 	snapshotsCmd.Flags().Bool("all", false, "Show snapshots from all hosts")
 	snapshotsCmd.Flags().Bool("latest", false, "Show only the latest snapshot")
 	snapshotsCmd.Flags().Bool("all-backends", false, "Show snapshots from all configured backends")
+	addBackendsFlag(snapshotsCmd)
 }
 
 func runSnapshots(cmd *cobra.Command) (err error) {
@@ -41,6 +43,7 @@ func runSnapshots(cmd *cobra.Command) (err error) {
 	showLatest, _ := cmd.Flags().GetBool("latest")
 	showAll, _ := cmd.Flags().GetBool("all")
 	allBackends, _ := cmd.Flags().GetBool("all-backends")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
@@ -53,6 +56,9 @@ func runSnapshots(cmd *cobra.Command) (err error) {
 	if allBackends {
 		flagMap["all-backends"] = true
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
 
 	// Log command start with context
 	LogCommandStart(cmd, flagMap)
@@ -62,67 +68,44 @@ func runSnapshots(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
-	// If --all-backends, show snapshots from all backends
-	if allBackends {
-		return showSnapshotsAllBackends(cfg, showAll, showLatest)
+	backends, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return activeBackends(cfg, allBackends)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Get active backend
-	activeBackend, _ := config.GetActiveBackend()
-
-	var repo, password string
-	var awsKey, awsSecret string
-	var backendName string
-
-	if activeBackend == "" || activeBackend == "primary" {
-		repo = cfg.Repository
-		password = cfg.GetPassword()
-		awsKey = cfg.GetAWSAccessKeyID()
-		awsSecret = cfg.GetAWSSecretAccessKey()
-		backendName = "primary"
-	} else {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
+	if len(backends) == 1 {
+		repo, err := cfg.Resolve(backends[0])
+		if err != nil {
+			return err
 		}
-		repo = backend.Repository
-		password = backend.Password
-		awsKey = backend.AWSAccessKeyID
-		awsSecret = backend.AWSSecretAccessKey
-		backendName = activeBackend
+		return showSnapshotsForBackend(repo, showAll, showLatest)
 	}
-
-	return showSnapshotsForBackend(backendName, repo, password, awsKey, awsSecret, showAll, showLatest)
+	return showSnapshotsAllBackends(cfg, backends, showAll, showLatest)
 }
 
-func showSnapshotsAllBackends(cfg *config.Config, showAll, showLatest bool) error {
-	// Primary
-	fmt.Println("\n═══ PRIMARY BACKEND ═══")
-	if err := showSnapshotsForBackend("primary", cfg.Repository, cfg.GetPassword(),
-		cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey(), showAll, showLatest); err != nil {
-		PrintError("Failed to list snapshots on primary: %v", err)
-	}
-
-	// Copy backends
-	for _, backendName := range cfg.CopyToBackends {
-		backend, ok := cfg.Backends[backendName]
-		if !ok {
+func showSnapshotsAllBackends(cfg *config.Config, backends []string, showAll, showLatest bool) error {
+	for _, name := range backends {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
 			continue
 		}
-		fmt.Printf("\n═══ BACKEND: %s ═══\n", backendName)
-		if err := showSnapshotsForBackend(backendName, backend.Repository, backend.Password,
-			backend.AWSAccessKeyID, backend.AWSSecretAccessKey, showAll, showLatest); err != nil {
-			PrintError("Failed to list snapshots on %s: %v", backendName, err)
+		if name == config.SelectPrimary {
+			fmt.Println("\n═══ PRIMARY BACKEND ═══")
+		} else {
+			fmt.Printf("\n═══ BACKEND: %s ═══\n", name)
+		}
+		if err := showSnapshotsForBackend(repo, showAll, showLatest); err != nil {
+			PrintError("Failed to list snapshots on %s: %v", name, err)
 		}
 	}
 
 	return nil
 }
 
-func showSnapshotsForBackend(name, repo, password, awsKey, awsSecret string, showAll, showLatest bool) error {
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
-	executor.Verbose = IsVerbose()
+func showSnapshotsForBackend(repo *config.Repository, showAll, showLatest bool) error {
+	executor := newExecutor(repo)
 
 	if showLatest {
 		snapshot, err := executor.GetLatestSnapshot()
@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"resticm/internal/config"
+	"resticm/internal/docker"
+	"resticm/internal/fssnap"
+	"resticm/internal/restic"
+	"resticm/internal/sources"
+)
+
+// backupAll backs up the configured directories, every database source and
+// every command source and the Docker volumes, each source into its own
+// snapshot. All sources are
+// attempted; the returned summary is the one of the directory backup.
+func backupAll(executor *restic.Executor, backend string, opts restic.BackupOptions) (*restic.BackupSummary, error) {
+	var summary *restic.BackupSummary
+	var failed []string
+	var firstErr error
+
+	if len(opts.Directories) > 0 {
+		var err error
+		summary, err = backupDirectories(executor, opts)
+		if err != nil {
+			failed = append(failed, "directories")
+			firstErr = err
+		}
+	}
+
+	for _, source := range cfg.Sources.Databases {
+		db := databaseFromConfig(source)
+		start := time.Now()
+		err := backupDatabase(executor, db, opts)
+		recordStep("database:"+db.Name, backend, start, err)
+		if err != nil {
+			PrintError("Database %s: %v", db.Name, err)
+			failed = append(failed, "database "+db.Name)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	for _, source := range cfg.Sources.Commands {
+		src := commandFromConfig(source)
+		start := time.Now()
+		err := backupCommand(executor, src, opts)
+		recordStep("command:"+src.Name, backend, start, err)
+		if err != nil {
+			PrintError("Command source %s: %v", src.Name, err)
+			failed = append(failed, "command "+src.Name)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if cfg.Sources.Docker.Enabled {
+		start := time.Now()
+		err := backupDocker(executor, opts)
+		recordStep("docker", backend, start, err)
+		if err != nil {
+			PrintError("Docker volumes: %v", err)
+			failed = append(failed, "docker")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	switch len(failed) {
+	case 0:
+		return summary, nil
+	case 1:
+		return summary, firstErr
+	default:
+		return summary, fmt.Errorf("%d backup sources failed (%v): %w", len(failed), failed, firstErr)
+	}
+}
+
+// snapshotProvider returns the provider of a snapshot type; tests replace it
+var snapshotProvider = fssnap.New
+
+// backupDirectories backs up the configured directories into one snapshot,
+// reading those listed in fs_snapshots from a read-only filesystem snapshot.
+// The snapshots are always torn down, even when the backup fails.
+func backupDirectories(executor *restic.Executor, opts restic.BackupOptions) (summary *restic.BackupSummary, err error) {
+	if len(cfg.FSSnapshots.Directories) == 0 {
+		return executor.Backup(opts)
+	}
+	if executor.DryRun {
+		for _, d := range cfg.FSSnapshots.Directories {
+			PrintInfo("[DRY-RUN] Would back up %s from a %s snapshot mounted at %s",
+				d.Path, d.Type, fssnap.MountPoint(cfg.FSSnapshots.MountDir, d.Path))
+		}
+		return executor.Backup(opts)
+	}
+
+	ctx := context.Background()
+	var mounted []*fssnap.Mounted
+	defer func() {
+		for i := len(mounted) - 1; i >= 0; i-- {
+			if uerr := mounted[i].Unmount(ctx); uerr != nil {
+				PrintError("Failed to remove filesystem snapshot: %v", uerr)
+				err = errors.Join(err, uerr)
+			}
+		}
+	}()
+
+	snapOpts := opts
+	snapOpts.Directories = append([]string(nil), opts.Directories...)
+	snapOpts.Tags = append([]string(nil), opts.Tags...)
+	for _, d := range cfg.FSSnapshots.Directories {
+		provider, perr := snapshotProvider(d.Type)
+		if perr != nil {
+			return nil, perr
+		}
+		m, merr := fssnap.Mount(ctx, provider, fsSnapshotFromConfig(d), cfg.FSSnapshots.MountDir)
+		mounted = append(mounted, m)
+		if merr != nil {
+			return nil, merr
+		}
+		PrintInfo("Snapshot of %s (%s) mounted at %s", d.Path, d.Type, m.MountPoint)
+		for i, dir := range snapOpts.Directories {
+			if filepath.Clean(dir) == filepath.Clean(d.Path) {
+				snapOpts.Directories[i] = m.MountPoint
+			}
+		}
+		snapOpts.Tags = append(snapOpts.Tags, fssnap.OriginTagPrefix+filepath.Clean(d.Path))
+	}
+	return executor.Backup(snapOpts)
+}
+
+// backupPaths returns the paths recorded in the directory snapshots: the
+// configured directories, with those read from a filesystem snapshot at
+// their mount point
+func backupPaths() []string {
+	paths := append([]string(nil), cfg.Directories...)
+	for i, dir := range paths {
+		if d := fsSnapshotOf(dir); d != nil {
+			paths[i] = fssnap.MountPoint(cfg.FSSnapshots.MountDir, d.Path)
+		}
+	}
+	return paths
+}
+
+// fsSnapshotOf returns the filesystem snapshot configured for a directory
+func fsSnapshotOf(dir string) *config.FSSnapshotDirectory {
+	for i, d := range cfg.FSSnapshots.Directories {
+		if filepath.Clean(d.Path) == filepath.Clean(dir) {
+			return &cfg.FSSnapshots.Directories[i]
+		}
+	}
+	return nil
+}
+
+// fsSnapshotFromConfig converts a configured snapshot directory
+func fsSnapshotFromConfig(d config.FSSnapshotDirectory) fssnap.Directory {
+	return fssnap.Directory{
+		Path:         d.Path,
+		Type:         d.Type,
+		Volume:       d.Volume,
+		Size:         d.Size,
+		MountOptions: d.MountOptions,
+	}
+}
+
+// backupDatabase streams a database dump into its own snapshot
+func backupDatabase(executor *restic.Executor, db sources.Database, opts restic.BackupOptions) error {
+	opts.StdinFilename = db.Filename()
+	opts.Tags = append(append([]string(nil), opts.Tags...), db.Tag())
+
+	if executor.DryRun {
+		PrintInfo("[DRY-RUN] Would dump database %s (%s) with %s to %s tagged %s",
+			db.Name, db.Type, db.DumpCommand(), db.Filename(), db.Tag())
+		return nil
+	}
+
+	PrintInfo("Dumping database %s (%s)...", db.Name, db.Type)
+	stream, err := db.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.Close() }()
+
+	_, err = executor.BackupStdin(opts, stream)
+	if err != nil {
+		return err
+	}
+	PrintSuccess("Database %s backed up", db.Name)
+	return nil
+}
+
+// backupCommand backs up the output of a command into its own snapshot.
+// restic runs the command and saves no snapshot if it exits non-zero.
+func backupCommand(executor *restic.Executor, src sources.Command, opts restic.BackupOptions) error {
+	opts.StdinFilename = src.File()
+	opts.StdinCommand = src.Argv
+	opts.Tags = append(append([]string(nil), opts.Tags...), src.Tag())
+	opts.Tags = append(opts.Tags, src.Tags...)
+
+	if executor.DryRun {
+		PrintInfo("[DRY-RUN] Would back up the output of '%s' to %s tagged %s",
+			strings.Join(src.Argv, " "), src.File(), strings.Join(opts.Tags, ","))
+		return nil
+	}
+
+	PrintInfo("Backing up output of %s...", src.Name)
+	if _, err := executor.Backup(opts); err != nil {
+		return err
+	}
+	PrintSuccess("Command source %s backed up", src.Name)
+	return nil
+}
+
+// errDockerInterrupted is returned when a signal stops the Docker backup
+var errDockerInterrupted = errors.New("docker backup interrupted")
+
+// backupDocker backs up the volumes of the labelled containers, one snapshot
+// per volume. The containers are quiesced together before the first volume
+// and always resumed afterwards, even when a backup fails or the run is
+// interrupted.
+func backupDocker(executor *restic.Executor, opts restic.BackupOptions) (err error) {
+	dc := cfg.Sources.Docker
+	timeout := 10 * time.Minute
+	if dc.Timeout != "" {
+		timeout, _ = config.ParseDuration(dc.Timeout)
+	}
+	stopTimeout := 30 * time.Second
+	if dc.StopTimeout != "" {
+		stopTimeout, _ = config.ParseDuration(dc.StopTimeout)
+	}
+	label := dc.Label
+	if label == "" {
+		label = docker.LabelEnable + "=true"
+	}
+
+	client := docker.NewClient(dc.Socket)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	containers, err := client.ListContainers(ctx, label)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		PrintInfo("No Docker containers labelled %s", label)
+		return nil
+	}
+	volumes := docker.VolumesOf(containers)
+
+	if executor.DryRun {
+		for _, c := range containers {
+			PrintInfo("[DRY-RUN] Would quiesce container %s (mode %s, %s)", c.Name(), c.Mode(dc.Mode), c.State)
+		}
+		for _, v := range volumes {
+			PrintInfo("[DRY-RUN] Would back up volume %s (%s) tagged %s", v.Name, v.Path, strings.Join(v.Tags(), ","))
+		}
+		return nil
+	}
+
+	// An interrupted run must not leave the containers stopped: the signal
+	// cancels the volume backup, then the deferred release resumes them
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var quiesced []*docker.Quiesced
+	defer func() {
+		for i := len(quiesced) - 1; i >= 0; i-- {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			rerr := quiesced[i].Release(ctx)
+			cancel()
+			if rerr != nil {
+				PrintError("Failed to resume container: %v", rerr)
+				err = errors.Join(err, rerr)
+			}
+		}
+	}()
+
+	for _, c := range containers {
+		ctx, cancel := context.WithTimeout(sigCtx, timeout)
+		q, qerr := client.Quiesce(ctx, c, c.Mode(dc.Mode), stopTimeout)
+		cancel()
+		quiesced = append(quiesced, q)
+		if qerr != nil {
+			return qerr
+		}
+		if sigCtx.Err() != nil {
+			return errDockerInterrupted
+		}
+		PrintInfo("Container %s %s", c.Name(), q.Action())
+	}
+
+	var failed []string
+	var firstErr error
+	for _, v := range volumes {
+		vopts := opts
+		vopts.Directories = []string{v.Path}
+		vopts.Tags = append(append([]string(nil), opts.Tags...), v.Tags()...)
+
+		PrintInfo("Backing up volume %s...", v.Name)
+		_, berr := executor.BackupContext(sigCtx, vopts)
+		if sigCtx.Err() != nil {
+			PrintWarning("Interrupted, resuming containers")
+			return errDockerInterrupted
+		}
+		if berr != nil {
+			PrintError("Volume %s: %v", v.Name, berr)
+			failed = append(failed, v.Name)
+			if firstErr == nil {
+				firstErr = berr
+			}
+			continue
+		}
+		PrintSuccess("Volume %s backed up", v.Name)
+	}
+	if len(failed) > 1 {
+		return fmt.Errorf("%d volume backups failed (%v): %w", len(failed), failed, firstErr)
+	}
+	return firstErr
+}
+
+// commandFromConfig converts a configured command source
+func commandFromConfig(c config.CommandSource) sources.Command {
+	return sources.Command{
+		Name:     c.Name,
+		Argv:     c.Command,
+		Filename: c.Filename,
+		Tags:     c.Tags,
+	}
+}
+
+// databaseFromConfig converts a configured database source
+func databaseFromConfig(d config.DatabaseSource) sources.Database {
+	return sources.Database{
+		Name:     d.Name,
+		Type:     d.Type,
+		Host:     d.Host,
+		Port:     d.Port,
+		User:     d.User,
+		Password: d.Password,
+		Database: d.Database,
+		Path:     d.Path,
+		Command:  d.Command,
+		Options:  d.Options,
+	}
+}
+
+// findDatabase returns the configured database source with the given name
+func findDatabase(name string) (sources.Database, bool) {
+	for _, d := range cfg.Sources.Databases {
+		if d.Name == name {
+			return databaseFromConfig(d), true
+		}
+	}
+	return sources.Database{}, false
+}
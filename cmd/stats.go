@@ -8,7 +8,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"resticm/internal/config"
-	"resticm/internal/restic"
 )
 
 var statsCmd = &cobra.Command{
@@ -17,7 +16,8 @@ var statsCmd = &cobra.Command{
 	Long: `Show repository statistics.
 
 By default, shows stats from the active backend only.
-Use --all-backends to show stats from all configured backends.`,
+Use --all-backends to show stats from the primary and copy backends, or
+--backends to choose the backends (e.g. 'offsite' for a group).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runStats(cmd)
 	},
@@ -26,6 +26,7 @@ Use --all-backends to show stats from all configured backends.`,
 func init() {
 	rootCmd.AddCommand(statsCmd)
 	statsCmd.Flags().Bool("all-backends", false, "Show stats from all configured backends")
+	addBackendsFlag(statsCmd)
 }
 
 func runStats(cmd *cobra.Command) (err error) {
@@ -37,12 +38,16 @@ func runStats(cmd *cobra.Command) (err error) {
 	}
 
 	allBackends, _ := cmd.Flags().GetBool("all-backends")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
 	if allBackends {
 		flagMap["all-backends"] = true
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
 
 	// Log command start with context
 	LogCommandStart(cmd, flagMap)
@@ -52,69 +57,49 @@ func runStats(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
-	if allBackends {
-		return showStatsAllBackends(cfg)
+	backends, err := commandBackends(cmd, cfg, func() ([]string, error) {
+		return activeBackends(cfg, allBackends)
+	})
+	if err != nil {
+		return err
 	}
 
-	activeBackend, _ := config.GetActiveBackend()
-
-	var repo, password string
-	var awsKey, awsSecret string
-	var backendName string
-
-	if activeBackend == "" || activeBackend == "primary" {
-		repo = cfg.Repository
-		password = cfg.GetPassword()
-		awsKey = cfg.GetAWSAccessKeyID()
-		awsSecret = cfg.GetAWSSecretAccessKey()
-		backendName = "primary"
-	} else {
-		backend, ok := cfg.Backends[activeBackend]
-		if !ok {
-			return fmt.Errorf("backend '%s' not found", activeBackend)
+	if len(backends) == 1 {
+		repo, err := cfg.Resolve(backends[0])
+		if err != nil {
+			return err
 		}
-		repo = backend.Repository
-		password = backend.Password
-		awsKey = backend.AWSAccessKeyID
-		awsSecret = backend.AWSSecretAccessKey
-		backendName = activeBackend
+		return showStatsForBackend(repo)
 	}
-
-	return showStatsForBackend(backendName, repo, password, awsKey, awsSecret)
+	return showStatsAllBackends(cfg, backends)
 }
 
-func showStatsAllBackends(cfg *config.Config) error {
+func showStatsAllBackends(cfg *config.Config, backends []string) error {
 	fmt.Println()
 	fmt.Println("═══════════════════════════════════════")
 	fmt.Println(" ALL BACKENDS STATISTICS")
 	fmt.Println("═══════════════════════════════════════")
 
-	// Primary
-	fmt.Println("\n┌─ PRIMARY")
-	if err := showStatsForBackend("primary", cfg.Repository, cfg.GetPassword(),
-		cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey()); err != nil {
-		PrintError("Failed to get stats for primary: %v", err)
-	}
-
-	// Copy backends
-	for _, backendName := range cfg.CopyToBackends {
-		backend, ok := cfg.Backends[backendName]
-		if !ok {
+	for _, name := range backends {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
 			continue
 		}
-		fmt.Printf("\n┌─ BACKEND: %s\n", backendName)
-		if err := showStatsForBackend(backendName, backend.Repository, backend.Password,
-			backend.AWSAccessKeyID, backend.AWSSecretAccessKey); err != nil {
-			PrintError("Failed to get stats for %s: %v", backendName, err)
+		if name == config.SelectPrimary {
+			fmt.Println("\n┌─ PRIMARY")
+		} else {
+			fmt.Printf("\n┌─ BACKEND: %s\n", name)
+		}
+		if err := showStatsForBackend(repo); err != nil {
+			PrintError("Failed to get stats for %s: %v", name, err)
 		}
 	}
 
 	return nil
 }
 
-func showStatsForBackend(name, repo, password, awsKey, awsSecret string) error {
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+func showStatsForBackend(repo *config.Repository) error {
+	executor := newExecutor(repo)
 
 	stats, err := executor.GetStats()
 	if err != nil {
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"resticm/internal/config"
+	"resticm/internal/history"
+	"resticm/internal/redact"
+	"resticm/internal/restic"
+	"resticm/internal/status"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show backup health across all backends",
+	Long: `Show backup health for the primary repository and every backend in
+copy_to_backends.
+
+For each repository it reports:
+  - Latest snapshot age per host
+  - Last successful run recorded in the history
+  - Last deep check
+  - Repository locks
+  - Repository size
+
+Each value is compared against the thresholds in the 'status' section of
+the configuration, producing OK, WARN or CRIT. The exit code follows the
+Nagios/Icinga plugin convention (0 OK, 1 WARN, 2 CRIT, 3 UNKNOWN), so the
+command can be used directly as a monitoring check:
+
+  resticm status --nagios`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStatus(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().String("host", "", "Only evaluate snapshots from this host")
+	statusCmd.Flags().Bool("no-size", false, "Skip computing repository size (faster on large repositories)")
+	statusCmd.Flags().Bool("nagios", false, "Print a single line with performance data for monitoring plugins")
+}
+
+func runStatus(cmd *cobra.Command) error {
+	cfg := GetConfig()
+	if cfg == nil {
+		return fmt.Errorf("configuration not loaded")
+	}
+
+	host, _ := cmd.Flags().GetString("host")
+	noSize, _ := cmd.Flags().GetBool("no-size")
+	nagios, _ := cmd.Flags().GetBool("nagios")
+
+	thresholds, err := statusThresholds(cfg)
+	if err != nil {
+		return &ExitError{Code: status.Unknown.ExitCode(), Err: err}
+	}
+
+	if err := restic.CheckResticInstalled(); err != nil {
+		return &ExitError{Code: status.Unknown.ExitCode(), Err: err}
+	}
+
+	report := collectReport(cfg, host, noSize)
+	report.Evaluate(thresholds, time.Now())
+
+	if logger != nil {
+		logger.Info("Status check: %s - %s", report.State, report.Summary())
+	}
+
+	switch {
+	case IsJSONOutput():
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(redact.String(string(output)))
+	case nagios:
+		fmt.Println(redact.String(nagiosLine(report, thresholds)))
+	default:
+		printStatusReport(report)
+	}
+
+	if report.State != status.OK {
+		return &ExitError{Code: report.State.ExitCode()}
+	}
+	return nil
+}
+
+// collectReport gathers the status of the primary repository and every copy backend
+func collectReport(cfg *config.Config, host string, noSize bool) *status.Report {
+	var store *history.Store
+	if cfg.History.Enabled {
+		store = history.NewStore(config.StateDir())
+	}
+
+	report := &status.Report{}
+
+	// Primary
+	primary, _ := cfg.Resolve(config.SelectPrimary)
+	report.Backends = append(report.Backends,
+		collectBackendStatus(cfg, primary, "backup", store, host, noSize))
+
+	// Copy backends
+	for _, backendName := range cfg.CopyToBackends {
+		backend, err := cfg.Resolve(backendName)
+		if err != nil {
+			report.Backends = append(report.Backends, &status.BackendStatus{
+				Name:  backendName,
+				Error: "backend not found in configuration",
+			})
+			continue
+		}
+		report.Backends = append(report.Backends,
+			collectBackendStatus(cfg, backend, "copy", store, host, noSize))
+	}
+
+	if host == "" {
+		rememberReport(report)
+	}
+	return report
+}
+
+// collectBackendStatus gathers the facts needed to evaluate one repository.
+// step is the history step that proves the backend was updated (backup or copy).
+func collectBackendStatus(cfg *config.Config, repo *config.Repository,
+	step string, store *history.Store, host string, noSize bool) *status.BackendStatus {
+	name, repository := repo.Name, repo.URL
+	executor := newExecutor(repo)
+	executor.NoLock = true
+
+	b := &status.BackendStatus{
+		Name:             name,
+		Repository:       repository,
+		HistoryEnabled:   store != nil,
+		DeepCheckEnabled: cfg.DeepCheckIntervalDays > 0,
+	}
+
+	snapshots, err := executor.ListSnapshots()
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+
+	// Latest snapshot per host
+	latest := make(map[string]*status.HostSnapshot)
+	for _, s := range snapshots {
+		if host != "" && s.Hostname != host {
+			continue
+		}
+		b.SnapshotCount++
+		h, ok := latest[s.Hostname]
+		if !ok {
+			h = &status.HostSnapshot{Hostname: s.Hostname}
+			latest[s.Hostname] = h
+		}
+		h.Count++
+		if s.Time.After(h.Latest) {
+			h.Latest = s.Time
+		}
+	}
+	for _, h := range latest {
+		b.Hosts = append(b.Hosts, *h)
+	}
+
+	// Last successful run
+	if store != nil {
+		if run, at, err := store.LastSuccessfulStep(step, name); err == nil && run != nil {
+			b.LastRun = at
+			b.LastRunID = run.ID
+		}
+	}
+
+	// Last deep check
+	if tracker, err := restic.NewDeepCheckTracker(repository); err == nil {
+		b.LastDeepCheck, _ = tracker.LastCheck()
+	}
+
+	// Locks
+	now := time.Now()
+	if locks, err := executor.ListLocks(); err == nil {
+		for _, l := range locks {
+			b.Locks = append(b.Locks, status.Lock{Hostname: l.Hostname, PID: l.PID, Age: now.Sub(l.Time)})
+		}
+	} else if logger != nil {
+		logger.Warn("Could not list locks on %s: %v", name, err)
+	}
+
+	// Size
+	if !noSize {
+		if size, err := executor.GetRepositorySize(); err == nil {
+			b.Size = size
+			b.SizeKnown = true
+		} else if logger != nil {
+			logger.Warn("Could not compute repository size on %s: %v", name, err)
+		}
+	}
+
+	return b
+}
+
+// statusThresholds builds the thresholds from defaults and the configuration
+func statusThresholds(cfg *config.Config) (status.Thresholds, error) {
+	t := status.DefaultThresholds()
+
+	// Deep checks default to a week of grace after the configured interval
+	if cfg.DeepCheckIntervalDays > 0 {
+		interval := time.Duration(cfg.DeepCheckIntervalDays) * 24 * time.Hour
+		t.DeepCheckAgeWarn = interval + 7*24*time.Hour
+		t.DeepCheckAgeCrit = 2 * interval
+	}
+
+	overrides := []struct {
+		value  string
+		target *time.Duration
+	}{
+		{cfg.Status.SnapshotAgeWarn, &t.SnapshotAgeWarn},
+		{cfg.Status.SnapshotAgeCrit, &t.SnapshotAgeCrit},
+		{cfg.Status.LastRunAgeWarn, &t.LastRunAgeWarn},
+		{cfg.Status.LastRunAgeCrit, &t.LastRunAgeCrit},
+		{cfg.Status.DeepCheckAgeWarn, &t.DeepCheckAgeWarn},
+		{cfg.Status.DeepCheckAgeCrit, &t.DeepCheckAgeCrit},
+		{cfg.Status.LockAgeWarn, &t.LockAgeWarn},
+		{cfg.Status.ReplicationLagWarn, &t.ReplicationLagWarn},
+		{cfg.Status.ReplicationLagCrit, &t.ReplicationLagCrit},
+	}
+	for _, o := range overrides {
+		if o.value == "" {
+			continue
+		}
+		d, err := config.ParseDuration(o.value)
+		if err != nil {
+			return t, err
+		}
+		*o.target = d
+	}
+	return t, nil
+}
+
+// printStatusReport prints the human-readable report
+func printStatusReport(report *status.Report) {
+	fmt.Println()
+	fmt.Println("═══════════════════════════════════════════════════")
+	fmt.Println(" RESTICM STATUS")
+	fmt.Println("═══════════════════════════════════════════════════")
+
+	for _, b := range report.Backends {
+		fmt.Printf("\n  ┌─ %s [%s]\n", b.Name, b.State)
+		if b.Repository != "" {
+			fmt.Printf("  │ Repository:  %s\n", redact.String(b.Repository))
+		}
+		if b.Error == "" {
+			fmt.Printf("  │ Snapshots:   %d (%d host(s))\n", b.SnapshotCount, len(b.Hosts))
+			if b.SizeKnown {
+				fmt.Printf("  │ Size:        %s\n", formatBytes(b.Size))
+			}
+			if !b.LastRun.IsZero() {
+				fmt.Printf("  │ Last run:    %s (%s)\n", b.LastRun.Local().Format("2006-01-02 15:04"), shortRunID(b.LastRunID))
+			}
+			if !b.LastDeepCheck.IsZero() {
+				fmt.Printf("  │ Deep check:  %s\n", b.LastDeepCheck.Local().Format("2006-01-02 15:04"))
+			}
+		}
+		for _, c := range b.Checks {
+			fmt.Printf("  │ %s %s\n", stateIcon(c.State), redact.String(c.Message))
+		}
+		fmt.Println("  └─")
+	}
+
+	fmt.Println()
+	switch report.State {
+	case status.OK:
+		PrintSuccess("Overall status: %s", report.State)
+	case status.Warn:
+		PrintWarning("Overall status: %s - %s", report.State, report.Summary())
+	default:
+		PrintError("Overall status: %s - %s", report.State, report.Summary())
+	}
+}
+
+// stateIcon returns the icon used for a check state
+func stateIcon(s status.State) string {
+	switch s {
+	case status.OK:
+		return "✅"
+	case status.Warn:
+		return "⚠️ "
+	case status.Crit:
+		return "❌"
+	default:
+		return "❓"
+	}
+}
+
+// nagiosLine formats the report as a monitoring plugin output line with performance data
+func nagiosLine(report *status.Report, t status.Thresholds) string {
+	var perf []string
+	for _, b := range report.Backends {
+		name := strings.ReplaceAll(b.Name, " ", "_")
+		hosts := append([]status.HostSnapshot(nil), b.Hosts...)
+		sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+		for _, h := range hosts {
+			perf = append(perf, fmt.Sprintf("'%s_%s_age'=%ds;%d;%d", name, h.Hostname,
+				int64(h.Age.Seconds()), int64(t.SnapshotAgeWarn.Seconds()), int64(t.SnapshotAgeCrit.Seconds())))
+		}
+		perf = append(perf, fmt.Sprintf("'%s_snapshots'=%d", name, b.SnapshotCount))
+		perf = append(perf, fmt.Sprintf("'%s_locks'=%d", name, len(b.Locks)))
+		if b.SizeKnown {
+			perf = append(perf, fmt.Sprintf("'%s_size'=%dB", name, b.Size))
+		}
+	}
+	return fmt.Sprintf("RESTICM %s - %s | %s", report.State, report.Summary(), strings.Join(perf, " "))
+}
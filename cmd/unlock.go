@@ -7,7 +7,6 @@ import (
 	"github.com/spf13/cobra"
 
 	"resticm/internal/config"
-	"resticm/internal/restic"
 	"resticm/internal/security"
 )
 
@@ -20,7 +19,8 @@ Use this command if a previous resticm process crashed and left
 a lock file behind.
 
 By default, unlocks the active backend only.
-Use --all-backends to unlock all configured backends.`,
+Use --all-backends to unlock the primary and copy backends, or --backends to
+choose the backends (e.g. 'offsite' for a group). --backends implies --restic.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runUnlock(cmd)
 	},
@@ -31,6 +31,7 @@ func init() {
 	unlockCmd.Flags().BoolP("force", "f", false, "Force unlock without confirmation")
 	unlockCmd.Flags().Bool("all-backends", false, "Unlock all configured backends")
 	unlockCmd.Flags().Bool("restic", false, "Also unlock restic repository locks")
+	addBackendsFlag(unlockCmd)
 }
 
 func runUnlock(cmd *cobra.Command) (err error) {
@@ -38,7 +39,9 @@ func runUnlock(cmd *cobra.Command) (err error) {
 
 	force, _ := cmd.Flags().GetBool("force")
 	allBackends, _ := cmd.Flags().GetBool("all-backends")
+	backendsExpr, _ := cmd.Flags().GetString("backends")
 	unlockRestic, _ := cmd.Flags().GetBool("restic")
+	unlockRestic = unlockRestic || backendsExpr != ""
 
 	// Build flag map for logging
 	flagMap := make(map[string]interface{})
@@ -48,6 +51,9 @@ func runUnlock(cmd *cobra.Command) (err error) {
 	if allBackends {
 		flagMap["all-backends"] = true
 	}
+	if backendsExpr != "" {
+		flagMap["backends"] = backendsExpr
+	}
 	if unlockRestic {
 		flagMap["restic"] = true
 	}
@@ -60,10 +66,28 @@ func runUnlock(cmd *cobra.Command) (err error) {
 		LogCommandEnd(cmd, startTime, err)
 	}()
 
-	// Handle resticm lock file
-	lock := security.NewLock("")
+	// Handle the resticm lock files of the selected backends
+	var backends []string
+	cfg := GetConfig()
+	if cfg != nil && (unlockRestic || cfg.LockScope == security.ScopeRepository) {
+		backends, err = commandBackends(cmd, cfg, func() ([]string, error) {
+			return activeBackends(cfg, allBackends)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	locks, err := instanceLocks(backends...)
+	if err != nil {
+		return err
+	}
 
-	if lock.IsLocked() {
+	present := false
+	for _, lock := range locks {
+		if !lock.IsLocked() {
+			continue
+		}
+		present = true
 		lock.PrintLockInfo()
 
 		if !force {
@@ -73,86 +97,56 @@ func runUnlock(cmd *cobra.Command) (err error) {
 			_, _ = fmt.Scanln(&response)
 			if response != "y" && response != "Y" {
 				PrintInfo("Cancelled")
-			} else {
-				if err := lock.ForceUnlock(); err != nil {
-					return err
-				}
-				PrintSuccess("Resticm lock file removed")
-			}
-		} else {
-			if err := lock.ForceUnlock(); err != nil {
-				return err
+				continue
 			}
-			PrintSuccess("Resticm lock file removed")
 		}
-	} else {
+		if err := lock.ForceUnlock(); err != nil {
+			return err
+		}
+		PrintSuccess("Resticm lock file removed")
+	}
+	if !present {
 		PrintInfo("No resticm lock file present")
 	}
 
 	// If --restic flag, also unlock restic repositories
 	if unlockRestic {
-		cfg := GetConfig()
 		if cfg == nil {
 			return fmt.Errorf("configuration not loaded")
 		}
 
-		if allBackends {
-			return unlockAllResticRepos(cfg, force)
-		}
-
-		// Unlock active backend only
-		activeBackend, _ := config.GetActiveBackend()
-		var repo, password, awsKey, awsSecret string
-
-		if activeBackend == "" || activeBackend == "primary" {
-			repo = cfg.Repository
-			password = cfg.GetPassword()
-			awsKey = cfg.GetAWSAccessKeyID()
-			awsSecret = cfg.GetAWSSecretAccessKey()
-		} else {
-			backend, ok := cfg.Backends[activeBackend]
-			if !ok {
-				return fmt.Errorf("backend '%s' not found", activeBackend)
+		if len(backends) == 1 {
+			repo, err := cfg.Resolve(backends[0])
+			if err != nil {
+				return err
 			}
-			repo = backend.Repository
-			password = backend.Password
-			awsKey = backend.AWSAccessKeyID
-			awsSecret = backend.AWSSecretAccessKey
+			return unlockResticRepo(repo)
 		}
-
-		return unlockResticRepo("active", repo, password, awsKey, awsSecret)
+		return unlockResticRepos(cfg, backends)
 	}
 
 	return nil
 }
 
-func unlockAllResticRepos(cfg *config.Config, force bool) error {
-	PrintInfo("Unlocking all restic repositories...")
-
-	// Primary
-	if err := unlockResticRepo("primary", cfg.Repository, cfg.GetPassword(),
-		cfg.GetAWSAccessKeyID(), cfg.GetAWSSecretAccessKey()); err != nil {
-		PrintError("Failed to unlock primary: %v", err)
-	}
+func unlockResticRepos(cfg *config.Config, backends []string) error {
+	PrintInfo("Unlocking %d restic repositories...", len(backends))
 
-	// Copy backends
-	for _, backendName := range cfg.CopyToBackends {
-		backend, ok := cfg.Backends[backendName]
-		if !ok {
+	for _, name := range backends {
+		repo, err := cfg.Resolve(name)
+		if err != nil {
 			continue
 		}
-		if err := unlockResticRepo(backendName, backend.Repository, backend.Password,
-			backend.AWSAccessKeyID, backend.AWSSecretAccessKey); err != nil {
-			PrintError("Failed to unlock %s: %v", backendName, err)
+		if err := unlockResticRepo(repo); err != nil {
+			PrintError("Failed to unlock %s: %v", name, err)
 		}
 	}
 
 	return nil
 }
 
-func unlockResticRepo(name, repo, password, awsKey, awsSecret string) error {
-	executor := restic.NewExecutor(repo, password)
-	executor.SetAWSCredentials(awsKey, awsSecret)
+func unlockResticRepo(repo *config.Repository) error {
+	name := repo.Name
+	executor := newExecutor(repo)
 
 	PrintInfo("Unlocking restic repository: %s", name)
 	if err := executor.Run("unlock"); err != nil {
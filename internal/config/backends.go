@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keywords of backend selections
+const (
+	SelectPrimary = "primary" // The primary repository
+	SelectCopy    = "copy"    // The backends of copy_to_backends
+	SelectAll     = "all"     // The primary and every configured backend
+)
+
+// SelectBackends resolves a backend selection: a comma separated list of
+// backend names, group names, "primary", "copy" (the copy_to_backends) and
+// "all" (the primary and every backend). A term prefixed with "!" excludes
+// its backends. The result keeps the order of the first appearance.
+//
+// Examples: "offsite", "primary,offsite", "all,!wasabi"
+func (c *Config) SelectBackends(expr string) ([]string, error) {
+	var selected, excluded []string
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		exclude := strings.HasPrefix(term, "!")
+		names, err := c.expandSelection(strings.TrimPrefix(term, "!"))
+		if err != nil {
+			return nil, err
+		}
+		if exclude {
+			excluded = append(excluded, names...)
+		} else {
+			selected = append(selected, names...)
+		}
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, name := range excluded {
+		seen[name] = true
+	}
+	for _, name := range selected {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("backend selection '%s' matches no backend", expr)
+	}
+	return result, nil
+}
+
+// expandSelection returns the backends of one selection term
+func (c *Config) expandSelection(term string) ([]string, error) {
+	switch term {
+	case SelectPrimary:
+		return []string{SelectPrimary}, nil
+	case SelectCopy:
+		return c.CopyToBackends, nil
+	case SelectAll:
+		return append([]string{SelectPrimary}, c.backendNames()...), nil
+	}
+	if members, ok := c.BackendGroups[term]; ok {
+		return members, nil
+	}
+	if _, ok := c.Backends[term]; ok {
+		return []string{term}, nil
+	}
+	return nil, fmt.Errorf("unknown backend or group '%s'", term)
+}
+
+// backendNames returns the names of the configured backends, sorted
+func (c *Config) backendNames() []string {
+	names := make([]string, 0, len(c.Backends))
+	for name := range c.Backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateBackendGroups checks that group names are free and that groups
+// only list the primary and configured backends
+func (c *Config) validateBackendGroups() error {
+	for group, members := range c.BackendGroups {
+		switch {
+		case group == SelectPrimary || group == SelectCopy || group == SelectAll:
+			return fmt.Errorf("backend_groups: '%s' is reserved", group)
+		case strings.HasPrefix(group, "!") || strings.Contains(group, ","):
+			return fmt.Errorf("backend_groups: invalid group name '%s'", group)
+		}
+		if _, ok := c.Backends[group]; ok {
+			return fmt.Errorf("backend_groups: '%s' is also a backend name", group)
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("backend_groups.%s: group is empty", group)
+		}
+		for _, member := range members {
+			if _, ok := c.Backends[member]; !ok && member != SelectPrimary {
+				return fmt.Errorf("backend_groups.%s: unknown backend '%s'", group, member)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func selectionConfig() *Config {
+	return &Config{
+		Repository: "/tmp/repo",
+		Password:   "secret",
+		Backends: map[string]Backend{
+			"b2":     {Repository: "b2:bucket:restic"},
+			"wasabi": {Repository: "s3:s3.wasabisys.com/bucket"},
+			"local":  {Repository: "/mnt/backup"},
+		},
+		BackendGroups:  map[string][]string{"offsite": {"b2", "wasabi"}},
+		CopyToBackends: []string{"local", "b2"},
+	}
+}
+
+func TestSelectBackends(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{"b2", []string{"b2"}, false},
+		{"offsite", []string{"b2", "wasabi"}, false},
+		{"primary, offsite", []string{"primary", "b2", "wasabi"}, false},
+		{"copy", []string{"local", "b2"}, false},
+		{"all", []string{"primary", "b2", "local", "wasabi"}, false},
+		{"all,!wasabi", []string{"primary", "b2", "local"}, false},
+		{"!offsite,all", []string{"primary", "local"}, false},
+		{"b2,offsite,b2", []string{"b2", "wasabi"}, false},
+		{"offsite,!offsite", nil, true},
+		{"nope", nil, true},
+	}
+
+	cfg := selectionConfig()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := cfg.SelectBackends(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelectBackends() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectBackends() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBackendGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  map[string][]string
+		wantErr bool
+	}{
+		{"valid", map[string][]string{"offsite": {"b2", "wasabi"}, "critical": {"primary", "local"}}, false},
+		{"reserved", map[string][]string{"all": {"b2"}}, true},
+		{"backend name", map[string][]string{"b2": {"wasabi"}}, true},
+		{"unknown member", map[string][]string{"offsite": {"b2", "glacier"}}, true},
+		{"empty", map[string][]string{"offsite": {}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selectionConfig()
+			cfg.Directories = []string{"/data"}
+			cfg.BackendGroups = tt.groups
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
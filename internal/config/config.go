@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"resticm/internal/redact"
 )
 
 // Config represents the main configuration structure
@@ -20,9 +25,29 @@ type Config struct {
 	AWSAccessKeyID     string `yaml:"aws_access_key_id"`
 	AWSSecretAccessKey string `yaml:"aws_secret_access_key"`
 
+	// Extra environment of restic for the primary repository (AWS_PROFILE,
+	// AWS_DEFAULT_REGION, B2_ACCOUNT_ID, AZURE_ACCOUNT_KEY, RCLONE_CONFIG, ...)
+	Env map[string]string `yaml:"env"`
+
+	// Typed credentials of the primary repository type (b2, azure, gs,
+	// swift, rest, sftp)
+	Credentials `yaml:",inline"`
+
+	// Cache directory of restic and bandwidth limits in KiB/s (0 for no
+	// limit). Backends inherit them unless they set their own.
+	CacheDir      string `yaml:"cache_dir"`
+	LimitUpload   int    `yaml:"limit_upload"`
+	LimitDownload int    `yaml:"limit_download"`
+
 	// Directories to backup
 	Directories []string `yaml:"directories"`
 
+	// Additional backup sources such as database dumps
+	Sources SourcesConfig `yaml:"sources"`
+
+	// Directories backed up from a filesystem snapshot
+	FSSnapshots FSSnapshotConfig `yaml:"fs_snapshots"`
+
 	// Exclude patterns
 	ExcludePatterns []string `yaml:"exclude_patterns"`
 	ExcludeFile     string   `yaml:"exclude_file"`
@@ -39,9 +64,16 @@ type Config struct {
 	// Secondary backends
 	Backends map[string]Backend `yaml:"backends"`
 
+	// Named groups of backends, usable in --backends selections
+	BackendGroups map[string][]string `yaml:"backend_groups"`
+
 	// Backends to copy to after backup
 	CopyToBackends []string `yaml:"copy_to_backends"`
 
+	// Number of copy backends synchronized at the same time (default 1, one
+	// after the other). Each backend runs its copy, forget, prune and check.
+	CopyParallelism int `yaml:"copy_parallelism"`
+
 	// Hooks configuration
 	Hooks HookConfig `yaml:"hooks"`
 
@@ -53,6 +85,23 @@ type Config struct {
 
 	// Verify no locks remain after operations (recommended for S3 with Object Lock)
 	VerifyNoLocks bool `yaml:"verify_no_locks"`
+
+	// Scope of the local lock preventing concurrent runs: global (default),
+	// config (per config file) or repository (per repository URL)
+	LockScope string `yaml:"lock_scope"`
+
+	// Remove locks left on the repositories by processes of this host that
+	// are no longer running, instead of only reporting them
+	AutoRemoveStaleLocks bool `yaml:"auto_remove_stale_locks"`
+
+	// Run history configuration
+	History HistoryConfig `yaml:"history"`
+
+	// Health thresholds for the status command
+	Status StatusConfig `yaml:"status"`
+
+	// Prometheus metrics export
+	Metrics MetricsConfig `yaml:"metrics"`
 }
 
 // RetentionConfig defines the retention policy
@@ -63,6 +112,35 @@ type RetentionConfig struct {
 	KeepWeekly  int    `yaml:"keep_weekly"`
 	KeepMonthly int    `yaml:"keep_monthly"`
 	KeepYearly  int    `yaml:"keep_yearly"`
+
+	// GroupBy groups snapshots for the policy, a comma-separated list of
+	// host, paths and tags (restic default: host,paths)
+	GroupBy string `yaml:"group_by"`
+}
+
+// validate checks the keep counts and group_by fields of a retention policy
+func (r *RetentionConfig) validate(prefix string) error {
+	for name, value := range map[string]int{
+		"keep_hourly":  r.KeepHourly,
+		"keep_daily":   r.KeepDaily,
+		"keep_weekly":  r.KeepWeekly,
+		"keep_monthly": r.KeepMonthly,
+		"keep_yearly":  r.KeepYearly,
+	} {
+		if value < 0 {
+			return fmt.Errorf("%s%s must not be negative", prefix, name)
+		}
+	}
+	if r.GroupBy != "" {
+		for _, field := range strings.Split(r.GroupBy, ",") {
+			switch strings.TrimSpace(field) {
+			case "host", "paths", "tags":
+			default:
+				return fmt.Errorf("%sgroup_by: invalid field '%s' (use host, paths and tags)", prefix, field)
+			}
+		}
+	}
+	return nil
 }
 
 // Backend represents a secondary backend configuration
@@ -71,14 +149,273 @@ type Backend struct {
 	Password           string `yaml:"password"`
 	AWSAccessKeyID     string `yaml:"aws_access_key_id"`
 	AWSSecretAccessKey string `yaml:"aws_secret_access_key"`
+
+	// Extra environment of restic for this backend, like Config.Env
+	Env map[string]string `yaml:"env"`
+
+	// Typed credentials of the backend repository type
+	Credentials `yaml:",inline"`
+
+	// Overrides of the global cache directory, limits and retention policy
+	CacheDir      string           `yaml:"cache_dir"`
+	LimitUpload   int              `yaml:"limit_upload"`
+	LimitDownload int              `yaml:"limit_download"`
+	Retention     *RetentionConfig `yaml:"retention"`
 }
 
 // HookConfig defines hook scripts
 type HookConfig struct {
-	PreBackup  string `yaml:"pre_backup"`
-	PostBackup string `yaml:"post_backup"`
-	OnError    string `yaml:"on_error"`
-	OnSuccess  string `yaml:"on_success"`
+	PreBackup  Hook `yaml:"pre_backup"`
+	PostBackup Hook `yaml:"post_backup"`
+	OnError    Hook `yaml:"on_error"`
+	OnSuccess  Hook `yaml:"on_success"`
+
+	// Timeout applies to hooks without their own timeout (e.g. "10m")
+	Timeout string `yaml:"timeout"`
+
+	// Steps configures hooks around individual workflow steps, keyed by step name
+	Steps map[string]StepHookConfig `yaml:"steps"`
+}
+
+// Hook defines a hook. In YAML it is either the path to an executable or a
+// directory of executables, or a mapping with one of path, command (run by
+// the shell) or argv, plus options.
+type Hook struct {
+	Path    string   `yaml:"path"`
+	Command string   `yaml:"command"`
+	Argv    []string `yaml:"argv"`
+	Timeout string   `yaml:"timeout"`
+	Workdir string   `yaml:"workdir"`
+	User    string   `yaml:"user"`
+
+	// ContinueOnError runs the remaining scripts of a hook directory after
+	// one fails instead of stopping
+	ContinueOnError bool `yaml:"continue_on_error"`
+}
+
+// UnmarshalYAML accepts a plain path as well as the mapping form
+func (h *Hook) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*h = Hook{}
+		return value.Decode(&h.Path)
+	}
+	type plain Hook
+	return value.Decode((*plain)(h))
+}
+
+// IsSet reports whether the hook has something to run
+func (h Hook) IsSet() bool {
+	return h.Path != "" || h.Command != "" || len(h.Argv) > 0
+}
+
+// String returns a short description of the hook
+func (h Hook) String() string {
+	switch {
+	case h.Path != "":
+		return h.Path
+	case h.Command != "":
+		return h.Command
+	default:
+		return strings.Join(h.Argv, " ")
+	}
+}
+
+// validate checks that exactly one way of running the hook is set
+func (h Hook) validate() error {
+	set := 0
+	for _, ok := range []bool{h.Path != "", h.Command != "", len(h.Argv) > 0} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of path, command and argv may be set")
+	}
+	if set == 0 && (h.Timeout != "" || h.Workdir != "" || h.User != "" || h.ContinueOnError) {
+		return fmt.Errorf("one of path, command or argv is required")
+	}
+	if h.Timeout != "" {
+		if _, err := ParseDuration(h.Timeout); err != nil {
+			return fmt.Errorf("timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+// StepHookConfig defines the hooks run around a workflow step
+type StepHookConfig struct {
+	Pre  Hook `yaml:"pre"`
+	Post Hook `yaml:"post"`
+
+	// OnPreFailure is "abort" (default, the step fails) or "skip"
+	OnPreFailure string `yaml:"on_pre_failure"`
+
+	// Backends overrides the hooks for individual backends
+	Backends map[string]StepHookConfig `yaml:"backends"`
+}
+
+// HookSteps lists the workflow steps that accept hooks
+var HookSteps = []string{"backup", "forget", "prune", "check", "copy"}
+
+// StepHooks returns the hooks for a step on a backend, with the backend
+// override applied on top of the step defaults
+func (h HookConfig) StepHooks(step, backend string) StepHookConfig {
+	hooks := h.Steps[step]
+	override, ok := hooks.Backends[backend]
+	hooks.Backends = nil
+	if !ok {
+		return hooks
+	}
+	if override.Pre.IsSet() {
+		hooks.Pre = override.Pre
+	}
+	if override.Post.IsSet() {
+		hooks.Post = override.Post
+	}
+	if override.OnPreFailure != "" {
+		hooks.OnPreFailure = override.OnPreFailure
+	}
+	return hooks
+}
+
+// SourcesConfig defines backup sources besides the directories
+type SourcesConfig struct {
+	Databases []DatabaseSource `yaml:"databases"`
+	Commands  []CommandSource  `yaml:"commands"`
+	Docker    DockerSource     `yaml:"docker"`
+}
+
+// DockerSource defines the backup of the volumes of labelled containers
+type DockerSource struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Socket is the Docker API socket (default: DOCKER_HOST or
+	// /var/run/docker.sock)
+	Socket string `yaml:"socket"`
+
+	// Label selects the containers (default: resticm.enable=true)
+	Label string `yaml:"label"`
+
+	// Mode applies to containers without a resticm.mode label:
+	// none, pause or stop (default: none)
+	Mode string `yaml:"mode"`
+
+	// StopTimeout is the grace period before a stopped container is killed
+	// (default: 30s)
+	StopTimeout string `yaml:"stop_timeout"`
+
+	// Timeout bounds each API call and pre/post command (default: 10m)
+	Timeout string `yaml:"timeout"`
+}
+
+// CommandSource defines a command whose standard output is backed up into
+// its own snapshot, tagged cmd:<name>
+type CommandSource struct {
+	Name string `yaml:"name"`
+
+	// Command is run directly, without a shell
+	Command []string `yaml:"command"`
+
+	// Filename of the output inside the snapshot (default: the name)
+	Filename string `yaml:"filename"`
+
+	// Tags are added to the snapshot besides the default tags
+	Tags []string `yaml:"tags"`
+}
+
+// DatabaseSource defines a database whose dump is streamed into its own
+// snapshot, tagged db:<name>
+type DatabaseSource struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Database string `yaml:"database"`
+
+	// Path is the database file (sqlite)
+	Path string `yaml:"path"`
+
+	// Command overrides the dump program (default: pg_dump, mysqldump,
+	// mariadb-dump or sqlite3)
+	Command string `yaml:"command"`
+
+	// Options are extra arguments passed to the dump program
+	Options []string `yaml:"options"`
+}
+
+// DatabaseTypes lists the supported database source types
+var DatabaseTypes = []string{"postgres", "mysql", "mariadb", "sqlite"}
+
+// sourceNameRe matches names usable in a tag and a file name
+var sourceNameRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// validate checks the settings required by the database type
+func (d DatabaseSource) validate() error {
+	switch d.Type {
+	case "postgres", "mysql", "mariadb":
+		if d.Database == "" {
+			return fmt.Errorf("database is required for type %s", d.Type)
+		}
+	case "sqlite":
+		if d.Path == "" {
+			return fmt.Errorf("path is required for type sqlite")
+		}
+	default:
+		return fmt.Errorf("invalid type '%s' (valid: %s)", d.Type, strings.Join(DatabaseTypes, ", "))
+	}
+	if d.Port < 0 || d.Port > 65535 {
+		return fmt.Errorf("invalid port %d", d.Port)
+	}
+	return nil
+}
+
+// FSSnapshotConfig defines the directories backed up from an LVM, Btrfs or
+// ZFS snapshot instead of the live filesystem
+type FSSnapshotConfig struct {
+	// MountDir holds the snapshot mount points (default: /run/resticm/snapshots)
+	MountDir string `yaml:"mount_dir"`
+
+	Directories []FSSnapshotDirectory `yaml:"directories"`
+}
+
+// FSSnapshotDirectory defines the snapshot of one configured directory
+type FSSnapshotDirectory struct {
+	// Path is the directory as listed in directories: the mount point of the
+	// logical volume or dataset, or the btrfs subvolume
+	Path string `yaml:"path"`
+	Type string `yaml:"type"`
+
+	// Volume is the LVM logical volume (vg/lv) or the ZFS dataset
+	Volume string `yaml:"volume"`
+
+	// Size of the LVM snapshot (e.g. "5G", default: 10%ORIGIN)
+	Size string `yaml:"size"`
+
+	// MountOptions are extra mount options (e.g. "nouuid" for XFS)
+	MountOptions string `yaml:"mount_options"`
+}
+
+// FSSnapshotTypes lists the supported filesystem snapshot types
+var FSSnapshotTypes = []string{"lvm", "btrfs", "zfs"}
+
+// validate checks the settings required by the snapshot type
+func (d FSSnapshotDirectory) validate() error {
+	switch d.Type {
+	case "lvm":
+		if !strings.Contains(d.Volume, "/") {
+			return fmt.Errorf("volume must be the logical volume as vg/lv")
+		}
+	case "zfs":
+		if d.Volume == "" {
+			return fmt.Errorf("volume (the dataset) is required for type zfs")
+		}
+	case "btrfs":
+	default:
+		return fmt.Errorf("invalid type '%s' (valid: %s)", d.Type, strings.Join(FSSnapshotTypes, ", "))
+	}
+	return nil
 }
 
 // NotificationConfig defines notification settings
@@ -100,12 +437,62 @@ type ProviderConfig struct {
 
 // LoggingConfig defines logging settings
 type LoggingConfig struct {
-	File      string `yaml:"file"`
-	MaxSizeMB int    `yaml:"max_size_mb"`
-	MaxFiles  int    `yaml:"max_files"`
-	Level     string `yaml:"level"`
-	Console   bool   `yaml:"console"`
-	JSON      bool   `yaml:"json"`
+	File      string       `yaml:"file"`
+	MaxSizeMB int          `yaml:"max_size_mb"`
+	MaxFiles  int          `yaml:"max_files"`
+	Level     string       `yaml:"level"`
+	Console   bool         `yaml:"console"`
+	JSON      bool         `yaml:"json"`
+	Syslog    SyslogConfig `yaml:"syslog"`
+	Journald  bool         `yaml:"journald"`
+}
+
+// SyslogConfig defines the syslog output
+type SyslogConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Network  string `yaml:"network"`
+	Address  string `yaml:"address"`
+	Tag      string `yaml:"tag"`
+	Facility string `yaml:"facility"`
+}
+
+// HistoryConfig defines run history settings
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	MaxRuns int  `yaml:"max_runs"`
+}
+
+// StatusConfig defines the thresholds used by the status command.
+// Values are durations such as "26h" or "7d"; empty uses the default.
+type StatusConfig struct {
+	SnapshotAgeWarn  string `yaml:"snapshot_age_warn"`
+	SnapshotAgeCrit  string `yaml:"snapshot_age_crit"`
+	LastRunAgeWarn   string `yaml:"last_run_age_warn"`
+	LastRunAgeCrit   string `yaml:"last_run_age_crit"`
+	DeepCheckAgeWarn string `yaml:"deep_check_age_warn"`
+	DeepCheckAgeCrit string `yaml:"deep_check_age_crit"`
+	LockAgeWarn      string `yaml:"lock_age_warn"`
+
+	// Thresholds of 'resticm replication status' for the age of the oldest
+	// snapshot not yet copied to a backend
+	ReplicationLagWarn string `yaml:"replication_lag_warn"`
+	ReplicationLagCrit string `yaml:"replication_lag_crit"`
+}
+
+// MetricsConfig defines Prometheus metrics export settings
+type MetricsConfig struct {
+	// Textfile is written atomically at the end of each run for the
+	// node_exporter textfile collector
+	Textfile string `yaml:"textfile"`
+
+	// Listen is the address served by 'resticm metrics serve'
+	Listen string `yaml:"listen"`
+
+	// CacheTTL limits how often repositories are queried when serving (e.g. "5m")
+	CacheTTL string `yaml:"cache_ttl"`
+
+	// NoSize skips computing repository size, which can be slow
+	NoSize bool `yaml:"no_size"`
 }
 
 // DefaultConfig returns a config with default values
@@ -127,6 +514,10 @@ func DefaultConfig() *Config {
 			Level:     "info",
 			Console:   true,
 		},
+		History: HistoryConfig{
+			Enabled: true,
+			MaxRuns: 1000,
+		},
 	}
 }
 
@@ -166,6 +557,9 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Make sure secrets never reach the console, logs or notifications
+	redact.AddSecret(cfg.Secrets()...)
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -174,6 +568,93 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// Secrets returns every credential held by the configuration
+func (c *Config) Secrets() []string {
+	// The resolved values may come from RESTIC_PASSWORD and
+	// AWS_SECRET_ACCESS_KEY instead of the file
+	secrets := []string{c.Password, c.AWSSecretAccessKey, c.GetPassword(), c.GetAWSSecretAccessKey()}
+	secrets = append(secrets, envSecrets(c.Env)...)
+	secrets = append(secrets, c.Credentials.secrets()...)
+	for _, b := range c.Backends {
+		secrets = append(secrets, b.Password, b.AWSSecretAccessKey)
+		secrets = append(secrets, envSecrets(b.Env)...)
+		secrets = append(secrets, b.Credentials.secrets()...)
+	}
+	for _, db := range c.Sources.Databases {
+		secrets = append(secrets, db.Password)
+	}
+	for _, p := range c.Notifications.Providers {
+		secrets = append(secrets, p.Token)
+		if p.Type != "ntfy" {
+			// Webhook URLs embed their token in the path
+			secrets = append(secrets, p.URL)
+		}
+		for k, v := range p.Options {
+			key := strings.ToLower(k)
+			if strings.Contains(key, "token") || strings.Contains(key, "password") || strings.Contains(key, "secret") {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+	return secrets
+}
+
+// envSecrets returns the values of the secret variables of an environment
+// (AWS_SECRET_ACCESS_KEY, B2_ACCOUNT_KEY, AZURE_ACCOUNT_SAS, ...)
+func envSecrets(env map[string]string) []string {
+	var secrets []string
+	for k, v := range env {
+		key := strings.ToUpper(k)
+		for _, marker := range []string{"SECRET", "TOKEN", "PASSWORD", "_KEY", "_SAS"} {
+			if strings.Contains(key, marker) && !strings.HasSuffix(key, "_KEY_ID") {
+				secrets = append(secrets, v)
+				break
+			}
+		}
+	}
+	return secrets
+}
+
+// BackendEnv returns the extra restic environment of a backend, its env
+// section and typed credentials; an empty name or "primary" is the primary
+// repository
+func (c *Config) BackendEnv(name string) map[string]string {
+	if c == nil {
+		return nil
+	}
+	env, creds := c.Env, c.Credentials
+	if name != "" && name != "primary" {
+		env, creds = c.Backends[name].Env, c.Backends[name].Credentials
+	}
+	merged := creds.ResticEnv()
+	for k, v := range env {
+		merged[k] = v
+	}
+	return merged
+}
+
+// BackendOptions returns the restic extended options of a backend, like
+// BackendEnv
+func (c *Config) BackendOptions(name string) []string {
+	if c == nil {
+		return nil
+	}
+	if name != "" && name != "primary" {
+		return c.Backends[name].ResticOptions()
+	}
+	return c.ResticOptions()
+}
+
+// StateDir returns the directory holding resticm state (history, deep check records):
+// /var/lib/resticm when running as root, ~/.config/resticm otherwise
+func StateDir() string {
+	if IsRoot() {
+		return "/var/lib/resticm"
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "resticm")
+}
+
 // alternateConfigPaths stores paths of config files that exist but weren't selected
 var alternateConfigPaths []string
 
@@ -243,6 +724,34 @@ func resolveConfigPath(path string) (string, error) {
 	return "", fmt.Errorf("no configuration file found. Create one at ~/.config/resticm/config.yaml or /etc/resticm/config.yaml")
 }
 
+// envNameRe matches valid environment variable names
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnv checks the variables of an env section. The repository and
+// password are set by resticm, and credentials must be set in one place only.
+func validateEnv(section string, env map[string]string, awsKeyID, awsSecret string, creds Credentials) error {
+	for k := range env {
+		if !envNameRe.MatchString(k) {
+			return fmt.Errorf("%s: invalid variable name '%s'", section, k)
+		}
+		if strings.HasPrefix(k, "RESTIC_REPOSITORY") || strings.HasPrefix(k, "RESTIC_PASSWORD") {
+			return fmt.Errorf("%s: %s is set by resticm from the repository and password", section, k)
+		}
+	}
+	if _, ok := env["AWS_ACCESS_KEY_ID"]; ok && awsKeyID != "" {
+		return fmt.Errorf("%s: AWS_ACCESS_KEY_ID conflicts with aws_access_key_id", section)
+	}
+	if _, ok := env["AWS_SECRET_ACCESS_KEY"]; ok && awsSecret != "" {
+		return fmt.Errorf("%s: AWS_SECRET_ACCESS_KEY conflicts with aws_secret_access_key", section)
+	}
+	for _, f := range creds.fields() {
+		if _, ok := env[f.Env]; ok && f.Env != "" {
+			return fmt.Errorf("%s: %s conflicts with %s", section, f.Env, f.Name)
+		}
+	}
+	return nil
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.Repository == "" {
@@ -253,13 +762,215 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("password is required (set in config or RESTIC_PASSWORD env)")
 	}
 
-	if len(c.Directories) == 0 {
-		return fmt.Errorf("at least one directory to backup is required")
+	if err := validateEnv("env", c.Env, c.AWSAccessKeyID, c.AWSSecretAccessKey, c.Credentials); err != nil {
+		return err
+	}
+	if err := c.Credentials.validate("", c.Repository); err != nil {
+		return err
+	}
+	if err := validateLimits("", c.LimitUpload, c.LimitDownload); err != nil {
+		return err
+	}
+	for name, b := range c.Backends {
+		if err := validateEnv("backends."+name+".env", b.Env, b.AWSAccessKeyID, b.AWSSecretAccessKey, b.Credentials); err != nil {
+			return err
+		}
+		if err := b.Credentials.validate("backends."+name+".", b.Repository); err != nil {
+			return err
+		}
+		if err := validateLimits("backends."+name+".", b.LimitUpload, b.LimitDownload); err != nil {
+			return err
+		}
+		if b.Retention != nil {
+			if err := b.Retention.validate("backends." + name + ".retention."); err != nil {
+				return err
+			}
+		}
 	}
 
+	if len(c.Directories) == 0 && len(c.Sources.Databases) == 0 && len(c.Sources.Commands) == 0 && !c.Sources.Docker.Enabled {
+		return fmt.Errorf("at least one directory or source to backup is required")
+	}
+
+	seen := make(map[string]bool)
+	for i, db := range c.Sources.Databases {
+		if db.Name == "" {
+			return fmt.Errorf("sources.databases[%d]: name is required", i)
+		}
+		if !sourceNameRe.MatchString(db.Name) {
+			return fmt.Errorf("sources.databases: invalid name '%s' (use letters, digits, '.', '_' and '-')", db.Name)
+		}
+		if seen[db.Name] {
+			return fmt.Errorf("sources.databases: duplicate name '%s'", db.Name)
+		}
+		seen[db.Name] = true
+		if err := db.validate(); err != nil {
+			return fmt.Errorf("sources.databases.%s: %w", db.Name, err)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, src := range c.Sources.Commands {
+		if src.Name == "" {
+			return fmt.Errorf("sources.commands[%d]: name is required", i)
+		}
+		if !sourceNameRe.MatchString(src.Name) {
+			return fmt.Errorf("sources.commands: invalid name '%s' (use letters, digits, '.', '_' and '-')", src.Name)
+		}
+		if seen[src.Name] {
+			return fmt.Errorf("sources.commands: duplicate name '%s'", src.Name)
+		}
+		seen[src.Name] = true
+		if len(src.Command) == 0 {
+			return fmt.Errorf("sources.commands.%s: command is required", src.Name)
+		}
+		for _, tag := range src.Tags {
+			if tag == "" || strings.Contains(tag, ",") {
+				return fmt.Errorf("sources.commands.%s: invalid tag '%s'", src.Name, tag)
+			}
+		}
+	}
+
+	seen = make(map[string]bool)
+	for i, dir := range c.FSSnapshots.Directories {
+		if dir.Path == "" {
+			return fmt.Errorf("fs_snapshots.directories[%d]: path is required", i)
+		}
+		listed := false
+		for _, d := range c.Directories {
+			listed = listed || filepath.Clean(d) == filepath.Clean(dir.Path)
+		}
+		if !listed {
+			return fmt.Errorf("fs_snapshots: %s is not in directories", dir.Path)
+		}
+		if seen[filepath.Clean(dir.Path)] {
+			return fmt.Errorf("fs_snapshots: duplicate path '%s'", dir.Path)
+		}
+		seen[filepath.Clean(dir.Path)] = true
+		if err := dir.validate(); err != nil {
+			return fmt.Errorf("fs_snapshots.%s: %w", dir.Path, err)
+		}
+	}
+
+	if c.CopyParallelism < 0 {
+		return fmt.Errorf("copy_parallelism: must not be negative")
+	}
+
+	if err := c.validateBackendGroups(); err != nil {
+		return err
+	}
+
+	switch c.LockScope {
+	case "", "global", "config", "repository":
+	default:
+		return fmt.Errorf("lock_scope: invalid scope '%s' (use global, config or repository)", c.LockScope)
+	}
+
+	switch c.Sources.Docker.Mode {
+	case "", "none", "pause", "stop":
+	default:
+		return fmt.Errorf("sources.docker.mode: invalid mode '%s' (use none, pause or stop)", c.Sources.Docker.Mode)
+	}
+	for name, value := range map[string]string{
+		"stop_timeout": c.Sources.Docker.StopTimeout,
+		"timeout":      c.Sources.Docker.Timeout,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := ParseDuration(value); err != nil {
+			return fmt.Errorf("sources.docker.%s: %w", name, err)
+		}
+	}
+
+	if err := c.Retention.validate("retention."); err != nil {
+		return err
+	}
+
+	for name, value := range map[string]string{
+		"snapshot_age_warn":    c.Status.SnapshotAgeWarn,
+		"snapshot_age_crit":    c.Status.SnapshotAgeCrit,
+		"last_run_age_warn":    c.Status.LastRunAgeWarn,
+		"last_run_age_crit":    c.Status.LastRunAgeCrit,
+		"deep_check_age_warn":  c.Status.DeepCheckAgeWarn,
+		"deep_check_age_crit":  c.Status.DeepCheckAgeCrit,
+		"lock_age_warn":        c.Status.LockAgeWarn,
+		"replication_lag_warn": c.Status.ReplicationLagWarn,
+		"replication_lag_crit": c.Status.ReplicationLagCrit,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := ParseDuration(value); err != nil {
+			return fmt.Errorf("status.%s: %w", name, err)
+		}
+	}
+
+	if c.Hooks.Timeout != "" {
+		if _, err := ParseDuration(c.Hooks.Timeout); err != nil {
+			return fmt.Errorf("hooks.timeout: %w", err)
+		}
+	}
+	for name, hook := range map[string]Hook{
+		"pre_backup":  c.Hooks.PreBackup,
+		"post_backup": c.Hooks.PostBackup,
+		"on_error":    c.Hooks.OnError,
+		"on_success":  c.Hooks.OnSuccess,
+	} {
+		if err := hook.validate(); err != nil {
+			return fmt.Errorf("hooks.%s: %w", name, err)
+		}
+	}
+
+	for step, hooks := range c.Hooks.Steps {
+		if !containsString(HookSteps, step) {
+			return fmt.Errorf("hooks.steps.%s: unknown step (valid: %s)", step, strings.Join(HookSteps, ", "))
+		}
+		if err := hooks.validate(); err != nil {
+			return fmt.Errorf("hooks.steps.%s: %w", step, err)
+		}
+		for backend, override := range hooks.Backends {
+			if err := override.validate(); err != nil {
+				return fmt.Errorf("hooks.steps.%s.backends.%s: %w", step, backend, err)
+			}
+		}
+	}
+
+	if c.Metrics.CacheTTL != "" {
+		if _, err := ParseDuration(c.Metrics.CacheTTL); err != nil {
+			return fmt.Errorf("metrics.cache_ttl: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks the hooks and failure policy of a step
+func (h StepHookConfig) validate() error {
+	switch h.OnPreFailure {
+	case "", "abort", "skip":
+	default:
+		return fmt.Errorf("invalid on_pre_failure '%s' (use abort or skip)", h.OnPreFailure)
+	}
+	if err := h.Pre.validate(); err != nil {
+		return fmt.Errorf("pre: %w", err)
+	}
+	if err := h.Post.validate(); err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
 	return nil
 }
 
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // GetPassword returns the password, checking env var first
 func (c *Config) GetPassword() string {
 	if env := os.Getenv("RESTIC_PASSWORD"); env != "" {
@@ -323,6 +1034,28 @@ retention:
 	return nil
 }
 
+// ParseDuration parses a duration that may also use a day suffix ("7d", "1d12h")
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var days time.Duration
+	if idx := strings.Index(s, "d"); idx > 0 {
+		n, err := strconv.Atoi(s[:idx])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[idx+1:]
+		if s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s' (use e.g. 30m, 26h or 7d)", s)
+	}
+	return days + d, nil
+}
+
 // ExpandPath expands ~ to home directory
 func ExpandPath(path string) string {
 	if strings.HasPrefix(path, "~") {
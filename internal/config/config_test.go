@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -219,3 +222,342 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("DefaultConfig().DeepCheckIntervalDays = %d, want 30", cfg.DeepCheckIntervalDays)
 	}
 }
+
+func TestConfigSecrets(t *testing.T) {
+	cfg := &Config{
+		Password:           "primary-pass",
+		AWSSecretAccessKey: "primary-aws-secret",
+		Backends: map[string]Backend{
+			"offsite": {Password: "offsite-pass", AWSSecretAccessKey: "offsite-aws-secret"},
+		},
+		Notifications: NotificationConfig{
+			Providers: []ProviderConfig{
+				{Type: "slack", URL: "https://hooks.slack.com/services/T/B/X"},
+				{Type: "ntfy", URL: "https://ntfy.sh/topic", Token: "ntfy-token", Options: map[string]string{"priority": "high", "password": "ntfy-pass"}},
+			},
+		},
+	}
+
+	secrets := map[string]bool{}
+	for _, s := range cfg.Secrets() {
+		secrets[s] = true
+	}
+
+	for _, want := range []string{"primary-pass", "primary-aws-secret", "offsite-pass", "offsite-aws-secret",
+		"https://hooks.slack.com/services/T/B/X", "ntfy-token", "ntfy-pass"} {
+		if !secrets[want] {
+			t.Errorf("Secrets() missing %q", want)
+		}
+	}
+	for _, notWant := range []string{"https://ntfy.sh/topic", "high"} {
+		if secrets[notWant] {
+			t.Errorf("Secrets() should not include %q", notWant)
+		}
+	}
+}
+
+func TestConfigSecretsFromEnv(t *testing.T) {
+	t.Setenv("RESTIC_PASSWORD", "env-restic-pass")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-aws-secret")
+	cfg := &Config{Password: "file-pass"}
+
+	secrets := cfg.Secrets()
+	for _, want := range []string{"env-restic-pass", "env-aws-secret", "file-pass"} {
+		if !containsString(secrets, want) {
+			t.Errorf("Secrets() missing %q", want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"26h", 26 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1d12h", 36 * time.Hour, false},
+		{"xd", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStepHooks(t *testing.T) {
+	hooks := HookConfig{
+		Steps: map[string]StepHookConfig{
+			"prune": {
+				Pre:  Hook{Path: "/etc/resticm/pre-prune.sh"},
+				Post: Hook{Path: "/etc/resticm/post-prune.sh"},
+				Backends: map[string]StepHookConfig{
+					"offsite": {Pre: Hook{Path: "/etc/resticm/pre-prune-offsite.sh"}, OnPreFailure: "skip"},
+				},
+			},
+		},
+	}
+
+	primary := hooks.StepHooks("prune", "primary")
+	if primary.Pre.Path != "/etc/resticm/pre-prune.sh" || primary.OnPreFailure != "" {
+		t.Errorf("StepHooks(prune, primary) = %+v", primary)
+	}
+
+	offsite := hooks.StepHooks("prune", "offsite")
+	if offsite.Pre.Path != "/etc/resticm/pre-prune-offsite.sh" || offsite.Post.Path != "/etc/resticm/post-prune.sh" || offsite.OnPreFailure != "skip" {
+		t.Errorf("StepHooks(prune, offsite) = %+v", offsite)
+	}
+
+	if none := hooks.StepHooks("check", "primary"); none.Pre.IsSet() || none.Post.IsSet() {
+		t.Errorf("StepHooks(check, primary) = %+v, want empty", none)
+	}
+}
+
+func TestValidateStepHooks(t *testing.T) {
+	base := func() *Config {
+		return &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/data"}}
+	}
+
+	cfg := base()
+	cfg.Hooks.Steps = map[string]StepHookConfig{"forget": {OnPreFailure: "skip"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg = base()
+	cfg.Hooks.Steps = map[string]StepHookConfig{"restore": {Pre: Hook{Path: "/bin/true"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown step")
+	}
+
+	cfg = base()
+	cfg.Hooks.Steps = map[string]StepHookConfig{
+		"copy": {Backends: map[string]StepHookConfig{"offsite": {OnPreFailure: "retry"}}},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid on_pre_failure")
+	}
+}
+
+func TestValidateDatabaseSources(t *testing.T) {
+	cfg := &Config{Repository: "/tmp/repo", Password: "secret"}
+	cfg.Sources.Databases = []DatabaseSource{
+		{Name: "app", Type: "postgres", Database: "app", Password: "db-secret"},
+		{Name: "blog", Type: "sqlite", Path: "/var/lib/blog/blog.db"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v (databases alone are a valid backup source)", err)
+	}
+	if !containsString(cfg.Secrets(), "db-secret") {
+		t.Error("database password missing from Secrets()")
+	}
+
+	tests := map[string]DatabaseSource{
+		"missing type":     {Name: "x", Database: "x"},
+		"unknown type":     {Name: "x", Type: "oracle", Database: "x"},
+		"missing database": {Name: "x", Type: "mysql"},
+		"missing path":     {Name: "x", Type: "sqlite"},
+		"invalid name":     {Name: "a,b", Type: "mysql", Database: "x"},
+		"missing name":     {Type: "mysql", Database: "x"},
+	}
+	for name, db := range tests {
+		cfg.Sources.Databases = []DatabaseSource{db}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	cfg.Sources.Databases = []DatabaseSource{
+		{Name: "app", Type: "mysql", Database: "a"},
+		{Name: "app", Type: "mysql", Database: "b"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for duplicate names")
+	}
+}
+
+func TestValidateCommandSources(t *testing.T) {
+	cfg := &Config{Repository: "/tmp/repo", Password: "secret"}
+	cfg.Sources.Commands = []CommandSource{{Name: "ldap", Command: []string{"slapcat"}, Tags: []string{"ldap"}}}
+	cfg.Retention.GroupBy = "host,tags"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	tests := map[string]CommandSource{
+		"missing command": {Name: "ldap"},
+		"invalid name":    {Name: "ld ap", Command: []string{"slapcat"}},
+		"invalid tag":     {Name: "ldap", Command: []string{"slapcat"}, Tags: []string{"a,b"}},
+	}
+	for name, src := range tests {
+		cfg.Sources.Commands = []CommandSource{src}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	cfg.Sources.Commands = []CommandSource{{Name: "ldap", Command: []string{"slapcat"}}}
+	cfg.Retention.GroupBy = "host,snapshot"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid group_by")
+	}
+}
+
+func TestValidateDockerSource(t *testing.T) {
+	cfg := &Config{Repository: "/tmp/repo", Password: "secret"}
+	cfg.Sources.Docker = DockerSource{Enabled: true, Mode: "stop", StopTimeout: "1m"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.Sources.Docker.Mode = "freeze"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+	cfg.Sources.Docker.Mode = "pause"
+	cfg.Sources.Docker.StopTimeout = "soon"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for invalid stop_timeout")
+	}
+}
+
+func TestValidateFSSnapshots(t *testing.T) {
+	cfg := &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/etc", "/srv/"}}
+	cfg.FSSnapshots.Directories = []FSSnapshotDirectory{{Path: "/srv", Type: "lvm", Volume: "vg0/srv"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	tests := map[string]FSSnapshotDirectory{
+		"not a directory":    {Path: "/var", Type: "btrfs"},
+		"invalid type":       {Path: "/srv", Type: "xfs"},
+		"lvm without vg":     {Path: "/srv", Type: "lvm", Volume: "srv"},
+		"zfs without volume": {Path: "/srv", Type: "zfs"},
+	}
+	for name, dir := range tests {
+		cfg.FSSnapshots.Directories = []FSSnapshotDirectory{dir}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestHookYAML(t *testing.T) {
+	data := `
+timeout: 10m
+pre_backup: /etc/resticm/hooks/pre-backup.sh
+post_backup:
+  command: "systemctl start app"
+  timeout: 2m
+on_error:
+  argv: ["/usr/local/bin/alert", "--severity", "high"]
+  workdir: /tmp
+  user: nobody
+`
+	var hooks HookConfig
+	if err := yaml.Unmarshal([]byte(data), &hooks); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if hooks.PreBackup.Path != "/etc/resticm/hooks/pre-backup.sh" {
+		t.Errorf("PreBackup = %+v", hooks.PreBackup)
+	}
+	if hooks.PostBackup.Command != "systemctl start app" || hooks.PostBackup.Timeout != "2m" {
+		t.Errorf("PostBackup = %+v", hooks.PostBackup)
+	}
+	if len(hooks.OnError.Argv) != 3 || hooks.OnError.Workdir != "/tmp" || hooks.OnError.User != "nobody" {
+		t.Errorf("OnError = %+v", hooks.OnError)
+	}
+	if hooks.OnSuccess.IsSet() {
+		t.Errorf("OnSuccess should not be set: %+v", hooks.OnSuccess)
+	}
+	if hooks.Timeout != "10m" {
+		t.Errorf("Timeout = %q", hooks.Timeout)
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    Hook
+		wantErr bool
+	}{
+		{"path", Hook{Path: "/bin/true"}, false},
+		{"command with timeout", Hook{Command: "true", Timeout: "30s"}, false},
+		{"path and command", Hook{Path: "/bin/true", Command: "true"}, true},
+		{"options without command", Hook{Timeout: "30s"}, true},
+		{"invalid timeout", Hook{Command: "true", Timeout: "soon"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/data"}}
+			cfg.Hooks.PreBackup = tt.hook
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend Backend
+		wantErr bool
+	}{
+		{"profile and region", Backend{Env: map[string]string{"AWS_PROFILE": "offsite", "AWS_DEFAULT_REGION": "eu-west-1"}}, false},
+		{"b2 keys", Backend{Env: map[string]string{"B2_ACCOUNT_ID": "id", "B2_ACCOUNT_KEY": "key"}}, false},
+		{"invalid name", Backend{Env: map[string]string{"AWS PROFILE": "offsite"}}, true},
+		{"repository", Backend{Env: map[string]string{"RESTIC_REPOSITORY": "/tmp/other"}}, true},
+		{"password file", Backend{Env: map[string]string{"RESTIC_PASSWORD_FILE": "/etc/pass"}}, true},
+		{"key in both", Backend{AWSAccessKeyID: "AKIA1", Env: map[string]string{"AWS_ACCESS_KEY_ID": "AKIA2"}}, true},
+		{"secret in both", Backend{AWSSecretAccessKey: "s1", Env: map[string]string{"AWS_SECRET_ACCESS_KEY": "s2"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.backend.Repository = "/tmp/offsite"
+			tt.backend.Password = "secret"
+			cfg := &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/data"},
+				Backends: map[string]Backend{"offsite": tt.backend}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvSecrets(t *testing.T) {
+	cfg := &Config{
+		Env: map[string]string{"AWS_PROFILE": "main", "AZURE_ACCOUNT_KEY": "azure-key"},
+		Backends: map[string]Backend{
+			"b2": {Env: map[string]string{"B2_ACCOUNT_ID": "b2-id", "B2_ACCOUNT_KEY": "b2-key", "AWS_SESSION_TOKEN": "session"}},
+		},
+	}
+
+	secrets := map[string]bool{}
+	for _, s := range cfg.Secrets() {
+		secrets[s] = true
+	}
+	for _, want := range []string{"azure-key", "b2-key", "session"} {
+		if !secrets[want] {
+			t.Errorf("Secrets() missing %q", want)
+		}
+	}
+	for _, notWant := range []string{"main", "b2-id"} {
+		if secrets[notWant] {
+			t.Errorf("Secrets() should not include %q", notWant)
+		}
+	}
+}
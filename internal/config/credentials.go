@@ -0,0 +1,229 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Credentials holds the typed credentials of the restic backend types. Only
+// the block matching the repository type may be set; its fields are passed
+// to restic as the documented environment variables or extended options.
+type Credentials struct {
+	B2    *B2Credentials    `yaml:"b2,omitempty"`
+	Azure *AzureCredentials `yaml:"azure,omitempty"`
+	GS    *GSCredentials    `yaml:"gs,omitempty"`
+	Swift *SwiftCredentials `yaml:"swift,omitempty"`
+	REST  *RESTCredentials  `yaml:"rest,omitempty"`
+	SFTP  *SFTPOptions      `yaml:"sftp,omitempty"`
+}
+
+// B2Credentials are Backblaze B2 credentials
+type B2Credentials struct {
+	AccountID  string `yaml:"account_id"`
+	AccountKey string `yaml:"account_key"`
+}
+
+// AzureCredentials are Azure Blob Storage credentials: an account key or a
+// SAS token
+type AzureCredentials struct {
+	AccountName    string `yaml:"account_name"`
+	AccountKey     string `yaml:"account_key"`
+	AccountSAS     string `yaml:"account_sas"`
+	EndpointSuffix string `yaml:"endpoint_suffix"`
+}
+
+// GSCredentials are Google Cloud Storage credentials: a service account file
+// or an access token
+type GSCredentials struct {
+	ProjectID       string `yaml:"project_id"`
+	CredentialsFile string `yaml:"credentials_file"`
+	AccessToken     string `yaml:"access_token"`
+}
+
+// SwiftCredentials are OpenStack Swift credentials: a Keystone auth URL with
+// a user or application credential, or a storage URL with a token
+type SwiftCredentials struct {
+	AuthURL                     string `yaml:"auth_url"`
+	Username                    string `yaml:"username"`
+	Password                    string `yaml:"password"`
+	UserDomainName              string `yaml:"user_domain_name"`
+	ProjectName                 string `yaml:"project_name"`
+	ProjectDomainName           string `yaml:"project_domain_name"`
+	RegionName                  string `yaml:"region_name"`
+	ApplicationCredentialID     string `yaml:"application_credential_id"`
+	ApplicationCredentialSecret string `yaml:"application_credential_secret"`
+	StorageURL                  string `yaml:"storage_url"`
+	AuthToken                   string `yaml:"auth_token"`
+}
+
+// RESTCredentials are the HTTP basic auth of a REST server
+type RESTCredentials struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// SFTPOptions configure the ssh connection of an SFTP repository: either the
+// full command to run, or extra arguments for the default ssh command
+type SFTPOptions struct {
+	Command string `yaml:"command"`
+	Args    string `yaml:"args"`
+}
+
+// credentialField is one field of a credential block
+type credentialField struct {
+	Name   string // block.field, as in the configuration
+	Env    string // restic environment variable
+	Value  string
+	Secret bool
+}
+
+// fields returns the fields of the credential blocks that are set
+func (c Credentials) fields() []credentialField {
+	var fields []credentialField
+	add := func(name, env, value string, secret bool) {
+		if value != "" {
+			fields = append(fields, credentialField{Name: name, Env: env, Value: value, Secret: secret})
+		}
+	}
+	if b := c.B2; b != nil {
+		add("b2.account_id", "B2_ACCOUNT_ID", b.AccountID, false)
+		add("b2.account_key", "B2_ACCOUNT_KEY", b.AccountKey, true)
+	}
+	if a := c.Azure; a != nil {
+		add("azure.account_name", "AZURE_ACCOUNT_NAME", a.AccountName, false)
+		add("azure.account_key", "AZURE_ACCOUNT_KEY", a.AccountKey, true)
+		add("azure.account_sas", "AZURE_ACCOUNT_SAS", a.AccountSAS, true)
+		add("azure.endpoint_suffix", "AZURE_ENDPOINT_SUFFIX", a.EndpointSuffix, false)
+	}
+	if g := c.GS; g != nil {
+		add("gs.project_id", "GOOGLE_PROJECT_ID", g.ProjectID, false)
+		add("gs.credentials_file", "GOOGLE_APPLICATION_CREDENTIALS", ExpandPath(g.CredentialsFile), false)
+		add("gs.access_token", "GOOGLE_ACCESS_TOKEN", g.AccessToken, true)
+	}
+	if s := c.Swift; s != nil {
+		add("swift.auth_url", "OS_AUTH_URL", s.AuthURL, false)
+		add("swift.username", "OS_USERNAME", s.Username, false)
+		add("swift.password", "OS_PASSWORD", s.Password, true)
+		add("swift.user_domain_name", "OS_USER_DOMAIN_NAME", s.UserDomainName, false)
+		add("swift.project_name", "OS_PROJECT_NAME", s.ProjectName, false)
+		add("swift.project_domain_name", "OS_PROJECT_DOMAIN_NAME", s.ProjectDomainName, false)
+		add("swift.region_name", "OS_REGION_NAME", s.RegionName, false)
+		add("swift.application_credential_id", "OS_APPLICATION_CREDENTIAL_ID", s.ApplicationCredentialID, false)
+		add("swift.application_credential_secret", "OS_APPLICATION_CREDENTIAL_SECRET", s.ApplicationCredentialSecret, true)
+		add("swift.storage_url", "OS_STORAGE_URL", s.StorageURL, false)
+		add("swift.auth_token", "OS_AUTH_TOKEN", s.AuthToken, true)
+	}
+	if r := c.REST; r != nil {
+		add("rest.username", "RESTIC_REST_USERNAME", r.Username, false)
+		add("rest.password", "RESTIC_REST_PASSWORD", r.Password, true)
+	}
+	if s := c.SFTP; s != nil {
+		add("sftp.command", "", s.Command, false)
+		add("sftp.args", "", s.Args, false)
+	}
+	return fields
+}
+
+// ResticEnv returns the restic environment variables of the credentials
+func (c Credentials) ResticEnv() map[string]string {
+	env := make(map[string]string)
+	for _, f := range c.fields() {
+		if f.Env != "" {
+			env[f.Env] = f.Value
+		}
+	}
+	return env
+}
+
+// ResticOptions returns the restic extended options (-o) of the credentials
+func (c Credentials) ResticOptions() []string {
+	var options []string
+	for _, f := range c.fields() {
+		if f.Env == "" {
+			options = append(options, f.Name+"="+f.Value)
+		}
+	}
+	return options
+}
+
+// Describe returns a "block.field: value" line for each field set, with the
+// secrets masked
+func (c Credentials) Describe() []string {
+	var lines []string
+	for _, f := range c.fields() {
+		value := f.Value
+		if f.Secret {
+			value = "********"
+		}
+		lines = append(lines, f.Name+": "+value)
+	}
+	return lines
+}
+
+// secrets returns the secret values of the credentials
+func (c Credentials) secrets() []string {
+	var secrets []string
+	for _, f := range c.fields() {
+		if f.Secret {
+			secrets = append(secrets, f.Value)
+		}
+	}
+	return secrets
+}
+
+// validate checks the credential blocks against the repository type
+func (c Credentials) validate(section, repository string) error {
+	blocks := []struct {
+		name string
+		set  bool
+	}{
+		{"b2", c.B2 != nil},
+		{"azure", c.Azure != nil},
+		{"gs", c.GS != nil},
+		{"swift", c.Swift != nil},
+		{"rest", c.REST != nil},
+		{"sftp", c.SFTP != nil},
+	}
+	for _, b := range blocks {
+		if b.set && !strings.HasPrefix(repository, b.name+":") {
+			return fmt.Errorf("%s%s credentials need a %s: repository", section, b.name, b.name)
+		}
+	}
+
+	if b := c.B2; b != nil && (b.AccountID == "" || b.AccountKey == "") {
+		return fmt.Errorf("%sb2 needs account_id and account_key", section)
+	}
+	if a := c.Azure; a != nil {
+		if a.AccountName == "" {
+			return fmt.Errorf("%sazure needs account_name", section)
+		}
+		if a.AccountKey != "" && a.AccountSAS != "" {
+			return fmt.Errorf("%sazure: account_key and account_sas are mutually exclusive", section)
+		}
+	}
+	if g := c.GS; g != nil && g.CredentialsFile != "" && g.AccessToken != "" {
+		return fmt.Errorf("%sgs: credentials_file and access_token are mutually exclusive", section)
+	}
+	if s := c.Swift; s != nil {
+		switch {
+		case s.StorageURL != "" || s.AuthToken != "":
+			if s.StorageURL == "" || s.AuthToken == "" {
+				return fmt.Errorf("%sswift: storage_url and auth_token go together", section)
+			}
+		case s.AuthURL == "":
+			return fmt.Errorf("%sswift needs auth_url, or storage_url and auth_token", section)
+		}
+	}
+	if r := c.REST; r != nil && (r.Username == "") != (r.Password == "") {
+		return fmt.Errorf("%srest needs both username and password", section)
+	}
+	if s := c.SFTP; s != nil {
+		if s.Command != "" && s.Args != "" {
+			return fmt.Errorf("%ssftp: command and args are mutually exclusive", section)
+		}
+		if s.Command == "" && s.Args == "" {
+			return fmt.Errorf("%ssftp needs command or args", section)
+		}
+	}
+	return nil
+}
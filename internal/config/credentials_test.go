@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigWithCredentials(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	configContent := `
+repository: "b2:primary-bucket:restic"
+password: "testpassword"
+b2:
+  account_id: "0012345"
+  account_key: "K001primary"
+directories:
+  - /home
+backends:
+  offsite:
+    repository: "azure:restic:/"
+    password: "azurepassword"
+    azure:
+      account_name: "backups"
+      account_sas: "sv=2024&sig=abc"
+    env:
+      AZURE_FORCE_CLI_CREDENTIAL: "false"
+  nas:
+    repository: "sftp:backup@nas:/srv/restic"
+    password: "naspassword"
+    sftp:
+      args: "-i /root/.ssh/nas"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	want := map[string]string{"B2_ACCOUNT_ID": "0012345", "B2_ACCOUNT_KEY": "K001primary"}
+	if env := cfg.BackendEnv("primary"); !reflect.DeepEqual(env, want) {
+		t.Errorf("BackendEnv(primary) = %v, want %v", env, want)
+	}
+	want = map[string]string{
+		"AZURE_ACCOUNT_NAME":         "backups",
+		"AZURE_ACCOUNT_SAS":          "sv=2024&sig=abc",
+		"AZURE_FORCE_CLI_CREDENTIAL": "false",
+	}
+	if env := cfg.BackendEnv("offsite"); !reflect.DeepEqual(env, want) {
+		t.Errorf("BackendEnv(offsite) = %v, want %v", env, want)
+	}
+	if opts := cfg.BackendOptions("nas"); !reflect.DeepEqual(opts, []string{"sftp.args=-i /root/.ssh/nas"}) {
+		t.Errorf("BackendOptions(nas) = %v", opts)
+	}
+
+	secrets := cfg.Secrets()
+	for _, s := range []string{"K001primary", "sv=2024&sig=abc"} {
+		if !slices.Contains(secrets, s) {
+			t.Errorf("Secrets() missing %q", s)
+		}
+	}
+	if slices.Contains(secrets, "0012345") {
+		t.Error("Secrets() should not include the B2 account ID")
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend Backend
+		wantErr bool
+	}{
+		{"b2", Backend{Repository: "b2:bucket:restic", Credentials: Credentials{B2: &B2Credentials{AccountID: "id", AccountKey: "key"}}}, false},
+		{"b2 without key", Backend{Repository: "b2:bucket:restic", Credentials: Credentials{B2: &B2Credentials{AccountID: "id"}}}, true},
+		{"b2 on s3", Backend{Repository: "s3:s3.amazonaws.com/bucket", Credentials: Credentials{B2: &B2Credentials{AccountID: "id", AccountKey: "key"}}}, true},
+		{"azure key and sas", Backend{Repository: "azure:c:/", Credentials: Credentials{Azure: &AzureCredentials{AccountName: "a", AccountKey: "k", AccountSAS: "s"}}}, true},
+		{"azure without name", Backend{Repository: "azure:c:/", Credentials: Credentials{Azure: &AzureCredentials{AccountKey: "k"}}}, true},
+		{"gs file and token", Backend{Repository: "gs:bucket:/", Credentials: Credentials{GS: &GSCredentials{CredentialsFile: "/f.json", AccessToken: "t"}}}, true},
+		{"swift auth url", Backend{Repository: "swift:c:/", Credentials: Credentials{Swift: &SwiftCredentials{AuthURL: "https://keystone/v3", Username: "u", Password: "p"}}}, false},
+		{"swift token", Backend{Repository: "swift:c:/", Credentials: Credentials{Swift: &SwiftCredentials{StorageURL: "https://swift/v1", AuthToken: "t"}}}, false},
+		{"swift token without url", Backend{Repository: "swift:c:/", Credentials: Credentials{Swift: &SwiftCredentials{AuthToken: "t"}}}, true},
+		{"swift empty", Backend{Repository: "swift:c:/", Credentials: Credentials{Swift: &SwiftCredentials{}}}, true},
+		{"rest without password", Backend{Repository: "rest:https://host/", Credentials: Credentials{REST: &RESTCredentials{Username: "u"}}}, true},
+		{"sftp command and args", Backend{Repository: "sftp:host:/srv", Credentials: Credentials{SFTP: &SFTPOptions{Command: "ssh host -s sftp", Args: "-p 2222"}}}, true},
+		{"env conflict", Backend{Repository: "b2:bucket:restic", Env: map[string]string{"B2_ACCOUNT_KEY": "other"},
+			Credentials: Credentials{B2: &B2Credentials{AccountID: "id", AccountKey: "key"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.backend.Password = "secret"
+			cfg := &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/data"},
+				Backends: map[string]Backend{"offsite": tt.backend}}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), "backends.offsite.") {
+				t.Errorf("Validate() error = %v, want it to name the backend", err)
+			}
+		})
+	}
+}
+
+func TestCredentialsDescribe(t *testing.T) {
+	creds := Credentials{REST: &RESTCredentials{Username: "backup", Password: "hunter2"}}
+	want := []string{"rest.username: backup", "rest.password: ********"}
+	if got := creds.Describe(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Describe() = %v, want %v", got, want)
+	}
+}
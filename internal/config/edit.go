@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetPassword replaces the password of a backend ("primary" for the primary
+// repository) in the configuration file at path. Only the value is rewritten:
+// the comments, order and formatting of the file are kept.
+func SetPassword(path, name, password string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	value, field, err := passwordNode(data, name)
+	if err != nil {
+		return err
+	}
+	updated, err := replaceScalar(data, value, quoteYAML(password))
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+
+	// Make sure the edit produced the new password and nothing else broke
+	if check, _, err := passwordNode(updated, name); err != nil || check.Value != password {
+		return fmt.Errorf("%s: failed to update the value in place", field)
+	}
+	return writeFileAtomic(path, updated)
+}
+
+// passwordNode returns the password value node of a backend in a
+// configuration document, with the name of its field
+func passwordNode(data []byte, name string) (*yaml.Node, string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, "", fmt.Errorf("config file is empty")
+	}
+
+	section := doc.Content[0]
+	field := "password"
+	if name != "" && name != SelectPrimary {
+		section = mappingValue(mappingValue(section, "backends"), name)
+		if section == nil {
+			return nil, "", fmt.Errorf("backend '%s' not found in config file", name)
+		}
+		field = "backends." + name + ".password"
+	}
+	value := mappingValue(section, "password")
+	if value == nil {
+		return nil, "", fmt.Errorf("%s is not set in the config file", field)
+	}
+	return value, field, nil
+}
+
+// mappingValue returns the value of a key of a mapping node, nil when the
+// node is not a mapping or has no such key
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// replaceScalar replaces the text of a single-line scalar node in data
+func replaceScalar(data []byte, node *yaml.Node, text string) ([]byte, error) {
+	if node.Kind != yaml.ScalarNode || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return nil, fmt.Errorf("only a single-line value can be updated")
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if node.Line < 1 || node.Line > len(lines) {
+		return nil, fmt.Errorf("value position out of range")
+	}
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	if start < 0 || start > len(line) {
+		return nil, fmt.Errorf("value position out of range")
+	}
+
+	end, err := scalarEnd(line, start, node.Style)
+	if err != nil {
+		return nil, err
+	}
+	lines[node.Line-1] = line[:start] + text + line[end:]
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// scalarEnd returns the end offset of the scalar starting at start in line
+func scalarEnd(line string, start int, style yaml.Style) (int, error) {
+	rest := line[start:]
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return start + i + 1, nil
+			}
+		}
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] != '\'' {
+				continue
+			}
+			if i+1 < len(rest) && rest[i+1] == '\'' {
+				i++
+				continue
+			}
+			return start + i + 1, nil
+		}
+	default:
+		end := len(strings.TrimRight(rest, "\r\n"))
+		if i := strings.Index(rest, " #"); i >= 0 && i < end {
+			end = i
+		}
+		return start + len(strings.TrimRight(rest[:end], " \t")), nil
+	}
+	return 0, fmt.Errorf("only a single-line value can be updated")
+}
+
+// quoteYAML returns s as a double-quoted YAML scalar
+func quoteYAML(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeFileAtomic replaces the file at path with data, keeping its mode. The
+// data is written to a temporary file in the same directory first, so an
+// interrupted write never leaves a truncated configuration.
+func writeFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetPassword(t *testing.T) {
+	configContent := `# resticm configuration
+repository: "/tmp/repo"
+password: "old-primary" # rotated with resticm key rotate
+directories:
+  - /home
+
+backends:
+  # Offsite copy
+  offsite:
+    repository: "b2:bucket:restic"
+    password: 'it''s-old'
+  nas:
+    repository: "/mnt/nas"
+    password: plain-old   # trailing comment
+  bare:
+    repository: "/mnt/bare"
+`
+	tests := []struct {
+		name     string
+		backend  string
+		password string
+		wantLine string
+	}{
+		{"primary", "primary", "n3w-pr1mary", `password: "n3w-pr1mary" # rotated with resticm key rotate`},
+		{"single quoted", "offsite", "new-offsite", `    password: "new-offsite"`},
+		{"plain", "nas", `we"ird\pass`, `    password: "we\"ird\\pass"   # trailing comment`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(configContent), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := SetPassword(path, tt.backend, tt.password); err != nil {
+				t.Fatalf("SetPassword() error = %v", err)
+			}
+
+			data, _ := os.ReadFile(path)
+			if !strings.Contains(string(data), tt.wantLine+"\n") {
+				t.Errorf("config missing %q:\n%s", tt.wantLine, data)
+			}
+			// Only the password line changed
+			if got, want := strings.Count(string(data), "\n"), strings.Count(configContent, "\n"); got != want {
+				t.Errorf("config has %d lines, want %d", got, want)
+			}
+			for _, comment := range []string{"# resticm configuration", "# Offsite copy", "# trailing comment"} {
+				if !strings.Contains(string(data), comment) {
+					t.Errorf("comment %q lost", comment)
+				}
+			}
+
+			cfg := DefaultConfig()
+			if err := yaml.Unmarshal(data, cfg); err != nil {
+				t.Fatalf("updated config does not parse: %v", err)
+			}
+			repo, _ := cfg.Resolve(tt.backend)
+			if repo.Password != tt.password {
+				t.Errorf("password = %q, want %q", repo.Password, tt.password)
+			}
+
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+				t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(configContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bare", "nope"} {
+		if err := SetPassword(path, name, "x"); err == nil {
+			t.Errorf("SetPassword(%s) should fail", name)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != configContent {
+		t.Error("a failed SetPassword must leave the file unchanged")
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -11,6 +12,11 @@ type EnvExporter struct {
 	Password           string
 	AWSAccessKeyID     string
 	AWSSecretAccessKey string
+	Env                map[string]string
+
+	// Options are restic extended options, which have no environment
+	// variable: they are printed as a comment to pass with -o
+	Options []string
 }
 
 // ExportFormat represents the shell format for export
@@ -28,24 +34,17 @@ func NewEnvExporter(cfg *Config, backendName string) (*EnvExporter, error) {
 		return nil, fmt.Errorf("config is nil")
 	}
 
-	exporter := &EnvExporter{}
-
-	if backendName == "" || backendName == "primary" {
-		// Use primary backend
-		exporter.Repository = cfg.Repository
-		exporter.Password = cfg.GetPassword()
-		exporter.AWSAccessKeyID = cfg.GetAWSAccessKeyID()
-		exporter.AWSSecretAccessKey = cfg.GetAWSSecretAccessKey()
-	} else {
-		// Use named backend
-		backend, exists := cfg.Backends[backendName]
-		if !exists {
-			return nil, fmt.Errorf("backend '%s' not found in configuration", backendName)
-		}
-		exporter.Repository = backend.Repository
-		exporter.Password = backend.Password
-		exporter.AWSAccessKeyID = backend.AWSAccessKeyID
-		exporter.AWSSecretAccessKey = backend.AWSSecretAccessKey
+	repo, err := cfg.Resolve(backendName)
+	if err != nil {
+		return nil, fmt.Errorf("backend '%s' not found in configuration", backendName)
+	}
+	exporter := &EnvExporter{
+		Repository:         repo.URL,
+		Password:           repo.Password,
+		AWSAccessKeyID:     repo.AWSAccessKeyID,
+		AWSSecretAccessKey: repo.AWSSecretAccessKey,
+		Env:                repo.Env,
+		Options:            repo.Options,
 	}
 
 	// Validate required fields
@@ -96,7 +95,11 @@ func (e *EnvExporter) exportBash() string {
 	if e.AWSSecretAccessKey != "" {
 		b.WriteString(fmt.Sprintf("export AWS_SECRET_ACCESS_KEY='%s'\n", escapeShell(e.AWSSecretAccessKey)))
 	}
+	for _, k := range e.envKeys() {
+		b.WriteString(fmt.Sprintf("export %s='%s'\n", k, escapeShell(e.Env[k])))
+	}
 
+	b.WriteString(e.optionsComment())
 	b.WriteString("# Environment variables exported successfully\n")
 	b.WriteString("# You can now use restic commands directly\n")
 
@@ -116,7 +119,11 @@ func (e *EnvExporter) exportFish() string {
 	if e.AWSSecretAccessKey != "" {
 		b.WriteString(fmt.Sprintf("set -x AWS_SECRET_ACCESS_KEY '%s'\n", escapeShell(e.AWSSecretAccessKey)))
 	}
+	for _, k := range e.envKeys() {
+		b.WriteString(fmt.Sprintf("set -x %s '%s'\n", k, escapeShell(e.Env[k])))
+	}
 
+	b.WriteString(e.optionsComment())
 	b.WriteString("# Environment variables exported successfully\n")
 	b.WriteString("# You can now use restic commands directly\n")
 
@@ -136,13 +143,40 @@ func (e *EnvExporter) exportPowershell() string {
 	if e.AWSSecretAccessKey != "" {
 		b.WriteString(fmt.Sprintf("$env:AWS_SECRET_ACCESS_KEY = '%s'\n", escapePowershell(e.AWSSecretAccessKey)))
 	}
+	for _, k := range e.envKeys() {
+		b.WriteString(fmt.Sprintf("$env:%s = '%s'\n", k, escapePowershell(e.Env[k])))
+	}
 
+	b.WriteString(e.optionsComment())
 	b.WriteString("# Environment variables exported successfully\n")
 	b.WriteString("# You can now use restic commands directly\n")
 
 	return b.String()
 }
 
+// optionsComment returns a comment listing the extended options, if any
+func (e *EnvExporter) optionsComment() string {
+	if len(e.Options) == 0 {
+		return ""
+	}
+	var args []string
+	for _, opt := range e.Options {
+		name, value, _ := strings.Cut(opt, "=")
+		args = append(args, fmt.Sprintf("-o %s='%s'", name, escapeShell(value)))
+	}
+	return "# Pass to restic: " + strings.Join(args, " ") + "\n"
+}
+
+// envKeys returns the names of the extra variables in a stable order
+func (e *EnvExporter) envKeys() []string {
+	keys := make([]string, 0, len(e.Env))
+	for k := range e.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // escapeShell escapes single quotes for bash/fish/sh shells
 func escapeShell(s string) string {
 	return strings.ReplaceAll(s, "'", "'\\''")
@@ -373,3 +373,41 @@ func TestEscapePowershell(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvExporterExportBashWithEnv(t *testing.T) {
+	exporter := &EnvExporter{
+		Repository: "s3:s3.amazonaws.com/bucket",
+		Password:   "test-password",
+		Env:        map[string]string{"AWS_PROFILE": "offsite", "AWS_DEFAULT_REGION": "eu-west-1"},
+	}
+
+	output, err := exporter.Export(FormatBash)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	region := strings.Index(output, "export AWS_DEFAULT_REGION='eu-west-1'")
+	profile := strings.Index(output, "export AWS_PROFILE='offsite'")
+	if region < 0 || profile < 0 {
+		t.Fatalf("Output missing env exports:\n%s", output)
+	}
+	if region > profile {
+		t.Error("Env variables should be exported in sorted order")
+	}
+}
+
+func TestEnvExporterExportBashWithOptions(t *testing.T) {
+	exporter := &EnvExporter{
+		Repository: "sftp:nas:/srv/restic",
+		Password:   "test-password",
+		Options:    []string{"sftp.args=-i /root/.ssh/nas"},
+	}
+
+	output, err := exporter.Export(FormatBash)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(output, "# Pass to restic: -o sftp.args='-i /root/.ssh/nas'\n") {
+		t.Errorf("Output missing the extended options:\n%s", output)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Repository is a backend resolved for running restic: its location and
+// credentials, with the cache directory, limits and retention policy it
+// inherits from the global configuration. Commands build their restic
+// executors from it, so a backend setting only needs to be resolved here.
+type Repository struct {
+	Name string // Backend name, "primary" for the primary repository
+	URL  string
+
+	Password           string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+
+	// Environment (env section and typed credentials) and extended options
+	Env     map[string]string
+	Options []string
+
+	CacheDir      string
+	LimitUpload   int // KiB/s, 0 for no limit
+	LimitDownload int // KiB/s, 0 for no limit
+
+	Retention RetentionConfig
+}
+
+// Resolve returns the repository of a backend by name; "primary" or an empty
+// name returns the primary repository with its password and AWS keys
+// resolved from the environment when not configured
+func (c *Config) Resolve(name string) (*Repository, error) {
+	if name == "" || name == SelectPrimary {
+		return &Repository{
+			Name:               SelectPrimary,
+			URL:                c.Repository,
+			Password:           c.GetPassword(),
+			AWSAccessKeyID:     c.GetAWSAccessKeyID(),
+			AWSSecretAccessKey: c.GetAWSSecretAccessKey(),
+			Env:                c.BackendEnv(SelectPrimary),
+			Options:            c.BackendOptions(SelectPrimary),
+			CacheDir:           c.CacheDir,
+			LimitUpload:        c.LimitUpload,
+			LimitDownload:      c.LimitDownload,
+			Retention:          c.Retention,
+		}, nil
+	}
+
+	b, ok := c.Backends[name]
+	if !ok {
+		return nil, fmt.Errorf("backend '%s' not found", name)
+	}
+	repo := &Repository{
+		Name:               name,
+		URL:                b.Repository,
+		Password:           b.Password,
+		AWSAccessKeyID:     b.AWSAccessKeyID,
+		AWSSecretAccessKey: b.AWSSecretAccessKey,
+		Env:                c.BackendEnv(name),
+		Options:            slices.Clone(c.BackendOptions(name)),
+		CacheDir:           firstNonEmpty(b.CacheDir, c.CacheDir),
+		LimitUpload:        c.LimitUpload,
+		LimitDownload:      c.LimitDownload,
+		Retention:          c.Retention,
+	}
+	if b.LimitUpload > 0 {
+		repo.LimitUpload = b.LimitUpload
+	}
+	if b.LimitDownload > 0 {
+		repo.LimitDownload = b.LimitDownload
+	}
+	if b.Retention != nil {
+		repo.Retention = *b.Retention
+	}
+	return repo, nil
+}
+
+// ActiveRepository returns the repository of the active backend (see
+// 'resticm backend use'), the primary when none is selected
+func (c *Config) ActiveRepository() (*Repository, error) {
+	activeBackend, _ := GetActiveBackend()
+	return c.Resolve(activeBackend)
+}
+
+// IsPrimary reports whether the repository is the primary one
+func (r *Repository) IsPrimary() bool {
+	return r.Name == SelectPrimary
+}
+
+// validateLimits checks the bandwidth limits of a section
+func validateLimits(sectionPrefix string, upload, download int) error {
+	if upload < 0 {
+		return fmt.Errorf("%slimit_upload must not be negative", sectionPrefix)
+	}
+	if download < 0 {
+		return fmt.Errorf("%slimit_download must not be negative", sectionPrefix)
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
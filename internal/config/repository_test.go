@@ -0,0 +1,101 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	cfg := &Config{
+		Repository:     "/tmp/repo",
+		Password:       "secret",
+		AWSAccessKeyID: "AKIAPRIMARY",
+		CacheDir:       "/var/cache/restic",
+		LimitUpload:    1024,
+		Retention:      RetentionConfig{KeepDaily: 7},
+		Backends: map[string]Backend{
+			"offsite": {
+				Repository:    "b2:bucket:restic",
+				Password:      "b2secret",
+				Credentials:   Credentials{B2: &B2Credentials{AccountID: "id", AccountKey: "key"}},
+				LimitDownload: 512,
+				Retention:     &RetentionConfig{KeepMonthly: 12},
+			},
+			"nas": {Repository: "/mnt/nas", Password: "nassecret", CacheDir: "/srv/cache", LimitUpload: 2048},
+		},
+	}
+
+	primary, err := cfg.Resolve("primary")
+	if err != nil {
+		t.Fatalf("Resolve(primary) error = %v", err)
+	}
+	if !primary.IsPrimary() || primary.URL != "/tmp/repo" || primary.Password != "secret" || primary.AWSAccessKeyID != "AKIAPRIMARY" {
+		t.Errorf("Resolve(primary) = %+v", primary)
+	}
+	if empty, _ := cfg.Resolve(""); empty == nil || !empty.IsPrimary() {
+		t.Errorf("Resolve(\"\") = %+v, want the primary", empty)
+	}
+
+	offsite, err := cfg.Resolve("offsite")
+	if err != nil {
+		t.Fatalf("Resolve(offsite) error = %v", err)
+	}
+	if offsite.IsPrimary() || offsite.URL != "b2:bucket:restic" || offsite.Password != "b2secret" {
+		t.Errorf("Resolve(offsite) = %+v", offsite)
+	}
+	if offsite.Env["B2_ACCOUNT_KEY"] != "key" {
+		t.Errorf("Resolve(offsite).Env = %v, want the typed credentials", offsite.Env)
+	}
+	if offsite.CacheDir != "/var/cache/restic" || offsite.LimitUpload != 1024 || offsite.LimitDownload != 512 {
+		t.Errorf("Resolve(offsite) cache/limits = %q %d %d", offsite.CacheDir, offsite.LimitUpload, offsite.LimitDownload)
+	}
+	if offsite.Retention != (RetentionConfig{KeepMonthly: 12}) {
+		t.Errorf("Resolve(offsite).Retention = %+v, want the backend policy", offsite.Retention)
+	}
+
+	nas, _ := cfg.Resolve("nas")
+	if nas.CacheDir != "/srv/cache" || nas.LimitUpload != 2048 || nas.Retention.KeepDaily != 7 {
+		t.Errorf("Resolve(nas) = %+v", nas)
+	}
+
+	if _, err := cfg.Resolve("nope"); err == nil {
+		t.Error("Resolve(nope) should fail")
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	cfg := &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/data"},
+		Backends: map[string]Backend{"nas": {Repository: "/mnt/nas", LimitDownload: -1}}}
+	err := cfg.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "backends.nas.limit_download") {
+		t.Errorf("Validate() error = %v, want a limit_download error", err)
+	}
+
+	cfg.Backends = nil
+	cfg.LimitUpload = -5
+	if err := cfg.Validate(); err == nil || !strings.HasPrefix(err.Error(), "limit_upload") {
+		t.Errorf("Validate() error = %v, want a limit_upload error", err)
+	}
+}
+
+func TestValidateBackendRetention(t *testing.T) {
+	cfg := &Config{Repository: "/tmp/repo", Password: "secret", Directories: []string{"/data"},
+		Backends: map[string]Backend{"nas": {Repository: "/mnt/nas",
+			Retention: &RetentionConfig{KeepDaily: 7, GroupBy: "host,tags"}}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	cfg.Backends["nas"].Retention.GroupBy = "host,snapshot"
+	err := cfg.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "backends.nas.retention.group_by") {
+		t.Errorf("Validate() error = %v, want a group_by error", err)
+	}
+
+	cfg.Backends["nas"].Retention.GroupBy = ""
+	cfg.Backends["nas"].Retention.KeepMonthly = -1
+	err = cfg.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), "backends.nas.retention.keep_monthly") {
+		t.Errorf("Validate() error = %v, want a keep_monthly error", err)
+	}
+}
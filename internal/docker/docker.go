@@ -0,0 +1,307 @@
+// Package docker talks to the Docker Engine API over its unix socket to find
+// the containers to back up and quiesce them around the backup
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSocket is the Docker API socket used when none is configured
+const DefaultSocket = "/var/run/docker.sock"
+
+// Container labels read by resticm
+const (
+	LabelEnable  = "resticm.enable"  // "true" to back up the container volumes
+	LabelMode    = "resticm.mode"    // none, pause or stop during the backup
+	LabelPre     = "resticm.pre"     // Shell command run in the container before
+	LabelPost    = "resticm.post"    // Shell command run in the container after
+	LabelVolumes = "resticm.volumes" // Comma separated volumes to back up (default: all)
+)
+
+// Quiesce modes
+const (
+	ModeNone  = "none"
+	ModePause = "pause"
+	ModeStop  = "stop"
+)
+
+// Tag prefixes of the volume snapshots
+const (
+	ContainerTagPrefix = "docker:"
+	VolumeTagPrefix    = "volume:"
+)
+
+// Client is a minimal Docker Engine API client
+type Client struct {
+	Socket string
+	http   *http.Client
+}
+
+// NewClient returns a client for the API socket. An empty socket uses
+// DOCKER_HOST when it is a unix:// address, else DefaultSocket.
+func NewClient(socket string) *Client {
+	if socket == "" {
+		socket = DefaultSocket
+		if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+			socket = strings.TrimPrefix(host, "unix://")
+		}
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Client{Socket: socket, http: &http.Client{Transport: transport}}
+}
+
+// Mount is a volume or bind mount of a container
+type Mount struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+}
+
+// Container is a container as listed by the API
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+	Mounts []Mount           `json:"Mounts"`
+}
+
+// Name returns the container name without the leading slash
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		if len(c.ID) > 12 {
+			return c.ID[:12]
+		}
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Mode returns the quiesce mode of the container, falling back to def
+func (c Container) Mode(def string) string {
+	if mode := strings.TrimSpace(c.Labels[LabelMode]); mode != "" {
+		return mode
+	}
+	if def == "" {
+		return ModeNone
+	}
+	return def
+}
+
+// Volumes returns the named volumes to back up, restricted to the
+// resticm.volumes label when set
+func (c Container) Volumes() []Mount {
+	var only map[string]bool
+	if label := strings.TrimSpace(c.Labels[LabelVolumes]); label != "" {
+		only = make(map[string]bool)
+		for _, name := range strings.Split(label, ",") {
+			only[strings.TrimSpace(name)] = true
+		}
+	}
+	var volumes []Mount
+	for _, m := range c.Mounts {
+		if m.Type != "volume" || m.Name == "" {
+			continue
+		}
+		if only != nil && !only[m.Name] {
+			continue
+		}
+		volumes = append(volumes, m)
+	}
+	return volumes
+}
+
+// apiError is the error body returned by the API
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// do sends a request and decodes a JSON response into out when not nil.
+// 304 Not Modified (container already paused, stopped, ...) is not an error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	u := "http://docker" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("docker API at %s: %w", c.Socket, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		var apiErr apiError
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("docker %s %s: %s (HTTP %d)", method, path, apiErr.Message, resp.StatusCode)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if w, ok := out.(io.Writer); ok {
+		_, err = io.Copy(w, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ListContainers returns the containers, running or not, carrying the label
+// (e.g. "resticm.enable=true")
+func (c *Client) ListContainers(ctx context.Context, label string) ([]Container, error) {
+	filters, _ := json.Marshal(map[string][]string{"label": {label}})
+	query := url.Values{"all": {"1"}, "filters": {string(filters)}}
+	var containers []Container
+	if err := c.do(ctx, http.MethodGet, "/containers/json", query, nil, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// Pause pauses the processes of a container
+func (c *Client) Pause(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+id+"/pause", nil, nil, nil)
+}
+
+// Unpause resumes a paused container
+func (c *Client) Unpause(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+id+"/unpause", nil, nil, nil)
+}
+
+// Stop stops a container, killing it after timeout
+func (c *Client) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	query := url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+	return c.do(ctx, http.MethodPost, "/containers/"+id+"/stop", query, nil, nil)
+}
+
+// Start starts a stopped container
+func (c *Client) Start(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+}
+
+// Exec runs a command in a running container and returns its combined
+// output. A non-zero exit code is returned as an error.
+func (c *Client) Exec(ctx context.Context, id string, cmd []string) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/containers/"+id+"/exec", nil, map[string]interface{}{
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          cmd,
+	}, &created); err != nil {
+		return "", err
+	}
+
+	var raw bytes.Buffer
+	if err := c.do(ctx, http.MethodPost, "/exec/"+created.ID+"/start", nil, map[string]interface{}{
+		"Detach": false,
+		"Tty":    false,
+	}, &raw); err != nil {
+		return "", err
+	}
+	output := strings.TrimSpace(demux(raw.Bytes()))
+
+	var inspect struct {
+		ExitCode int  `json:"ExitCode"`
+		Running  bool `json:"Running"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/exec/"+created.ID+"/json", nil, nil, &inspect); err != nil {
+		return output, err
+	}
+	if inspect.ExitCode != 0 {
+		if output == "" {
+			return output, fmt.Errorf("exit code %d", inspect.ExitCode)
+		}
+		return output, fmt.Errorf("exit code %d: %s", inspect.ExitCode, output)
+	}
+	return output, nil
+}
+
+// demux joins the stdout and stderr frames of a non-TTY exec stream. Each
+// frame has an 8 byte header: stream type, 3 zero bytes, big endian size.
+func demux(data []byte) string {
+	var out strings.Builder
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			size = len(data)
+		}
+		out.Write(data[:size])
+		data = data[size:]
+	}
+	return out.String()
+}
+
+// Volume is a named volume to back up and the containers using it
+type Volume struct {
+	Name       string
+	Path       string // Data directory on the host
+	Containers []string
+}
+
+// Tags returns the tags of the volume snapshot
+func (v Volume) Tags() []string {
+	tags := []string{VolumeTagPrefix + v.Name}
+	for _, name := range v.Containers {
+		tags = append(tags, ContainerTagPrefix+name)
+	}
+	return tags
+}
+
+// VolumesOf returns the volumes to back up for the containers, each volume
+// once even when it is shared, in container order
+func VolumesOf(containers []Container) []Volume {
+	var volumes []Volume
+	index := make(map[string]int)
+	for _, c := range containers {
+		for _, m := range c.Volumes() {
+			i, ok := index[m.Name]
+			if !ok {
+				i = len(volumes)
+				index[m.Name] = i
+				volumes = append(volumes, Volume{Name: m.Name, Path: m.Source})
+			}
+			volumes[i].Containers = append(volumes[i].Containers, c.Name())
+		}
+	}
+	return volumes
+}
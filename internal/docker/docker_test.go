@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker is a Docker API server on a unix socket recording the calls
+type fakeDocker struct {
+	mu         sync.Mutex
+	calls      []string
+	containers []Container
+	execCmd    []string
+	execExit   int
+	fail       map[string]int // Path -> HTTP status to return
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	if status, ok := f.fail[r.URL.Path]; ok {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(apiError{Message: "simulated failure"})
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/containers/json":
+		_ = json.NewEncoder(w).Encode(f.containers)
+	case strings.HasSuffix(r.URL.Path, "/exec") && strings.HasPrefix(r.URL.Path, "/containers/"):
+		var body struct{ Cmd []string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.execCmd = body.Cmd
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"exec1"}`))
+	case r.URL.Path == "/exec/exec1/start":
+		// One stdout and one stderr frame
+		for i, text := range []string{"flushed\n", "warning\n"} {
+			header := make([]byte, 8)
+			header[0] = byte(i + 1)
+			binary.BigEndian.PutUint32(header[4:], uint32(len(text)))
+			_, _ = w.Write(append(header, text...))
+		}
+	case r.URL.Path == "/exec/exec1/json":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ExitCode": f.execExit})
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// startFakeDocker serves f on a socket in a temporary directory
+func startFakeDocker(t *testing.T, f *fakeDocker) *Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	server := &http.Server{Handler: f}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return NewClient(socket)
+}
+
+func TestListContainersAndVolumes(t *testing.T) {
+	f := &fakeDocker{containers: []Container{
+		{ID: "a1", Names: []string{"/db"}, State: "running", Labels: map[string]string{LabelMode: "stop"}, Mounts: []Mount{
+			{Type: "volume", Name: "pgdata", Source: "/var/lib/docker/volumes/pgdata/_data"},
+			{Type: "bind", Source: "/etc/localtime"},
+		}},
+		{ID: "b2", Names: []string{"/app"}, State: "running", Labels: map[string]string{LabelVolumes: "uploads"}, Mounts: []Mount{
+			{Type: "volume", Name: "uploads", Source: "/var/lib/docker/volumes/uploads/_data"},
+			{Type: "volume", Name: "cache", Source: "/var/lib/docker/volumes/cache/_data"},
+			{Type: "volume", Name: "pgdata", Source: "/var/lib/docker/volumes/pgdata/_data"},
+		}},
+	}}
+	client := startFakeDocker(t, f)
+
+	containers, err := client.ListContainers(context.Background(), "resticm.enable=true")
+	if err != nil {
+		t.Fatalf("ListContainers() error = %v", err)
+	}
+	if len(containers) != 2 || containers[0].Name() != "db" {
+		t.Fatalf("containers = %+v", containers)
+	}
+	if containers[0].Mode(ModePause) != ModeStop || containers[1].Mode(ModePause) != ModePause {
+		t.Errorf("Mode() = %s, %s", containers[0].Mode(ModePause), containers[1].Mode(ModePause))
+	}
+
+	volumes := VolumesOf(containers)
+	if len(volumes) != 2 {
+		t.Fatalf("volumes = %+v, want pgdata and uploads", volumes)
+	}
+	if got, want := strings.Join(volumes[0].Tags(), ","), "volume:pgdata,docker:db"; got != want {
+		t.Errorf("Tags() = %q, want %q", got, want)
+	}
+	if volumes[1].Name != "uploads" || volumes[1].Path != "/var/lib/docker/volumes/uploads/_data" {
+		t.Errorf("volumes[1] = %+v", volumes[1])
+	}
+}
+
+func TestQuiesceAndRelease(t *testing.T) {
+	f := &fakeDocker{}
+	client := startFakeDocker(t, f)
+	ctr := Container{ID: "a1", Names: []string{"/db"}, State: "running", Labels: map[string]string{
+		LabelPre:  "pg_ctl checkpoint",
+		LabelPost: "echo done",
+	}}
+
+	q, err := client.Quiesce(context.Background(), ctr, ModeStop, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Quiesce() error = %v", err)
+	}
+	if strings.Join(f.execCmd, " ") != "sh -c pg_ctl checkpoint" {
+		t.Errorf("pre command = %v", f.execCmd)
+	}
+	if err := q.Release(context.Background()); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	want := []string{
+		"POST /containers/a1/exec", "POST /exec/exec1/start", "GET /exec/exec1/json",
+		"POST /containers/a1/stop",
+		"POST /containers/a1/start",
+		"POST /containers/a1/exec", "POST /exec/exec1/start", "GET /exec/exec1/json",
+	}
+	if got := strings.Join(f.calls, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestQuiesceFailures(t *testing.T) {
+	f := &fakeDocker{execExit: 3}
+	client := startFakeDocker(t, f)
+	ctr := Container{ID: "a1", Names: []string{"/db"}, State: "running", Labels: map[string]string{LabelPre: "false"}}
+
+	// A failed pre command is reported with its output; nothing to undo
+	q, err := client.Quiesce(context.Background(), ctr, ModePause, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "exit code 3: flushed\nwarning") {
+		t.Errorf("Quiesce() error = %v, want the exit code and output", err)
+	}
+	f.calls = nil
+	if err := q.Release(context.Background()); err != nil || len(f.calls) != 0 {
+		t.Errorf("Release() = %v, calls %v", err, f.calls)
+	}
+
+	// A failed stop still restarts the container
+	f.fail = map[string]int{"/containers/b2/stop": http.StatusInternalServerError}
+	q, err = client.Quiesce(context.Background(), Container{ID: "b2", State: "running"}, ModeStop, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "simulated failure") {
+		t.Errorf("Quiesce() error = %v", err)
+	}
+	f.calls = nil
+	if err := q.Release(context.Background()); err != nil {
+		t.Errorf("Release() error = %v", err)
+	}
+	if len(f.calls) != 1 || f.calls[0] != "POST /containers/b2/start" {
+		t.Errorf("calls = %v, want a start", f.calls)
+	}
+
+	// Stopped containers are left alone
+	f.calls = nil
+	q, err = client.Quiesce(context.Background(), Container{ID: "c3", State: "exited"}, ModeStop, time.Second)
+	if err != nil || len(f.calls) != 0 || q.Action() != "not running" {
+		t.Errorf("Quiesce() = %v, calls %v", err, f.calls)
+	}
+
+	if _, err := client.Quiesce(context.Background(), ctr, "freeze", time.Second); err == nil {
+		t.Error("expected error for an invalid mode")
+	}
+}
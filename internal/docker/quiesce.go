@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Quiesced records what was done to a container before the backup, so
+// Release undoes exactly that
+type Quiesced struct {
+	Container Container
+	Mode      string
+
+	client  *Client
+	preRan  bool
+	paused  bool
+	stopped bool
+}
+
+// Quiesce prepares a container for the backup: it runs the resticm.pre
+// command inside it, then pauses or stops it according to mode. The returned
+// Quiesced must be released even when an error is returned, since part of
+// the preparation may have been done.
+func (c *Client) Quiesce(ctx context.Context, ctr Container, mode string, stopTimeout time.Duration) (*Quiesced, error) {
+	q := &Quiesced{Container: ctr, Mode: mode, client: c}
+	running := ctr.State == "running"
+
+	switch mode {
+	case ModeNone, ModePause, ModeStop:
+	default:
+		return q, fmt.Errorf("container %s: invalid %s '%s' (use none, pause or stop)", ctr.Name(), LabelMode, mode)
+	}
+
+	if pre := ctr.Labels[LabelPre]; pre != "" && running {
+		q.preRan = true
+		if _, err := c.Exec(ctx, ctr.ID, []string{"sh", "-c", pre}); err != nil {
+			return q, fmt.Errorf("container %s: pre command failed: %w", ctr.Name(), err)
+		}
+	}
+
+	if !running {
+		return q, nil
+	}
+	switch mode {
+	case ModePause:
+		if err := c.Pause(ctx, ctr.ID); err != nil {
+			return q, fmt.Errorf("container %s: %w", ctr.Name(), err)
+		}
+		q.paused = true
+	case ModeStop:
+		if err := c.Stop(ctx, ctr.ID, stopTimeout); err != nil {
+			// The container may be stopped even if the request failed
+			q.stopped = true
+			return q, fmt.Errorf("container %s: %w", ctr.Name(), err)
+		}
+		q.stopped = true
+	}
+	return q, nil
+}
+
+// Release resumes the container and runs its resticm.post command. All
+// steps are attempted; their errors are joined.
+func (q *Quiesced) Release(ctx context.Context) error {
+	var errs []error
+	name := q.Container.Name()
+	if q.paused {
+		if err := q.client.Unpause(ctx, q.Container.ID); err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", name, err))
+		} else {
+			q.paused = false
+		}
+	}
+	if q.stopped {
+		if err := q.client.Start(ctx, q.Container.ID); err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", name, err))
+		} else {
+			q.stopped = false
+		}
+	}
+	if post := q.Container.Labels[LabelPost]; post != "" && q.preRan && len(errs) == 0 {
+		q.preRan = false
+		if _, err := q.client.Exec(ctx, q.Container.ID, []string{"sh", "-c", post}); err != nil {
+			errs = append(errs, fmt.Errorf("container %s: post command failed: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Action describes what Quiesce does to the container, for dry runs and logs
+func (q *Quiesced) Action() string {
+	switch {
+	case q.Container.State != "running":
+		return "not running"
+	case q.Mode == ModePause:
+		return "paused"
+	case q.Mode == ModeStop:
+		return "stopped"
+	default:
+		return "left running"
+	}
+}
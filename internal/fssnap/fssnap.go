@@ -0,0 +1,260 @@
+// Package fssnap creates read-only filesystem snapshots (LVM, Btrfs, ZFS) so
+// busy directories are backed up from a consistent point in time
+package fssnap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot types
+const (
+	TypeLVM   = "lvm"
+	TypeBtrfs = "btrfs"
+	TypeZFS   = "zfs"
+)
+
+// Types lists the supported snapshot types
+var Types = []string{TypeLVM, TypeBtrfs, TypeZFS}
+
+// SnapshotName names the snapshots created by resticm
+const SnapshotName = "resticm"
+
+// DefaultMountDir holds the mount points of the snapshots
+const DefaultMountDir = "/run/resticm/snapshots"
+
+// OriginTagPrefix tags a backup with the original path of a directory read
+// from a snapshot
+const OriginTagPrefix = "fssnap:"
+
+// Directory is a directory backed up from a snapshot
+type Directory struct {
+	Path   string // Directory as configured: the volume mount point or subvolume
+	Type   string
+	Volume string // LVM logical volume (vg/lv) or ZFS dataset; unused for btrfs
+
+	// Size of the LVM copy-on-write area (e.g. "5G" or "20%ORIGIN")
+	Size string
+
+	// MountOptions are extra mount options (e.g. "nouuid" for XFS on LVM)
+	MountOptions string
+}
+
+// SnapshotProvider creates and removes the snapshot of a directory
+type SnapshotProvider interface {
+	// Create snapshots the directory and mounts the snapshot read-only at
+	// mountpoint, which exists and is empty
+	Create(ctx context.Context, dir Directory, mountpoint string) error
+
+	// Remove unmounts and destroys the snapshot. It is called even when
+	// Create failed, so it must cope with a partial snapshot.
+	Remove(ctx context.Context, dir Directory, mountpoint string) error
+}
+
+// Runner runs an external command and returns its error with its output
+type Runner func(ctx context.Context, name string, args ...string) error
+
+// New returns the provider for a snapshot type
+func New(snapshotType string) (SnapshotProvider, error) {
+	switch snapshotType {
+	case TypeLVM:
+		return &LVM{Run: Run}, nil
+	case TypeBtrfs:
+		return &Btrfs{Run: Run}, nil
+	case TypeZFS:
+		return &ZFS{Run: Run}, nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot type '%s' (valid: %s)", snapshotType, strings.Join(Types, ", "))
+	}
+}
+
+// Run runs a command, returning its combined output with the error
+func Run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// MountPoint returns where the snapshot of path is mounted. It mirrors the
+// original path below mountDir, so each directory keeps the same path in
+// every backup and restic finds its parent snapshot.
+func MountPoint(mountDir, path string) string {
+	if mountDir == "" {
+		mountDir = DefaultMountDir
+	}
+	return filepath.Join(mountDir, filepath.Clean("/"+path))
+}
+
+// Mounted is a snapshot mounted for the backup
+type Mounted struct {
+	Directory  Directory
+	MountPoint string
+	provider   SnapshotProvider
+}
+
+// Mount creates the snapshot of dir and mounts it below mountDir. The
+// returned Mounted must be released with Unmount even when an error is
+// returned.
+func Mount(ctx context.Context, provider SnapshotProvider, dir Directory, mountDir string) (*Mounted, error) {
+	m := &Mounted{Directory: dir, MountPoint: MountPoint(mountDir, dir.Path), provider: provider}
+	if err := os.MkdirAll(m.MountPoint, 0700); err != nil {
+		return m, fmt.Errorf("failed to create mount point: %w", err)
+	}
+	if err := provider.Create(ctx, dir, m.MountPoint); err != nil {
+		return m, fmt.Errorf("snapshot of %s: %w", dir.Path, err)
+	}
+	return m, nil
+}
+
+// Unmount unmounts and destroys the snapshot, then removes the mount point
+func (m *Mounted) Unmount(ctx context.Context) error {
+	if err := m.provider.Remove(ctx, m.Directory, m.MountPoint); err != nil {
+		return fmt.Errorf("snapshot of %s: %w", m.Directory.Path, err)
+	}
+	_ = os.Remove(m.MountPoint)
+	return nil
+}
+
+// mountOptions joins the read-only flag with extra options
+func mountOptions(base, extra string) string {
+	if extra == "" {
+		return base
+	}
+	return base + "," + extra
+}
+
+// isMounted reports whether path is a mount point
+func isMounted(path string) bool {
+	data, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		// Without mountinfo assume mounted; umount reports the error
+		return true
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 4 && fields[4] == path {
+			return true
+		}
+	}
+	return false
+}
+
+// LVM snapshots a logical volume with lvcreate --snapshot
+type LVM struct {
+	Run Runner
+}
+
+// snapshotVolume returns the vg/lv name of the snapshot
+func (p *LVM) snapshotVolume(dir Directory) string {
+	return dir.Volume + "-" + SnapshotName
+}
+
+// Create implements SnapshotProvider
+func (p *LVM) Create(ctx context.Context, dir Directory, mountpoint string) error {
+	vg, lv, ok := strings.Cut(dir.Volume, "/")
+	if !ok {
+		return fmt.Errorf("invalid LVM volume '%s' (use vg/lv)", dir.Volume)
+	}
+	size := dir.Size
+	if size == "" {
+		size = "10%ORIGIN"
+	}
+	sizeFlag := "--size"
+	if strings.Contains(size, "%") {
+		sizeFlag = "--extents"
+	}
+	if err := p.Run(ctx, "lvcreate", "--snapshot", "--name", lv+"-"+SnapshotName, sizeFlag, size, dir.Volume); err != nil {
+		return err
+	}
+	device := "/dev/" + vg + "/" + lv + "-" + SnapshotName
+	return p.Run(ctx, "mount", "-o", mountOptions("ro", dir.MountOptions), device, mountpoint)
+}
+
+// Remove implements SnapshotProvider
+func (p *LVM) Remove(ctx context.Context, dir Directory, mountpoint string) error {
+	if isMounted(mountpoint) {
+		if err := p.Run(ctx, "umount", mountpoint); err != nil {
+			return err
+		}
+	}
+	// A missing snapshot (Create failed early) is not an error
+	if p.Run(ctx, "lvs", p.snapshotVolume(dir)) != nil {
+		return nil
+	}
+	return p.Run(ctx, "lvremove", "--force", p.snapshotVolume(dir))
+}
+
+// Btrfs takes a read-only snapshot of a subvolume next to it
+type Btrfs struct {
+	Run Runner
+}
+
+// snapshotPath returns where the snapshot of the subvolume is created
+func (p *Btrfs) snapshotPath(dir Directory) string {
+	return filepath.Join(dir.Path, "."+SnapshotName+"-snapshot")
+}
+
+// Create implements SnapshotProvider
+func (p *Btrfs) Create(ctx context.Context, dir Directory, mountpoint string) error {
+	if err := p.Run(ctx, "btrfs", "subvolume", "snapshot", "-r", dir.Path, p.snapshotPath(dir)); err != nil {
+		return err
+	}
+	return p.Run(ctx, "mount", "-o", mountOptions("bind,ro", dir.MountOptions), p.snapshotPath(dir), mountpoint)
+}
+
+// Remove implements SnapshotProvider
+func (p *Btrfs) Remove(ctx context.Context, dir Directory, mountpoint string) error {
+	if isMounted(mountpoint) {
+		if err := p.Run(ctx, "umount", mountpoint); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(p.snapshotPath(dir)); os.IsNotExist(err) {
+		return nil
+	}
+	return p.Run(ctx, "btrfs", "subvolume", "delete", p.snapshotPath(dir))
+}
+
+// ZFS snapshots a dataset and mounts the snapshot
+type ZFS struct {
+	Run Runner
+}
+
+// snapshotName returns the dataset@snapshot name
+func (p *ZFS) snapshotName(dir Directory) string {
+	return dir.Volume + "@" + SnapshotName
+}
+
+// Create implements SnapshotProvider
+func (p *ZFS) Create(ctx context.Context, dir Directory, mountpoint string) error {
+	if err := p.Run(ctx, "zfs", "snapshot", p.snapshotName(dir)); err != nil {
+		return err
+	}
+	return p.Run(ctx, "mount", "-t", "zfs", "-o", mountOptions("ro", dir.MountOptions), p.snapshotName(dir), mountpoint)
+}
+
+// Remove implements SnapshotProvider
+func (p *ZFS) Remove(ctx context.Context, dir Directory, mountpoint string) error {
+	if isMounted(mountpoint) {
+		if err := p.Run(ctx, "umount", mountpoint); err != nil {
+			return err
+		}
+	}
+	if p.Run(ctx, "zfs", "list", "-t", "snapshot", p.snapshotName(dir)) != nil {
+		return nil
+	}
+	return p.Run(ctx, "zfs", "destroy", p.snapshotName(dir))
+}
@@ -0,0 +1,121 @@
+package fssnap
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recorder is a Runner recording the commands, failing those in fail
+type recorder struct {
+	calls []string
+	fail  map[string]bool
+}
+
+func (r *recorder) run(_ context.Context, name string, args ...string) error {
+	call := strings.Join(append([]string{name}, args...), " ")
+	r.calls = append(r.calls, call)
+	if r.fail[name] {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func TestMountPoint(t *testing.T) {
+	if got := MountPoint("", "/var/lib/mysql/"); got != "/run/resticm/snapshots/var/lib/mysql" {
+		t.Errorf("MountPoint() = %q", got)
+	}
+	if got := MountPoint("/mnt/snap", "srv"); got != "/mnt/snap/srv" {
+		t.Errorf("MountPoint() = %q", got)
+	}
+	if _, err := New("xfs"); err == nil {
+		t.Error("expected error for an unknown type")
+	}
+}
+
+func TestLVM(t *testing.T) {
+	r := &recorder{}
+	p := &LVM{Run: r.run}
+	dir := Directory{Path: "/srv", Type: TypeLVM, Volume: "vg0/srv", Size: "2G", MountOptions: "nouuid"}
+	mountpoint := t.TempDir()
+
+	if err := p.Create(context.Background(), dir, mountpoint); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := p.Remove(context.Background(), dir, mountpoint); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	want := []string{
+		"lvcreate --snapshot --name srv-resticm --size 2G vg0/srv",
+		"mount -o ro,nouuid /dev/vg0/srv-resticm " + mountpoint,
+		// The temporary mount point is not mounted, so no umount
+		"lvs vg0/srv-resticm",
+		"lvremove --force vg0/srv-resticm",
+	}
+	if got := strings.Join(r.calls, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// Nothing to remove when the snapshot was never created
+	r = &recorder{fail: map[string]bool{"lvs": true}}
+	p.Run = r.run
+	if err := p.Remove(context.Background(), dir, mountpoint); err != nil || len(r.calls) != 1 {
+		t.Errorf("Remove() = %v, commands %v", err, r.calls)
+	}
+}
+
+func TestZFSAndBtrfs(t *testing.T) {
+	r := &recorder{}
+	mountpoint := t.TempDir()
+	zfs := &ZFS{Run: r.run}
+	if err := zfs.Create(context.Background(), Directory{Path: "/tank/data", Type: TypeZFS, Volume: "tank/data"}, mountpoint); err != nil {
+		t.Fatal(err)
+	}
+	if want := "mount -t zfs -o ro tank/data@resticm " + mountpoint; r.calls[1] != want {
+		t.Errorf("mount = %q, want %q", r.calls[1], want)
+	}
+
+	r = &recorder{}
+	subvolume := t.TempDir()
+	btrfs := &Btrfs{Run: r.run}
+	dir := Directory{Path: subvolume, Type: TypeBtrfs}
+	if err := btrfs.Create(context.Background(), dir, mountpoint); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := filepath.Join(subvolume, ".resticm-snapshot")
+	if want := "btrfs subvolume snapshot -r " + subvolume + " " + snapshot; r.calls[0] != want {
+		t.Errorf("snapshot = %q, want %q", r.calls[0], want)
+	}
+	// The fake runner created no snapshot directory, so nothing is deleted
+	if err := btrfs.Remove(context.Background(), dir, mountpoint); err != nil || len(r.calls) != 2 {
+		t.Errorf("Remove() = %v, commands %v", err, r.calls)
+	}
+}
+
+func TestMountUnmount(t *testing.T) {
+	r := &recorder{fail: map[string]bool{"mount": true}}
+	mountDir := t.TempDir()
+	dir := Directory{Path: "/srv", Type: TypeZFS, Volume: "tank/srv"}
+
+	m, err := Mount(context.Background(), &ZFS{Run: r.run}, dir, mountDir)
+	if err == nil || !strings.Contains(err.Error(), "mount failed") {
+		t.Errorf("Mount() error = %v, want the mount failure", err)
+	}
+	if m.MountPoint != filepath.Join(mountDir, "srv") {
+		t.Errorf("MountPoint = %q", m.MountPoint)
+	}
+
+	// The partial snapshot is destroyed and the mount point removed
+	if err := m.Unmount(context.Background()); err != nil {
+		t.Fatalf("Unmount() error = %v", err)
+	}
+	if last := r.calls[len(r.calls)-1]; last != "zfs destroy tank/srv@resticm" {
+		t.Errorf("last command = %q", last)
+	}
+	if _, err := os.Stat(m.MountPoint); !os.IsNotExist(err) {
+		t.Errorf("mount point %s not removed", m.MountPoint)
+	}
+}
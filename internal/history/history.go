@@ -0,0 +1,376 @@
+// Package history records resticm runs in a local JSON-lines file
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"resticm/internal/redact"
+	"resticm/internal/restic"
+)
+
+// FileName is the name of the history file inside the state directory
+const FileName = "history.jsonl"
+
+// DefaultMaxRuns is the number of runs kept when no limit is configured
+const DefaultMaxRuns = 1000
+
+// Run and step statuses
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Step records a single operation within a run
+type Step struct {
+	Name     string        `json:"name"`
+	Backend  string        `json:"backend,omitempty"`
+	Status   string        `json:"status"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Run records a single resticm command execution
+type Run struct {
+	ID       string                 `json:"id"`
+	Command  string                 `json:"command"`
+	Hostname string                 `json:"hostname,omitempty"`
+	Tags     []string               `json:"tags,omitempty"`
+	Flags    map[string]interface{} `json:"flags,omitempty"`
+	DryRun   bool                   `json:"dry_run,omitempty"`
+	Start    time.Time              `json:"start"`
+	End      time.Time              `json:"end"`
+	Duration time.Duration          `json:"duration_ns"`
+	Status   string                 `json:"status"`
+	Error    string                 `json:"error,omitempty"`
+	Steps    []Step                 `json:"steps,omitempty"`
+	Backup   *restic.BackupSummary  `json:"backup,omitempty"`
+
+	mu sync.Mutex
+}
+
+// NewRun starts recording a run
+func NewRun(id, command string, flags map[string]interface{}) *Run {
+	hostname, _ := os.Hostname()
+	return &Run{
+		ID:       id,
+		Command:  command,
+		Hostname: hostname,
+		Flags:    flags,
+		Start:    time.Now(),
+		Status:   StatusRunning,
+	}
+}
+
+// AddStep records a finished step; a nil error marks it successful
+func (r *Run) AddStep(name, backend string, start time.Time, err error) {
+	step := Step{
+		Name:     name,
+		Backend:  backend,
+		Status:   StatusSuccess,
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		step.Status = StatusFailed
+		step.Error = redact.String(err.Error())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Steps = append(r.Steps, step)
+}
+
+// SkipStep records a step that was not executed
+func (r *Run) SkipStep(name, backend, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Steps = append(r.Steps, Step{
+		Name:    name,
+		Backend: backend,
+		Status:  StatusSkipped,
+		Start:   time.Now(),
+		Error:   redact.String(reason),
+	})
+}
+
+// SetBackup attaches the backup summary to the run
+func (r *Run) SetBackup(summary *restic.BackupSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Backup = summary
+}
+
+// SetTags records the tags applied to the snapshots of the run
+func (r *Run) SetTags(tags []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Tags = append([]string(nil), tags...)
+}
+
+// SnapshotID returns the ID of the snapshot created by the run, if any
+func (r *Run) SnapshotID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Backup == nil {
+		return ""
+	}
+	return r.Backup.SnapshotID
+}
+
+// JSON encodes the run. The duration of a run still in progress is the time
+// elapsed so far.
+func (r *Run) JSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Status == StatusRunning {
+		r.Duration = time.Since(r.Start)
+		defer func() { r.Duration = 0 }()
+	}
+	return json.Marshal(r)
+}
+
+// Finish marks the run as completed with the given result
+func (r *Run) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.End = time.Now()
+	r.Duration = r.End.Sub(r.Start)
+	r.Status = StatusSuccess
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = redact.String(err.Error())
+	}
+}
+
+// Filter selects runs when listing history
+type Filter struct {
+	Command string    // Match runs whose command path contains this value
+	Status  string    // Match runs with this status
+	Since   time.Time // Match runs started at or after this time
+	Limit   int       // Return at most this many of the most recent runs
+}
+
+// Match reports whether a run satisfies the filter
+func (f Filter) Match(r *Run) bool {
+	if f.Command != "" && !strings.Contains(r.Command, f.Command) {
+		return false
+	}
+	if f.Status != "" && r.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && r.Start.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Store persists runs to a JSON-lines file
+type Store struct {
+	path    string
+	maxRuns int
+}
+
+// NewStore creates a store backed by the history file in dir
+func NewStore(dir string) *Store {
+	return &Store{
+		path:    filepath.Join(dir, FileName),
+		maxRuns: DefaultMaxRuns,
+	}
+}
+
+// SetMaxRuns sets how many runs are kept; zero or less keeps everything
+func (s *Store) SetMaxRuns(n int) {
+	s.maxRuns = n
+}
+
+// Path returns the history file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append writes a run to the history file, trimming old runs beyond the limit
+func (s *Store) Append(run *Run) error {
+	data, err := run.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	// Concurrent runs append while another may be trimming: the rename in
+	// trim would drop lines appended to the replaced file
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return s.trim()
+}
+
+// List returns the runs matching the filter, oldest first
+func (s *Store) List(filter Filter) ([]*Run, error) {
+	runs, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*Run
+	for _, r := range runs {
+		if filter.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[len(matched)-filter.Limit:]
+	}
+	return matched, nil
+}
+
+// Last returns the most recent run matching the filter, or nil
+func (s *Store) Last(filter Filter) (*Run, error) {
+	filter.Limit = 1
+	runs, err := s.List(filter)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return runs[0], nil
+}
+
+// LastSuccessfulStep returns the most recent non dry-run run in which the named
+// step succeeded on backend, along with the time that step finished
+func (s *Store) LastSuccessfulStep(step, backend string) (*Run, time.Time, error) {
+	runs, err := s.readAll()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		if r.DryRun {
+			continue
+		}
+		for j := len(r.Steps) - 1; j >= 0; j-- {
+			st := r.Steps[j]
+			if st.Name == step && st.Backend == backend && st.Status == StatusSuccess {
+				return r, st.Start.Add(st.Duration), nil
+			}
+		}
+	}
+	return nil, time.Time{}, nil
+}
+
+// Get returns the run with the given ID (or unique ID prefix), or nil
+func (s *Store) Get(id string) (*Run, error) {
+	runs, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var found *Run
+	for _, r := range runs {
+		if r.ID == id {
+			return r, nil
+		}
+		if strings.HasPrefix(r.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("run ID prefix %q is ambiguous", id)
+			}
+			found = r
+		}
+	}
+	return found, nil
+}
+
+// readAll loads every run, skipping lines that cannot be decoded
+func (s *Store) readAll() ([]*Run, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var runs []*Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var r Run
+		if err := json.Unmarshal(line, &r); err != nil {
+			// A partially written line must not make the whole history unreadable
+			continue
+		}
+		runs = append(runs, &r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return runs, nil
+}
+
+// trim rewrites the history file keeping only the most recent runs; the
+// caller holds the history lock
+func (s *Store) trim() error {
+	if s.maxRuns <= 0 {
+		return nil
+	}
+	runs, err := s.readAll()
+	if err != nil || len(runs) <= s.maxRuns {
+		return err
+	}
+	runs = runs[len(runs)-s.maxRuns:]
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), FileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to rewrite history file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range runs {
+		if err := enc.Encode(r); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to rewrite history file: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to rewrite history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
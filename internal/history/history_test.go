@@ -0,0 +1,354 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"resticm/internal/redact"
+	"resticm/internal/restic"
+)
+
+func TestRunLifecycle(t *testing.T) {
+	run := NewRun("abc123", "resticm backup", map[string]interface{}{"tag": "manual"})
+	if run.Status != StatusRunning {
+		t.Errorf("Status = %q, want %q", run.Status, StatusRunning)
+	}
+
+	start := time.Now().Add(-2 * time.Second)
+	run.AddStep("backup", "primary", start, nil)
+	run.AddStep("copy", "offsite", start, errors.New("connection refused"))
+	run.SkipStep("prune", "offsite", "copy failed")
+	run.SetBackup(&restic.BackupSummary{SnapshotID: "deadbeef", DataAdded: 1024})
+	run.Finish(errors.New("1 operation(s) failed"))
+
+	if run.Status != StatusFailed || run.Error != "1 operation(s) failed" {
+		t.Errorf("unexpected final status %q / error %q", run.Status, run.Error)
+	}
+	if run.End.Before(run.Start) || run.Duration <= 0 {
+		t.Errorf("invalid timing: start=%v end=%v duration=%v", run.Start, run.End, run.Duration)
+	}
+	if len(run.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(run.Steps))
+	}
+	if run.Steps[0].Status != StatusSuccess || run.Steps[0].Duration < 2*time.Second {
+		t.Errorf("unexpected backup step: %+v", run.Steps[0])
+	}
+	if run.Steps[1].Status != StatusFailed || run.Steps[1].Error != "connection refused" {
+		t.Errorf("unexpected copy step: %+v", run.Steps[1])
+	}
+	if run.Steps[2].Status != StatusSkipped {
+		t.Errorf("unexpected prune step: %+v", run.Steps[2])
+	}
+}
+
+func TestRunRedactsErrors(t *testing.T) {
+	redact.AddSecret("history-repo-password")
+
+	run := NewRun("abc123", "resticm backup", nil)
+	run.AddStep("backup", "primary", time.Now(), errors.New("wrong password history-repo-password"))
+	run.SkipStep("copy", "offsite", "key history-repo-password rejected")
+	run.Finish(errors.New("restic -p history-repo-password failed"))
+
+	data, err := run.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if strings.Contains(string(data), "history-repo-password") {
+		t.Errorf("secret stored in the history: %s", data)
+	}
+	if !strings.Contains(run.Steps[0].Error, redact.Mask) {
+		t.Errorf("step error = %q, want the secret masked", run.Steps[0].Error)
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	run := NewRun("abc123", "resticm backup", nil)
+	run.Start = time.Now().Add(-time.Minute)
+	run.SetTags([]string{"daily"})
+	run.SetBackup(&restic.BackupSummary{SnapshotID: "deadbeef"})
+
+	if got := run.SnapshotID(); got != "deadbeef" {
+		t.Errorf("SnapshotID() = %q, want %q", got, "deadbeef")
+	}
+
+	data, err := run.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	var decoded Run
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Status != StatusRunning || decoded.Duration < time.Minute {
+		t.Errorf("running run encoded with status %q and duration %v", decoded.Status, decoded.Duration)
+	}
+	if len(decoded.Tags) != 1 || decoded.Tags[0] != "daily" {
+		t.Errorf("Tags = %v", decoded.Tags)
+	}
+	if run.Duration != 0 {
+		t.Errorf("JSON() changed the duration of a running run to %v", run.Duration)
+	}
+}
+
+func TestStoreAppendAndList(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	runs, err := store.List(Filter{})
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("empty store: runs=%v err=%v", runs, err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	for i, spec := range []struct {
+		command string
+		err     error
+	}{
+		{"resticm backup", nil},
+		{"resticm full", errors.New("check failed")},
+		{"resticm backup", errors.New("repository locked")},
+		{"resticm forget", nil},
+	} {
+		run := NewRun(string(rune('a'+i))+"-run", spec.command, nil)
+		run.Start = base.Add(time.Duration(i) * 10 * time.Minute)
+		run.SetBackup(&restic.BackupSummary{SnapshotID: "snap"})
+		run.Finish(spec.err)
+		if err := store.Append(run); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	runs, err = store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 4 || runs[0].ID != "a-run" || runs[3].ID != "d-run" {
+		t.Fatalf("unexpected runs: %d", len(runs))
+	}
+	if runs[0].Backup == nil || runs[0].Backup.SnapshotID != "snap" {
+		t.Errorf("backup summary not persisted: %+v", runs[0].Backup)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"by command", Filter{Command: "backup"}, []string{"a-run", "c-run"}},
+		{"by status", Filter{Status: StatusFailed}, []string{"b-run", "c-run"}},
+		{"since", Filter{Since: base.Add(15 * time.Minute)}, []string{"c-run", "d-run"}},
+		{"limit keeps most recent", Filter{Limit: 2}, []string{"c-run", "d-run"}},
+		{"combined", Filter{Command: "backup", Status: StatusSuccess}, []string{"a-run"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d runs, want %d", len(got), len(tt.want))
+			}
+			for i, r := range got {
+				if r.ID != tt.want[i] {
+					t.Errorf("run[%d] = %s, want %s", i, r.ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	last, err := store.Last(Filter{Command: "backup", Status: StatusSuccess})
+	if err != nil || last == nil || last.ID != "a-run" {
+		t.Errorf("Last() = %v, %v", last, err)
+	}
+}
+
+func TestStoreGet(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for _, id := range []string{"abc111", "abc222", "def333"} {
+		run := NewRun(id, "resticm backup", nil)
+		run.Finish(nil)
+		if err := store.Append(run); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	if r, err := store.Get("abc222"); err != nil || r == nil || r.ID != "abc222" {
+		t.Errorf("Get(exact) = %v, %v", r, err)
+	}
+	if r, err := store.Get("def"); err != nil || r == nil || r.ID != "def333" {
+		t.Errorf("Get(prefix) = %v, %v", r, err)
+	}
+	if _, err := store.Get("abc"); err == nil {
+		t.Error("expected error for ambiguous prefix")
+	}
+	if r, err := store.Get("zzz"); err != nil || r != nil {
+		t.Errorf("Get(missing) = %v, %v", r, err)
+	}
+}
+
+func TestStoreTrim(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.SetMaxRuns(3)
+
+	for i := 0; i < 5; i++ {
+		run := NewRun(string(rune('a'+i)), "resticm backup", nil)
+		run.Finish(nil)
+		if err := store.Append(run); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	runs, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 3 || runs[0].ID != "c" || runs[2].ID != "e" {
+		t.Errorf("expected runs c..e after trim, got %d runs", len(runs))
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(store.Path()), "*.tmp")); len(tmps) > 0 {
+		t.Errorf("temporary files should not remain after trim: %v", tmps)
+	}
+}
+
+func TestStoreConcurrentAppend(t *testing.T) {
+	dir := t.TempDir()
+	const writers, perWriter = 8, 25
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// A store per writer, as separate resticm processes would have
+			store := NewStore(dir)
+			store.SetMaxRuns(writers * perWriter / 2)
+			for i := 0; i < perWriter; i++ {
+				run := NewRun(fmt.Sprintf("w%d-%d", w, i), "resticm backup", nil)
+				run.Finish(nil)
+				if err := store.Append(run); err != nil {
+					t.Errorf("Append() error = %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	runs, err := NewStore(dir).List(Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != writers*perWriter/2 {
+		t.Errorf("expected %d runs after concurrent appends, got %d", writers*perWriter/2, len(runs))
+	}
+	// Trimming drops the oldest runs only: once a run of a writer is kept,
+	// every later run of that writer must be kept too
+	ids := make(map[string]bool, len(runs))
+	for _, r := range runs {
+		ids[r.ID] = true
+	}
+	for w := 0; w < writers; w++ {
+		kept := false
+		for i := 0; i < perWriter; i++ {
+			id := fmt.Sprintf("w%d-%d", w, i)
+			if kept && !ids[id] {
+				t.Errorf("run %s was lost", id)
+			}
+			kept = kept || ids[id]
+		}
+	}
+}
+
+func TestStoreSkipsCorruptLines(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	run := NewRun("good", "resticm backup", nil)
+	run.Finish(nil)
+	if err := store.Append(run); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	// Simulate a run interrupted while writing
+	f, err := os.OpenFile(filepath.Join(dir, FileName), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"id":"partial","comm`)
+	_ = f.Close()
+
+	runs, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 1 || runs[0].ID != "good" {
+		t.Errorf("expected only the valid run, got %d", len(runs))
+	}
+}
+
+func TestStoreFilePermissions(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "state"))
+	run := NewRun("perm", "resticm backup", nil)
+	run.Finish(nil)
+	if err := store.Append(run); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	info, err := os.Stat(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history file mode = %o, want 600", info.Mode().Perm())
+	}
+}
+
+func TestStoreLastSuccessfulStep(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	start := time.Now().Add(-time.Hour)
+	ok := NewRun("ok", "resticm full", nil)
+	ok.AddStep("backup", "primary", start, nil)
+	ok.AddStep("copy", "offsite", start, nil)
+	ok.Finish(nil)
+
+	failed := NewRun("failed", "resticm full", nil)
+	failed.AddStep("backup", "primary", time.Now(), nil)
+	failed.AddStep("copy", "offsite", time.Now(), errors.New("timeout"))
+	failed.Finish(errors.New("1 operation(s) failed"))
+
+	dry := NewRun("dry", "resticm backup", nil)
+	dry.DryRun = true
+	dry.AddStep("backup", "primary", time.Now(), nil)
+	dry.Finish(nil)
+
+	for _, r := range []*Run{ok, failed, dry} {
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	run, at, err := store.LastSuccessfulStep("backup", "primary")
+	if err != nil || run == nil || run.ID != "failed" {
+		t.Fatalf("LastSuccessfulStep(backup) = %v, %v", run, err)
+	}
+	if at.IsZero() {
+		t.Error("expected step completion time")
+	}
+
+	run, _, err = store.LastSuccessfulStep("copy", "offsite")
+	if err != nil || run == nil || run.ID != "ok" {
+		t.Errorf("LastSuccessfulStep(copy) = %v, %v", run, err)
+	}
+
+	run, _, err = store.LastSuccessfulStep("copy", "unknown")
+	if err != nil || run != nil {
+		t.Errorf("LastSuccessfulStep(unknown) = %v, %v", run, err)
+	}
+}
@@ -0,0 +1,55 @@
+package hooks
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables describing the run to every hook
+const (
+	EnvRunID      = "RESTICM_RUN_ID"
+	EnvCommand    = "RESTICM_COMMAND"
+	EnvConfig     = "RESTICM_CONFIG"
+	EnvRepository = "RESTICM_REPOSITORY"
+	EnvBackend    = "RESTICM_BACKEND"
+	EnvHostname   = "RESTICM_HOSTNAME"
+	EnvTags       = "RESTICM_TAGS"
+	EnvSnapshotID = "RESTICM_SNAPSHOT_ID"
+	EnvDuration   = "RESTICM_DURATION"
+	EnvDryRun     = "RESTICM_DRY_RUN"
+	EnvResultFile = "RESTICM_RESULT_FILE"
+)
+
+// RunInfo describes the current run to hooks
+type RunInfo struct {
+	RunID      string
+	Command    string
+	ConfigPath string
+	Repository string
+	Backend    string
+	Hostname   string
+	Tags       []string
+	SnapshotID string        // Empty until a backup has created a snapshot
+	Duration   time.Duration // Time elapsed since the run started
+	DryRun     bool
+	ResultFile string // JSON file with the run result so far
+}
+
+// Env returns the RESTICM_* variables for the run. Step hooks override
+// RESTICM_BACKEND and RESTICM_REPOSITORY with the backend of their step.
+func (i RunInfo) Env() []string {
+	return []string{
+		EnvRunID + "=" + i.RunID,
+		EnvCommand + "=" + i.Command,
+		EnvConfig + "=" + i.ConfigPath,
+		EnvRepository + "=" + i.Repository,
+		EnvBackend + "=" + i.Backend,
+		EnvHostname + "=" + i.Hostname,
+		EnvTags + "=" + strings.Join(i.Tags, ","),
+		EnvSnapshotID + "=" + i.SnapshotID,
+		EnvDuration + "=" + strconv.Itoa(int(i.Duration.Seconds())),
+		EnvDryRun + "=" + strconv.FormatBool(i.DryRun),
+		EnvResultFile + "=" + i.ResultFile,
+	}
+}
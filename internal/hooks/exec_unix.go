@@ -0,0 +1,80 @@
+//go:build !windows
+// +build !windows
+
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// shellCommand returns the shell used for inline hook commands
+func shellCommand() (string, string) {
+	return "/bin/sh", "-c"
+}
+
+// setProcessGroup starts the hook in its own process group
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessGroup kills the hook and every process it started
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// setCredentials runs the hook as the named user
+func setCredentials(cmd *exec.Cmd, username string) error {
+	if username == "" {
+		return nil
+	}
+
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("unknown user '%s': %w", username, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid uid for user '%s': %w", username, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid gid for user '%s': %w", username, err)
+	}
+
+	if uint32(uid) == uint32(os.Getuid()) {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("running hooks as user '%s' requires root", username)
+	}
+
+	var groups []uint32
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				groups = append(groups, uint32(g))
+			}
+		}
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}
+	cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	return nil
+}
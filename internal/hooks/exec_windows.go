@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package hooks
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// shellCommand returns the shell used for inline hook commands
+func shellCommand() (string, string) {
+	return "cmd", "/C"
+}
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the hook process
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// setCredentials is not supported on Windows
+func setCredentials(cmd *exec.Cmd, username string) error {
+	if username == "" {
+		return nil
+	}
+	return fmt.Errorf("running hooks as another user is not supported on Windows")
+}
@@ -3,9 +3,17 @@ package hooks
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"resticm/internal/redact"
 )
 
 // Logger interface for hook logging
@@ -14,16 +22,62 @@ type Logger interface {
 	Error(format string, args ...interface{})
 }
 
+// Hook describes a hook: an executable file, an inline shell command or an
+// argv list. Exactly one of Path, Command and Argv is set.
+type Hook struct {
+	Path    string
+	Command string
+	Argv    []string
+
+	// Timeout kills the hook and its children when exceeded; zero uses Runner.Timeout
+	Timeout time.Duration
+	// Workdir is the working directory; empty uses the current directory
+	Workdir string
+	// User runs the hook as another user (requires root)
+	User string
+
+	// ContinueOnError keeps running the remaining scripts of a hook
+	// directory after one fails; by default the first failure stops it
+	ContinueOnError bool
+}
+
+// IsSet reports whether the hook has something to run
+func (h Hook) IsSet() bool {
+	return h.Path != "" || h.Command != "" || len(h.Argv) > 0
+}
+
+// String returns a short description of the hook for messages
+func (h Hook) String() string {
+	switch {
+	case h.Path != "":
+		return h.Path
+	case h.Command != "":
+		return h.Command
+	default:
+		return strings.Join(h.Argv, " ")
+	}
+}
+
 // Runner executes hook scripts
 type Runner struct {
-	PreBackup  string
-	PostBackup string
-	OnError    string
-	OnSuccess  string
+	PreBackup  Hook
+	PostBackup Hook
+	OnError    Hook
+	OnSuccess  Hook
 	DryRun     bool
 	Env        []string
 	Verbose    bool
 	Logger     Logger
+
+	// Timeout applies to hooks without their own timeout; zero means no limit
+	Timeout time.Duration
+
+	// RunInfo is called before each hook executes to describe the current run
+	// in RESTICM_* variables; nil passes no run information
+	RunInfo func() RunInfo
+
+	// Stdout receives the hook messages and output; nil writes to os.Stdout
+	Stdout io.Writer
 }
 
 // NewRunner creates a new hook runner
@@ -31,75 +85,198 @@ func NewRunner() *Runner {
 	return &Runner{}
 }
 
+// printf writes a hook message to the runner output
+func (r *Runner) printf(format string, a ...interface{}) {
+	w := r.Stdout
+	if w == nil {
+		w = os.Stdout
+	}
+	_, _ = fmt.Fprintf(w, format, a...)
+}
+
 // Run executes a hook script
 func (r *Runner) Run(path string, extraEnv []string) (string, error) {
-	// Empty path means no hook configured
-	if path == "" {
-		return "", nil
-	}
+	return r.RunHook(Hook{Path: path}, extraEnv)
+}
 
-	// Check if hook exists
-	info, err := os.Stat(path)
-	if os.IsNotExist(err) {
-		// Hook file not found, silently skip (not configured)
+// RunHook executes a hook, streaming its output line by line to the console
+// and log, and returns the combined output
+func (r *Runner) RunHook(hook Hook, extraEnv []string) (string, error) {
+	// Nothing configured
+	if !hook.IsSet() {
 		return "", nil
 	}
-	if err != nil {
-		return "", fmt.Errorf("cannot access hook %s: %w", path, err)
-	}
 
-	// Check if hook is executable
-	if info.Mode()&0111 == 0 {
-		return "", fmt.Errorf("hook %s exists but is not executable (chmod +x %s)", path, path)
+	name := hook.String()
+
+	if hook.Path != "" {
+		// Check if hook exists
+		info, err := os.Stat(hook.Path)
+		if os.IsNotExist(err) {
+			// Hook file not found, silently skip (not configured)
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot access hook %s: %w", hook.Path, err)
+		}
+
+		// Directories are run like run-parts
+		if info.IsDir() {
+			return r.runParts(hook, extraEnv)
+		}
+
+		// Check if hook is executable
+		if info.Mode()&0111 == 0 {
+			return "", fmt.Errorf("hook %s exists but is not executable (chmod +x %s)", hook.Path, hook.Path)
+		}
 	}
 
 	// In dry-run mode, don't execute
 	if r.DryRun {
-		fmt.Printf("🪝 [DRY-RUN] Would execute hook: %s\n", path)
+		r.printf("🪝 [DRY-RUN] Would execute hook: %s\n", name)
 		return "", nil
 	}
 
 	// Log hook execution
-	fmt.Printf("🪝 Executing hook: %s\n", path)
+	r.printf("🪝 Executing hook: %s\n", name)
 	if r.Logger != nil {
-		r.Logger.Info("Executing hook: %s", path)
+		r.Logger.Info("Executing hook: %s", name)
+	}
+
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = r.Timeout
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
 	// Prepare command
-	cmd := exec.Command(path)
+	cmd, err := hookCommand(ctx, hook)
+	if err != nil {
+		return "", err
+	}
+	cmd.Dir = hook.Workdir
 	cmd.Env = append(os.Environ(), r.Env...)
+	if r.RunInfo != nil {
+		cmd.Env = append(cmd.Env, r.RunInfo().Env()...)
+	}
 	cmd.Env = append(cmd.Env, extraEnv...)
+	if err := setCredentials(cmd, hook.User); err != nil {
+		return "", fmt.Errorf("hook %s: %w", name, err)
+	}
 
-	// Capture output
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Kill the whole process group on timeout, so children started by the hook
+	// do not keep running; WaitDelay stops waiting for pipes they inherited.
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = 5 * time.Second
+
+	// Stream and capture output
+	out := &lineWriter{emit: func(line string) {
+		r.printf("   │ %s\n", line)
+		if r.Logger != nil {
+			r.Logger.Info("[hook %s] %s", name, line)
+		}
+	}}
+	cmd.Stdout = out
+	cmd.Stderr = out
 
 	// Execute
 	runErr := cmd.Run()
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		output += stderr.String()
-	}
+	out.Flush()
+	output := out.String()
 
 	if runErr != nil {
-		fmt.Printf("❌ Hook failed: %s\n", path)
+		if ctx.Err() == context.DeadlineExceeded {
+			runErr = fmt.Errorf("timed out after %s", timeout)
+		}
+		r.printf("❌ Hook failed: %s\n", name)
 		if r.Logger != nil {
-			r.Logger.Error("Hook failed: %s - %v", path, runErr)
+			r.Logger.Error("Hook failed: %s - %v", name, runErr)
 		}
-		return output, fmt.Errorf("hook %s failed: %w\nOutput: %s", path, runErr, output)
+		return output, fmt.Errorf("hook %s failed: %w\nOutput: %s", name, runErr, output)
 	}
 
-	fmt.Printf("✅ Hook completed: %s\n", path)
+	r.printf("✅ Hook completed: %s\n", name)
 	if r.Logger != nil {
-		r.Logger.Info("Hook completed: %s", path)
+		r.Logger.Info("Hook completed: %s", name)
 	}
 	return output, nil
 }
 
+// hookCommand builds the command for a hook
+func hookCommand(ctx context.Context, hook Hook) (*exec.Cmd, error) {
+	switch {
+	case hook.Path != "":
+		return exec.CommandContext(ctx, hook.Path), nil
+	case hook.Command != "":
+		shell, flag := shellCommand()
+		return exec.CommandContext(ctx, shell, flag, hook.Command), nil
+	default:
+		return exec.CommandContext(ctx, hook.Argv[0], hook.Argv[1:]...), nil
+	}
+}
+
+// lineWriter captures output and emits each complete, redacted line
+type lineWriter struct {
+	mu   sync.Mutex
+	emit func(line string)
+	buf  bytes.Buffer
+	out  bytes.Buffer
+}
+
+// Write buffers p and emits complete lines
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.write(line)
+	}
+	return len(p), nil
+}
+
+// Flush emits a trailing line without newline
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.write(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+// write records and emits one line
+func (w *lineWriter) write(line string) {
+	line = redact.String(line)
+	w.out.WriteString(line)
+	if w.emit != nil {
+		w.emit(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// String returns the captured output
+func (w *lineWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.String()
+}
+
 // RunPreBackup executes the pre-backup hook
 func (r *Runner) RunPreBackup() error {
-	_, err := r.Run(r.PreBackup, nil)
+	_, err := r.RunHook(r.PreBackup, nil)
 	return err
 }
 
@@ -114,19 +291,79 @@ func (r *Runner) RunPostBackup(success bool, backupErr error) error {
 			env = append(env, fmt.Sprintf("BACKUP_ERROR=%s", backupErr.Error()))
 		}
 	}
-	_, err := r.Run(r.PostBackup, env)
+	_, err := r.RunHook(r.PostBackup, env)
 	return err
 }
 
 // RunOnError executes the on-error hook
 func (r *Runner) RunOnError(opErr error) error {
 	env := []string{fmt.Sprintf("ERROR=%s", opErr.Error())}
-	_, err := r.Run(r.OnError, env)
+	_, err := r.RunHook(r.OnError, env)
 	return err
 }
 
 // RunOnSuccess executes the on-success hook
 func (r *Runner) RunOnSuccess() error {
-	_, err := r.Run(r.OnSuccess, nil)
+	_, err := r.RunHook(r.OnSuccess, nil)
+	return err
+}
+
+// Pre-hook failure policies for workflow steps
+const (
+	PreFailureAbort = "abort"
+	PreFailureSkip  = "skip"
+)
+
+// ErrStepSkipped is returned by RunPreStep when a failed pre hook skips the step
+var ErrStepSkipped = errors.New("step skipped")
+
+// StepHook configures the hooks run around a workflow step
+type StepHook struct {
+	Pre  Hook
+	Post Hook
+
+	// OnPreFailure is PreFailureAbort (default) or PreFailureSkip
+	OnPreFailure string
+}
+
+// stepEnv returns the environment describing a step to its hooks
+func stepEnv(step, backend, repository, hook, status string, stepErr error) []string {
+	env := []string{
+		"RESTICM_STEP=" + step,
+		EnvBackend + "=" + backend,
+		"RESTICM_HOOK=" + hook,
+		"RESTICM_STEP_STATUS=" + status,
+	}
+	// An unknown repository keeps the one of the run
+	if repository != "" {
+		env = append(env, EnvRepository+"="+repository)
+	}
+	if stepErr != nil {
+		env = append(env, "RESTICM_STEP_ERROR="+stepErr.Error())
+	}
+	return env
+}
+
+// RunPreStep executes the pre hook of a step on the repository of a backend.
+// If the hook fails, the step is either skipped (an error wrapping
+// ErrStepSkipped) or aborted (the hook error).
+func (r *Runner) RunPreStep(step, backend, repository string, hook StepHook) error {
+	_, err := r.RunHook(hook.Pre, stepEnv(step, backend, repository, "pre", "running", nil))
+	if err == nil {
+		return nil
+	}
+	if hook.OnPreFailure == PreFailureSkip {
+		return fmt.Errorf("%w: %v", ErrStepSkipped, err)
+	}
+	return err
+}
+
+// RunPostStep executes the post hook of a step with the step outcome
+func (r *Runner) RunPostStep(step, backend, repository string, hook StepHook, stepErr error) error {
+	status := "success"
+	if stepErr != nil {
+		status = "failure"
+	}
+	_, err := r.RunHook(hook.Post, stepEnv(step, backend, repository, "post", status, stepErr))
 	return err
 }
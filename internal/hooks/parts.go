@@ -0,0 +1,107 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoredSuffixes are left behind by editors and package managers and never run
+var ignoredSuffixes = []string{"~", ".bak", ".swp", ".disabled", ".dpkg-old", ".dpkg-dist", ".dpkg-new", ".rpmsave", ".rpmnew"}
+
+// ListParts returns the executable scripts of a hook directory in lexical
+// order. Hidden files, subdirectories, backup files and non-executable files
+// are skipped, like run-parts.
+func ListParts(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read hook directory %s: %w", dir, err)
+	}
+
+	var parts []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || ignoredPart(name) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		parts = append(parts, path)
+	}
+	return parts, nil
+}
+
+// ignoredPart reports whether a file name looks like a backup or disabled script
+func ignoredPart(name string) bool {
+	for _, suffix := range ignoredSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// runParts executes every script of a hook directory in lexical order
+func (r *Runner) runParts(hook Hook, extraEnv []string) (string, error) {
+	parts, err := ListParts(hook.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if len(parts) == 0 {
+		r.printf("🪝 No executable scripts in hook directory: %s\n", hook.Path)
+		return "", nil
+	}
+
+	if r.DryRun {
+		r.printf("🪝 [DRY-RUN] Would execute %d script(s) from %s:\n", len(parts), hook.Path)
+		for _, part := range parts {
+			r.printf("   - %s\n", filepath.Base(part))
+		}
+		return "", nil
+	}
+
+	r.printf("🪝 Running %d script(s) from %s\n", len(parts), hook.Path)
+	if r.Logger != nil {
+		r.Logger.Info("Running %d script(s) from hook directory %s", len(parts), hook.Path)
+	}
+
+	var output strings.Builder
+	var failed []string
+	ran := 0
+	for _, part := range parts {
+		partHook := hook
+		partHook.Path = part
+
+		out, err := r.RunHook(partHook, extraEnv)
+		output.WriteString(out)
+		ran++
+		if err != nil {
+			failed = append(failed, filepath.Base(part))
+			if !hook.ContinueOnError {
+				break
+			}
+		}
+	}
+
+	if skipped := len(parts) - ran; skipped > 0 {
+		r.printf("⏭️  Skipped %d remaining script(s) in %s after failure\n", skipped, hook.Path)
+	}
+
+	if len(failed) > 0 {
+		if r.Logger != nil {
+			r.Logger.Error("Hook directory %s: %d of %d script(s) failed: %s", hook.Path, len(failed), ran, strings.Join(failed, ", "))
+		}
+		return output.String(), fmt.Errorf("hook directory %s: %d script(s) failed: %s", hook.Path, len(failed), strings.Join(failed, ", "))
+	}
+
+	if r.Logger != nil {
+		r.Logger.Info("Hook directory %s: %d script(s) completed", hook.Path, ran)
+	}
+	return output.String(), nil
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// textHandler is a slog.Handler producing resticm's human-readable line format:
+//
+//	[2006-01-02 15:04:05] [LEVEL] [prefix] message key=value ...
+type textHandler struct {
+	w      io.Writer
+	prefix string
+	attrs  []slog.Attr
+	groups []string
+}
+
+// Enabled reports whether the handler handles records at the given level.
+// Level filtering is done by Logger, so every level is accepted here.
+func (h *textHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+// Handle formats the record as a single line
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+
+	buf.WriteString("[")
+	buf.WriteString(r.Time.Format("2006-01-02 15:04:05"))
+	buf.WriteString("] [")
+	buf.WriteString(levelFromSlog(r.Level).String())
+	buf.WriteString("] ")
+	if h.prefix != "" {
+		buf.WriteString("[")
+		buf.WriteString(h.prefix)
+		buf.WriteString("] ")
+	}
+	buf.WriteString(r.Message)
+
+	for _, a := range flattenRecord(h.attrs, h.groups, r) {
+		buf.WriteByte(' ')
+		buf.WriteString(a.Key)
+		buf.WriteByte('=')
+		buf.WriteString(quoteIfNeeded(attrString(a.Value)))
+	}
+	buf.WriteByte('\n')
+
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// WithAttrs returns a handler that always includes the given attributes
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textHandler{w: h.w, prefix: h.prefix, attrs: appendGrouped(h.attrs, h.groups, attrs), groups: h.groups}
+}
+
+// WithGroup returns a handler that qualifies subsequent attribute keys
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(append([]string{}, h.groups...), name)
+	return &textHandler{w: h.w, prefix: h.prefix, attrs: h.attrs, groups: groups}
+}
+
+// flattenRecord returns the handler attributes followed by the record
+// attributes, with groups flattened into dotted keys
+func flattenRecord(handlerAttrs []slog.Attr, groups []string, r slog.Record) []slog.Attr {
+	out := make([]slog.Attr, 0, len(handlerAttrs)+r.NumAttrs())
+	out = append(out, handlerAttrs...)
+	groupPrefix := strings.Join(groups, ".")
+	r.Attrs(func(a slog.Attr) bool {
+		out = flattenAttr(out, groupPrefix, a)
+		return true
+	})
+	return out
+}
+
+// appendGrouped flattens attrs under the current groups and appends them to existing
+func appendGrouped(existing []slog.Attr, groups []string, attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, 0, len(existing)+len(attrs))
+	out = append(out, existing...)
+	groupPrefix := strings.Join(groups, ".")
+	for _, a := range attrs {
+		out = flattenAttr(out, groupPrefix, a)
+	}
+	return out
+}
+
+// flattenAttr appends a resolved attribute, expanding groups into dotted keys
+func flattenAttr(out []slog.Attr, groupPrefix string, a slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return out
+	}
+
+	key := a.Key
+	if groupPrefix != "" {
+		key = groupPrefix + "." + key
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			out = flattenAttr(out, key, ga)
+		}
+		return out
+	}
+
+	return append(out, slog.Attr{Key: key, Value: a.Value})
+}
+
+// attrString renders an attribute value as text
+func attrString(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339)
+	case slog.KindDuration:
+		return v.Duration().String()
+	default:
+		return v.String()
+	}
+}
+
+// quoteIfNeeded quotes values that would otherwise be ambiguous in key=value output
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// journalSocket is the path of journald's native protocol socket
+var journalSocket = "/run/systemd/journal/socket"
+
+// journalWriter sends entries to journald using the native protocol
+type journalWriter struct {
+	mu         sync.Mutex
+	conn       net.Conn
+	identifier string
+}
+
+// newJournalWriter connects to the journald socket
+func newJournalWriter(identifier string) (*journalWriter, error) {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald socket %s: %w", journalSocket, err)
+	}
+	if identifier == "" {
+		identifier = "resticm"
+	}
+	return &journalWriter{conn: conn, identifier: identifier}, nil
+}
+
+// format serializes a record using the journald native protocol
+func (w *journalWriter) format(r slog.Record, attrs []slog.Attr) []byte {
+	var buf bytes.Buffer
+
+	writeJournalField(&buf, "MESSAGE", r.Message)
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(levelFromSlog(r.Level))))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", w.identifier)
+
+	for _, a := range attrs {
+		name := journalFieldName(a.Key)
+		if name == "" {
+			continue
+		}
+		writeJournalField(&buf, name, attrString(a.Value))
+	}
+
+	return buf.Bytes()
+}
+
+// send writes a datagram to journald
+func (w *journalWriter) send(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.conn.Write(data)
+	return err
+}
+
+// Close closes the journald connection
+func (w *journalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.Close()
+}
+
+// writeJournalField appends a field, using the binary-safe form for multi-line values
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts an attribute key to a valid journald field name
+// (uppercase letters, digits and underscores, not starting with an underscore)
+func journalFieldName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journalHandler is a slog.Handler sending records to journald
+type journalHandler struct {
+	w      *journalWriter
+	attrs  []slog.Attr
+	groups []string
+}
+
+// Enabled reports whether the handler handles records at the given level
+func (h *journalHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+// Handle sends the record to journald
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	return h.w.send(h.w.format(r, flattenRecord(h.attrs, h.groups, r)))
+}
+
+// WithAttrs returns a handler that always includes the given attributes
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &journalHandler{w: h.w, attrs: appendGrouped(h.attrs, h.groups, attrs), groups: h.groups}
+}
+
+// WithGroup returns a handler that qualifies subsequent attribute keys
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &journalHandler{w: h.w, attrs: h.attrs, groups: append(append([]string{}, h.groups...), name)}
+}
@@ -2,13 +2,17 @@
 package logging
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"resticm/internal/redact"
 )
 
 // Level represents log level
@@ -36,13 +40,55 @@ func (l Level) String() string {
 	}
 }
 
-// Logger is a structured logger
+// slogLevel maps a Level to the equivalent slog level
+func (l Level) slogLevel() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// levelFromSlog maps a slog level back to a Level
+func levelFromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	default:
+		return ERROR
+	}
+}
+
+// Standard attribute keys used across resticm log records
+const (
+	KeyCommand    = "command"
+	KeyBackend    = "backend"
+	KeyRepository = "repository"
+	KeyRunID      = "run_id"
+	KeyDuration   = "duration"
+	KeyExitCode   = "exit_code"
+	KeyError      = "error"
+	KeyComponent  = "component"
+)
+
+// Logger is a structured logger built on log/slog
 type Logger struct {
 	mu       sync.Mutex
 	level    Level
 	outputs  []io.Writer
 	prefix   string
 	jsonMode bool
+	attrs    []slog.Attr
+	sinks    []slog.Handler
 }
 
 // Config represents logging configuration
@@ -53,6 +99,8 @@ type Config struct {
 	Level     string
 	Console   bool
 	JSON      bool
+	Syslog    SyslogConfig
+	Journald  bool
 }
 
 var defaultLogger = NewLogger(INFO)
@@ -91,7 +139,23 @@ func Configure(cfg Config) (*Logger, error) {
 		outputs = append(outputs, file)
 	}
 
-	if len(outputs) == 0 {
+	if cfg.Syslog.Enabled {
+		w, err := newSyslogWriter(cfg.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		logger.sinks = append(logger.sinks, &syslogHandler{w: w})
+	}
+
+	if cfg.Journald {
+		w, err := newJournalWriter(cfg.Syslog.Tag)
+		if err != nil {
+			return nil, err
+		}
+		logger.sinks = append(logger.sinks, &journalHandler{w: w})
+	}
+
+	if len(outputs) == 0 && len(logger.sinks) == 0 {
 		outputs = append(outputs, os.Stdout)
 	}
 
@@ -128,32 +192,79 @@ func (l *Logger) SetPrefix(prefix string) {
 	l.prefix = prefix
 }
 
-// log writes a log entry
-func (l *Logger) log(level Level, format string, args ...interface{}) {
-	if level < l.level {
-		return
+// handlerFor returns the slog handler writing to a single output
+func (l *Logger) handlerFor(out io.Writer) slog.Handler {
+	var h slog.Handler
+	if l.jsonMode {
+		h = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+		if l.prefix != "" {
+			h = h.WithAttrs([]slog.Attr{slog.String(KeyComponent, l.prefix)})
+		}
+	} else {
+		h = &textHandler{w: out, prefix: l.prefix}
 	}
+	return h
+}
 
+// write emits a record with the given attributes to every output
+func (l *Logger) write(level Level, msg string, attrs []slog.Attr) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	now := time.Now().Format("2006-01-02 15:04:05")
-	msg := fmt.Sprintf(format, args...)
+	if level < l.level {
+		return
+	}
+
+	record := slog.NewRecord(time.Now(), level.slogLevel(), redact.String(msg), 0)
+	record.AddAttrs(l.attrs...)
+	record.AddAttrs(redactAttrs(attrs)...)
 
-	var line string
-	if l.jsonMode {
-		line = fmt.Sprintf(`{"time":"%s","level":"%s","msg":"%s"}`, now, level, msg)
-	} else {
+	for _, out := range l.outputs {
+		_ = l.handlerFor(out).Handle(context.Background(), record)
+	}
+
+	for _, sink := range l.sinks {
 		if l.prefix != "" {
-			line = fmt.Sprintf("[%s] [%s] [%s] %s", now, level, l.prefix, msg)
-		} else {
-			line = fmt.Sprintf("[%s] [%s] %s", now, level, msg)
+			sink = sink.WithAttrs([]slog.Attr{slog.String(KeyComponent, l.prefix)})
 		}
+		_ = sink.Handle(context.Background(), record)
 	}
+}
 
-	for _, out := range l.outputs {
-		_, _ = fmt.Fprintln(out, line)
+// redactAttrs masks secrets in string, error and fmt.Stringer attribute values
+func redactAttrs(attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return attrs
+	}
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+		switch a.Value.Kind() {
+		case slog.KindString:
+			a.Value = slog.StringValue(redact.String(a.Value.String()))
+		case slog.KindGroup:
+			a.Value = slog.GroupValue(redactAttrs(a.Value.Group())...)
+		case slog.KindAny:
+			switch v := a.Value.Any().(type) {
+			case error:
+				a.Value = slog.StringValue(redact.String(v.Error()))
+			case fmt.Stringer:
+				a.Value = slog.StringValue(redact.String(v.String()))
+			}
+		}
+		out[i] = a
 	}
+	return out
+}
+
+// log writes a log entry
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	l.write(level, fmt.Sprintf(format, args...), nil)
+}
+
+// LogAttrs writes a log entry carrying structured attributes
+func (l *Logger) LogAttrs(level Level, msg string, attrs ...slog.Attr) {
+	l.write(level, msg, attrs)
 }
 
 // Debug logs at debug level
@@ -189,10 +300,54 @@ func (l *Logger) WithPrefix(prefix string) *Logger {
 		outputs:  l.outputs,
 		prefix:   prefix,
 		jsonMode: l.jsonMode,
+		attrs:    l.attrs,
+		sinks:    l.sinks,
 	}
 	return newLogger
 }
 
+// With returns a new logger that adds the given attributes to every entry
+func (l *Logger) With(attrs ...slog.Attr) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	merged := make([]slog.Attr, 0, len(l.attrs)+len(attrs))
+	merged = append(merged, l.attrs...)
+	merged = append(merged, redactAttrs(attrs)...)
+
+	return &Logger{
+		level:    l.level,
+		outputs:  l.outputs,
+		prefix:   l.prefix,
+		jsonMode: l.jsonMode,
+		attrs:    merged,
+		sinks:    l.sinks,
+	}
+}
+
+// WithoutConsole returns a new logger that does not write to stdout, for
+// commands whose stdout carries data
+func (l *Logger) WithoutConsole() *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	outputs := make([]io.Writer, 0, len(l.outputs))
+	for _, out := range l.outputs {
+		if out != os.Stdout {
+			outputs = append(outputs, out)
+		}
+	}
+
+	return &Logger{
+		level:    l.level,
+		outputs:  outputs,
+		prefix:   l.prefix,
+		jsonMode: l.jsonMode,
+		attrs:    l.attrs,
+		sinks:    l.sinks,
+	}
+}
+
 // Package-level functions using default logger
 
 // Debug logs at debug level
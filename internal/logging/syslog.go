@@ -0,0 +1,265 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogConfig configures the syslog output
+type SyslogConfig struct {
+	Enabled  bool
+	Network  string // "udp", "tcp", "unix" or "unixgram"; empty means the local syslog socket
+	Address  string // host:port for udp/tcp, socket path for unix
+	Tag      string // APP-NAME field, defaults to "resticm"
+	Facility string // syslog facility name, defaults to "daemon"
+}
+
+// sdID is the RFC5424 structured data ID used for resticm attributes.
+// 32473 is the private enterprise number reserved for documentation.
+const sdID = "resticm@32473"
+
+// localSyslogSockets lists the usual locations of the local syslog socket
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// facilities maps facility names to their RFC5424 codes
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverity maps a Level to its syslog severity
+func syslogSeverity(l Level) int {
+	switch l {
+	case DEBUG:
+		return 7
+	case INFO:
+		return 6
+	case WARN:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// syslogWriter holds the connection to a syslog daemon
+type syslogWriter struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	conn     net.Conn
+	tag      string
+	facility int
+	hostname string
+}
+
+// newSyslogWriter connects to the configured syslog destination
+func newSyslogWriter(cfg SyslogConfig) (*syslogWriter, error) {
+	facility := 3
+	if cfg.Facility != "" {
+		f, ok := facilities[strings.ToLower(cfg.Facility)]
+		if !ok {
+			return nil, fmt.Errorf("unknown syslog facility: %s", cfg.Facility)
+		}
+		facility = f
+	}
+
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "resticm"
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogWriter{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		tag:      tag,
+		facility: facility,
+		hostname: hostname,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect dials the syslog destination, probing local sockets when none is configured
+func (w *syslogWriter) connect() error {
+	if w.network != "" && w.network != "unix" && w.network != "unixgram" {
+		conn, err := net.DialTimeout(w.network, w.address, 5*time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog %s/%s: %w", w.network, w.address, err)
+		}
+		w.conn = conn
+		return nil
+	}
+
+	addresses := localSyslogSockets
+	if w.address != "" {
+		addresses = []string{w.address}
+	}
+	networks := []string{"unixgram", "unix"}
+	if w.network != "" {
+		networks = []string{w.network}
+	}
+
+	for _, addr := range addresses {
+		for _, network := range networks {
+			if conn, err := net.Dial(network, addr); err == nil {
+				w.conn = conn
+				w.network = network
+				w.address = addr
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("failed to connect to local syslog socket (tried %s)", strings.Join(addresses, ", "))
+}
+
+// format builds an RFC5424 message
+func (w *syslogWriter) format(r slog.Record, attrs []slog.Attr) string {
+	pri := w.facility*8 + syslogSeverity(levelFromSlog(r.Level))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ",
+		pri, r.Time.Format(time.RFC3339Nano), w.hostname, w.tag, os.Getpid())
+
+	if len(attrs) == 0 {
+		b.WriteString("-")
+	} else {
+		b.WriteString("[" + sdID)
+		for _, a := range attrs {
+			fmt.Fprintf(&b, " %s=\"%s\"", sdName(a.Key), sdEscape(attrString(a.Value)))
+		}
+		b.WriteString("]")
+	}
+
+	b.WriteString(" ")
+	b.WriteString(r.Message)
+	return b.String()
+}
+
+// send writes a message, reconnecting once if the connection was lost
+func (w *syslogWriter) send(msg string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if _, err := w.conn.Write(w.frame(msg)); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	if err := w.connect(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(w.frame(msg))
+	return err
+}
+
+// frame delimits a message for the transport: octet counting (RFC 6587) over
+// TCP, a trailing newline over a local stream socket, which syslog daemons
+// read line by line, and none over datagrams
+func (w *syslogWriter) frame(msg string) []byte {
+	switch {
+	case strings.HasPrefix(w.network, "tcp"):
+		return []byte(fmt.Sprintf("%d %s", len(msg), msg))
+	case w.network == "unix" && !strings.HasSuffix(msg, "\n"):
+		return []byte(msg + "\n")
+	}
+	return []byte(msg)
+}
+
+// Close closes the syslog connection
+func (w *syslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// syslogHandler is a slog.Handler sending records to syslog
+type syslogHandler struct {
+	w      *syslogWriter
+	attrs  []slog.Attr
+	groups []string
+}
+
+// Enabled reports whether the handler handles records at the given level
+func (h *syslogHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+// Handle sends the record as an RFC5424 message
+func (h *syslogHandler) Handle(_ context.Context, r slog.Record) error {
+	return h.w.send(h.w.format(r, flattenRecord(h.attrs, h.groups, r)))
+}
+
+// WithAttrs returns a handler that always includes the given attributes
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{w: h.w, attrs: appendGrouped(h.attrs, h.groups, attrs), groups: h.groups}
+}
+
+// WithGroup returns a handler that qualifies subsequent attribute keys
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &syslogHandler{w: h.w, attrs: h.attrs, groups: append(append([]string{}, h.groups...), name)}
+}
+
+// sdName sanitizes an attribute key into a valid SD-PARAM name
+func sdName(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if r > 32 && r < 127 && r != '=' && r != ']' && r != '"' && r != ' ' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// sdEscape escapes an SD-PARAM value as required by RFC5424
+func sdEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return r.Replace(s)
+}
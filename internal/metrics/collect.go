@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	"resticm/internal/history"
+	"resticm/internal/status"
+)
+
+// Workflow returns the workflow label for a recorded command path,
+// e.g. "resticm backup" -> "backup" and "resticm" -> "default"
+func Workflow(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) <= 1 {
+		return "default"
+	}
+	return strings.Join(fields[1:], "_")
+}
+
+// AddRuns adds run metrics derived from the history. runs must be ordered
+// oldest first; dry runs and unfinished runs are ignored.
+func AddRuns(s *Set, runs []*history.Run) {
+	type workflowState struct {
+		last        *history.Run
+		lastSuccess *history.Run
+	}
+	workflows := make(map[string]*workflowState)
+	var order []string
+	var lastBackup *history.Run
+
+	for _, r := range runs {
+		if r.DryRun || r.Status == history.StatusRunning {
+			continue
+		}
+		name := Workflow(r.Command)
+		w, ok := workflows[name]
+		if !ok {
+			w = &workflowState{}
+			workflows[name] = w
+			order = append(order, name)
+		}
+		w.last = r
+		if r.Status == history.StatusSuccess {
+			w.lastSuccess = r
+		}
+		if r.Backup != nil {
+			lastBackup = r
+		}
+	}
+
+	for _, name := range order {
+		w := workflows[name]
+		success := 0.0
+		if w.last.Status == history.StatusSuccess {
+			success = 1
+		}
+		s.Add("last_run_timestamp_seconds", "Time the last run of the workflow finished.", Gauge,
+			unixSeconds(w.last.End), "workflow", name)
+		s.Add("last_run_success", "Whether the last run of the workflow succeeded (1) or failed (0).", Gauge,
+			success, "workflow", name)
+		s.Add("last_run_duration_seconds", "Duration of the last run of the workflow.", Gauge,
+			w.last.Duration.Seconds(), "workflow", name)
+		if w.lastSuccess != nil {
+			s.Add("last_success_timestamp_seconds", "Time the last successful run of the workflow finished.", Gauge,
+				unixSeconds(w.lastSuccess.End), "workflow", name)
+		}
+	}
+
+	if lastBackup == nil {
+		return
+	}
+	b := lastBackup.Backup
+	for _, step := range lastBackup.Steps {
+		if step.Name == "backup" {
+			s.Add("backup_duration_seconds", "Duration of the last backup.", Gauge, step.Duration.Seconds())
+			s.Add("backup_timestamp_seconds", "Time the last backup finished.", Gauge,
+				unixSeconds(step.Start.Add(step.Duration)))
+			break
+		}
+	}
+	s.Add("backup_added_bytes", "Bytes added to the repository by the last backup.", Gauge, float64(b.DataAdded))
+	s.Add("backup_added_packed_bytes", "Bytes stored after compression by the last backup.", Gauge, float64(b.DataAddedPacked))
+	s.Add("backup_processed_files", "Files processed by the last backup.", Gauge, float64(b.TotalFilesProcessed))
+	s.Add("backup_processed_bytes", "Bytes processed by the last backup.", Gauge, float64(b.TotalBytesProcessed))
+	s.Add("backup_files", "Files seen by the last backup by state.", Gauge, float64(b.FilesNew), "state", "new")
+	s.Add("backup_files", "Files seen by the last backup by state.", Gauge, float64(b.FilesChanged), "state", "changed")
+	s.Add("backup_files", "Files seen by the last backup by state.", Gauge, float64(b.FilesUnmodified), "state", "unmodified")
+}
+
+// AddBackends adds repository metrics for each backend
+func AddBackends(s *Set, backends []*status.BackendStatus, now time.Time) {
+	for _, b := range backends {
+		up := 0.0
+		if b.Error == "" {
+			up = 1
+		}
+		s.Add("repository_up", "Whether the repository could be queried.", Gauge, up, "backend", b.Name)
+		if b.Error != "" {
+			continue
+		}
+
+		s.Add("snapshots", "Number of snapshots in the repository.", Gauge, float64(b.SnapshotCount), "backend", b.Name)
+		for _, h := range b.Hosts {
+			s.Add("latest_snapshot_timestamp_seconds", "Time of the latest snapshot per host.", Gauge,
+				unixSeconds(h.Latest), "backend", b.Name, "host", h.Hostname)
+			s.Add("latest_snapshot_age_seconds", "Age of the latest snapshot per host.", Gauge,
+				now.Sub(h.Latest).Seconds(), "backend", b.Name, "host", h.Hostname)
+		}
+
+		s.Add("locks", "Number of locks in the repository.", Gauge, float64(len(b.Locks)), "backend", b.Name)
+
+		if b.SizeKnown {
+			s.Add("repository_size_bytes", "Raw data size of the repository.", Gauge, float64(b.Size), "backend", b.Name)
+		}
+
+		if !b.LastDeepCheck.IsZero() {
+			s.Add("deep_check_timestamp_seconds", "Time of the last deep check.", Gauge,
+				unixSeconds(b.LastDeepCheck), "backend", b.Name)
+			s.Add("days_since_deep_check", "Days since the last deep check.", Gauge,
+				now.Sub(b.LastDeepCheck).Hours()/24, "backend", b.Name)
+		}
+	}
+}
+
+// unixSeconds converts a time to fractional Unix seconds
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CollectFunc gathers a fresh metric set
+type CollectFunc func() (*Set, error)
+
+// Handler serves metrics over HTTP. Collection queries every repository, so
+// results are cached for ttl; concurrent scrapes share one collection.
+type Handler struct {
+	collect CollectFunc
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	cached    *Set
+	collected time.Time
+}
+
+// NewHandler creates a handler caching collected metrics for ttl
+func NewHandler(collect CollectFunc, ttl time.Duration) *Handler {
+	return &Handler{collect: collect, ttl: ttl}
+}
+
+// ServeHTTP writes the current metrics in the text exposition format
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	set, err := h.get()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = set.WriteTo(w)
+}
+
+// get returns the cached set or collects a new one when it has expired
+func (h *Handler) get() (*Set, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.collected) < h.ttl {
+		return h.cached, nil
+	}
+
+	start := time.Now()
+	set, err := h.collect()
+	if err != nil {
+		return nil, err
+	}
+	set.Add("collect_duration_seconds", "Time spent collecting metrics.", Gauge, time.Since(start).Seconds())
+
+	h.cached = set
+	h.collected = time.Now()
+	return set, nil
+}
+
+// NewServeMux returns a mux serving the handler on /metrics
+func NewServeMux(h http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", h)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>resticm</title></head><body><h1>resticm</h1><p><a href="/metrics">Metrics</a></p></body></html>`))
+	})
+	return mux
+}
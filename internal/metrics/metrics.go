@@ -0,0 +1,177 @@
+// Package metrics exports resticm metrics in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "resticm"
+
+// Metric types
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// Sample is a single value with its labels
+type Sample struct {
+	Labels [][2]string
+	Value  float64
+}
+
+// Family groups the samples of one metric name
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Set is an ordered collection of metric families
+type Set struct {
+	families map[string]*Family
+	order    []string
+}
+
+// NewSet creates an empty metric set
+func NewSet() *Set {
+	return &Set{families: make(map[string]*Family)}
+}
+
+// Add records a sample. name is prefixed with the namespace and labels are
+// given as key/value pairs.
+func (s *Set) Add(name, help, typ string, value float64, labels ...string) {
+	full := Namespace + "_" + name
+	f, ok := s.families[full]
+	if !ok {
+		f = &Family{Name: full, Help: help, Type: typ}
+		s.families[full] = f
+		s.order = append(s.order, full)
+	}
+
+	sample := Sample{Value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		sample.Labels = append(sample.Labels, [2]string{labels[i], labels[i+1]})
+	}
+	f.Samples = append(f.Samples, sample)
+}
+
+// Families returns the families in insertion order
+func (s *Set) Families() []*Family {
+	out := make([]*Family, 0, len(s.order))
+	for _, name := range s.order {
+		out = append(out, s.families[name])
+	}
+	return out
+}
+
+// Get returns the family with the given name (without namespace), or nil
+func (s *Set) Get(name string) *Family {
+	return s.families[Namespace+"_"+name]
+}
+
+// WriteTo writes the set in the Prometheus text exposition format
+func (s *Set) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+
+	for _, f := range s.Families() {
+		m, _ := fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.Name, escapeHelp(f.Help), f.Name, f.Type)
+		n += int64(m)
+
+		samples := append([]Sample(nil), f.Samples...)
+		sort.SliceStable(samples, func(i, j int) bool {
+			return labelString(samples[i].Labels) < labelString(samples[j].Labels)
+		})
+		for _, sample := range samples {
+			m, _ = fmt.Fprintf(bw, "%s%s %s\n", f.Name, labelString(sample.Labels), formatValue(sample.Value))
+			n += int64(m)
+		}
+	}
+
+	return n, bw.Flush()
+}
+
+// String returns the set in the text exposition format
+func (s *Set) String() string {
+	var b strings.Builder
+	_, _ = s.WriteTo(&b)
+	return b.String()
+}
+
+// WriteTextfile writes the set to path atomically, so the node_exporter
+// textfile collector never reads a partial file
+func WriteTextfile(path string, s *Set) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	// The temporary file must not end in .prom or the collector may pick it up
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := s.WriteTo(tmp); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+	return nil
+}
+
+// labelString formats labels as {k="v",...}
+func labelString(labels [][2]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf("%s=\"%s\"", l[0], escapeLabel(l[1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escapeLabel escapes a label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes a HELP docstring
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// formatValue formats a sample value
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
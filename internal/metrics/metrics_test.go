@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"resticm/internal/history"
+	"resticm/internal/restic"
+	"resticm/internal/status"
+)
+
+func TestSetWriteTo(t *testing.T) {
+	s := NewSet()
+	s.Add("snapshots", "Number of snapshots.", Gauge, 3, "backend", "primary")
+	s.Add("snapshots", "Number of snapshots.", Gauge, 5, "backend", "b2")
+	s.Add("up", "Up.", Gauge, 1, "path", "a \"quoted\"\\path")
+
+	want := `# HELP resticm_snapshots Number of snapshots.
+# TYPE resticm_snapshots gauge
+resticm_snapshots{backend="b2"} 5
+resticm_snapshots{backend="primary"} 3
+# HELP resticm_up Up.
+# TYPE resticm_up gauge
+resticm_up{path="a \"quoted\"\\path"} 1
+`
+	if got := s.String(); got != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "collector", "resticm.prom")
+
+	s := NewSet()
+	s.Add("up", "Up.", Gauge, 1)
+	if err := WriteTextfile(path, s); err != nil {
+		t.Fatalf("WriteTextfile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "resticm_up 1\n") {
+		t.Errorf("textfile content = %q", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the metrics file, found %d entries", len(entries))
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	tests := map[string]string{
+		"resticm":        "default",
+		"resticm backup": "backup",
+		"resticm full":   "full",
+	}
+	for command, want := range tests {
+		if got := Workflow(command); got != want {
+			t.Errorf("Workflow(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestAddRuns(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+
+	ok := history.NewRun("1", "resticm backup", nil)
+	ok.AddStep("backup", "primary", start, nil)
+	ok.SetBackup(&restic.BackupSummary{DataAdded: 1024, TotalFilesProcessed: 10, FilesNew: 2})
+	ok.Finish(nil)
+
+	failed := history.NewRun("2", "resticm backup", nil)
+	failed.Finish(errors.New("boom"))
+
+	dry := history.NewRun("3", "resticm full", nil)
+	dry.DryRun = true
+	dry.Finish(nil)
+
+	s := NewSet()
+	AddRuns(s, []*history.Run{ok, failed, dry})
+
+	out := s.String()
+	for _, want := range []string{
+		`resticm_last_run_success{workflow="backup"} 0`,
+		`resticm_backup_added_bytes 1024`,
+		`resticm_backup_processed_files 10`,
+		`resticm_backup_files{state="new"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `workflow="full"`) {
+		t.Errorf("dry runs should be ignored:\n%s", out)
+	}
+	if s.Get("last_success_timestamp_seconds") == nil {
+		t.Error("last_success_timestamp_seconds missing")
+	}
+}
+
+func TestAddBackends(t *testing.T) {
+	now := time.Now()
+	backends := []*status.BackendStatus{
+		{
+			Name:          "primary",
+			SnapshotCount: 4,
+			Hosts:         []status.HostSnapshot{{Hostname: "web1", Latest: now.Add(-2 * time.Hour)}},
+			Locks:         []status.Lock{{Hostname: "web1"}},
+			SizeKnown:     true,
+			Size:          2048,
+			LastDeepCheck: now.Add(-48 * time.Hour),
+		},
+		{Name: "offsite", Error: "unreachable"},
+	}
+
+	s := NewSet()
+	AddBackends(s, backends, now)
+
+	out := s.String()
+	for _, want := range []string{
+		`resticm_repository_up{backend="offsite"} 0`,
+		`resticm_repository_up{backend="primary"} 1`,
+		`resticm_snapshots{backend="primary"} 4`,
+		`resticm_latest_snapshot_age_seconds{backend="primary",host="web1"} 7200`,
+		`resticm_locks{backend="primary"} 1`,
+		`resticm_repository_size_bytes{backend="primary"} 2048`,
+		`resticm_days_since_deep_check{backend="primary"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `resticm_snapshots{backend="offsite"}`) {
+		t.Errorf("unreachable backend should only report repository_up:\n%s", out)
+	}
+}
+
+func TestHandlerCaches(t *testing.T) {
+	calls := 0
+	h := NewHandler(func() (*Set, error) {
+		calls++
+		s := NewSet()
+		s.Add("up", "Up.", Gauge, 1)
+		return s, nil
+	}, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		if !strings.Contains(rec.Body.String(), "resticm_up 1") {
+			t.Fatalf("body = %q", rec.Body.String())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("collect called %d times, want 1", calls)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	h := NewHandler(func() (*Set, error) { return nil, errors.New("boom") }, time.Minute)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 500 {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"resticm/internal/redact"
 )
 
 // Provider represents a notification provider
@@ -132,6 +134,12 @@ func (n *Notifier) NotifyError(title, body string, err error, details map[string
 }
 
 func (n *Notifier) send(msg *Message) error {
+	msg.Title = redact.String(msg.Title)
+	msg.Body = redact.String(msg.Body)
+	for k, v := range msg.Details {
+		msg.Details[k] = redact.String(v)
+	}
+
 	var lastErr error
 	for _, provider := range n.providers {
 		if err := provider.Send(msg); err != nil {
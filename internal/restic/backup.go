@@ -1,7 +1,17 @@
 package restic
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"resticm/internal/redact"
 )
 
 // BackupOptions contains options for the backup operation
@@ -12,29 +22,156 @@ type BackupOptions struct {
 	ExcludeFile     string
 	ExtraArgs       []string
 	Hostname        string
+
+	// StdinFilename names the file holding the data read from standard input
+	// by BackupStdin or produced by StdinCommand; Directories and excludes are
+	// ignored then
+	StdinFilename string
+
+	// StdinCommand backs up the standard output of this command (restic
+	// --stdin-from-command, restic 0.17+). If it exits non-zero, restic fails
+	// and saves no snapshot.
+	StdinCommand []string
+}
+
+// BackupSummary holds the statistics restic prints at the end of a backup
+type BackupSummary struct {
+	SnapshotID          string `json:"snapshot_id,omitempty"`
+	FilesNew            int    `json:"files_new"`
+	FilesChanged        int    `json:"files_changed"`
+	FilesUnmodified     int    `json:"files_unmodified"`
+	DirsNew             int    `json:"dirs_new"`
+	DirsChanged         int    `json:"dirs_changed"`
+	DirsUnmodified      int    `json:"dirs_unmodified"`
+	DataAdded           int64  `json:"data_added"`
+	DataAddedPacked     int64  `json:"data_added_packed"`
+	TotalFilesProcessed int    `json:"total_files_processed"`
+	TotalBytesProcessed int64  `json:"total_bytes_processed"`
 }
 
-// Backup performs a backup operation
-func (e *Executor) Backup(opts BackupOptions) error {
+// Backup performs a backup operation and returns the summary parsed from restic's output
+func (e *Executor) Backup(opts BackupOptions) (*BackupSummary, error) {
+	return e.BackupContext(context.Background(), opts)
+}
+
+// BackupContext performs a backup operation like Backup, interrupting restic
+// when ctx is done
+func (e *Executor) BackupContext(ctx context.Context, opts BackupOptions) (*BackupSummary, error) {
+	parser, restore := e.parseBackupSummary()
+	defer restore()
+
+	err := e.RunContext(ctx, e.backupArgs(opts)...)
+	return parser.result(), err
+}
+
+// BackupStdin backs up the data read from r as a single file named
+// opts.StdinFilename. If reading r fails, restic is killed before it sees the
+// end of the input, so no snapshot of incomplete data is saved.
+func (e *Executor) BackupStdin(opts BackupOptions, r io.Reader) (*BackupSummary, error) {
+	parser, restore := e.parseBackupSummary()
+	defer restore()
+
+	args := e.backupArgs(opts)
+	cmd := e.command(args...)
+	cmd.Env = e.buildEnv()
+	if e.Stdout != nil {
+		stdout := redact.NewWriter(e.Stdout)
+		defer func() { _ = stdout.Flush() }()
+		cmd.Stdout = stdout
+	}
+	if e.Stderr != nil {
+		stderr := redact.NewWriter(e.Stderr)
+		defer func() { _ = stderr.Flush() }()
+		cmd.Stderr = stderr
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Verbose {
+		fmt.Printf("$ restic %s\n", redact.String(strings.Join(args, " ")))
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	src := &readErrorRecorder{r: r}
+	_, _ = io.Copy(stdin, src)
+	if src.err != nil {
+		// Abort before closing stdin: restic saves a snapshot once it reads EOF
+		_ = cmd.Process.Kill()
+		_ = stdin.Close()
+		_ = cmd.Wait()
+		return nil, src.err
+	}
+	_ = stdin.Close()
+
+	err = describeExit(cmd.Wait())
+	return parser.result(), err
+}
+
+// readErrorRecorder remembers the first read error other than io.EOF
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+// Read reads from the underlying reader
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// parseBackupSummary tees stdout through a summary parser until restore is called
+func (e *Executor) parseBackupSummary() (*backupSummaryParser, func()) {
+	parser := &backupSummaryParser{}
+	stdout := e.Stdout
+	if stdout != nil {
+		e.Stdout = io.MultiWriter(stdout, parser)
+	} else {
+		e.Stdout = parser
+	}
+	return parser, func() { e.Stdout = stdout }
+}
+
+// backupArgs builds the restic backup arguments
+func (e *Executor) backupArgs(opts BackupOptions) []string {
 	args := []string{"backup"}
 
-	// Add directories
-	args = append(args, opts.Directories...)
+	switch {
+	case len(opts.StdinCommand) > 0:
+		args = append(args, "--stdin-from-command")
+		if opts.StdinFilename != "" {
+			args = append(args, "--stdin-filename", opts.StdinFilename)
+		}
+	case opts.StdinFilename != "":
+		args = append(args, "--stdin", "--stdin-filename", opts.StdinFilename)
+	default:
+		// Add directories
+		args = append(args, opts.Directories...)
+	}
 
 	// Add tags
 	for _, tag := range opts.Tags {
 		args = append(args, "--tag", tag)
 	}
 
-	// Add exclude patterns
-	for _, pattern := range opts.ExcludePatterns {
-		args = append(args, "--exclude", pattern)
-	}
+	if opts.StdinFilename == "" && len(opts.StdinCommand) == 0 {
+		// Add exclude patterns
+		for _, pattern := range opts.ExcludePatterns {
+			args = append(args, "--exclude", pattern)
+		}
 
-	// Add exclude file
-	if opts.ExcludeFile != "" {
-		if _, err := os.Stat(opts.ExcludeFile); err == nil {
-			args = append(args, "--exclude-file", opts.ExcludeFile)
+		// Add exclude file
+		if opts.ExcludeFile != "" {
+			if _, err := os.Stat(opts.ExcludeFile); err == nil {
+				args = append(args, "--exclude-file", opts.ExcludeFile)
+			}
 		}
 	}
 
@@ -51,5 +188,126 @@ func (e *Executor) Backup(opts BackupOptions) error {
 		args = append(args, "--dry-run")
 	}
 
-	return e.Run(args...)
+	// The command comes last so its arguments are not parsed by restic
+	if len(opts.StdinCommand) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.StdinCommand...)
+	}
+
+	return args
+}
+
+var (
+	summaryCountsRe    = regexp.MustCompile(`^(Files|Dirs):\s+(\d+) new,\s+(\d+) changed,\s+(\d+) unmodified`)
+	summaryAddedRe     = regexp.MustCompile(`^(?:Would add|Added) to the (?:repository|repo):\s+([\d.]+ [KMGTP]?i?B)(?:\s+\(([\d.]+ [KMGTP]?i?B) stored\))?`)
+	summaryProcessedRe = regexp.MustCompile(`^processed (\d+) files, ([\d.]+ [KMGTP]?i?B)`)
+	summarySnapshotRe  = regexp.MustCompile(`^snapshot ([0-9a-f]+) saved`)
+)
+
+// backupSummaryParser scans restic backup output line by line
+type backupSummaryParser struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	summary BackupSummary
+	found   bool
+}
+
+// Write buffers output and parses every complete line
+func (p *backupSummaryParser) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf.Write(data)
+	for {
+		line, err := p.buf.ReadString('\n')
+		if err != nil {
+			// Incomplete line, keep it for the next write
+			p.buf.Reset()
+			p.buf.WriteString(line)
+			break
+		}
+		p.parseLine(line)
+	}
+	return len(data), nil
+}
+
+// parseLine extracts summary fields from a single output line
+func (p *backupSummaryParser) parseLine(line string) {
+	line = strings.TrimSpace(line)
+
+	if m := summaryCountsRe.FindStringSubmatch(line); m != nil {
+		n, c, u := atoi(m[2]), atoi(m[3]), atoi(m[4])
+		if m[1] == "Files" {
+			p.summary.FilesNew, p.summary.FilesChanged, p.summary.FilesUnmodified = n, c, u
+		} else {
+			p.summary.DirsNew, p.summary.DirsChanged, p.summary.DirsUnmodified = n, c, u
+		}
+		p.found = true
+	} else if m := summaryAddedRe.FindStringSubmatch(line); m != nil {
+		p.summary.DataAdded = ParseSize(m[1])
+		if m[2] != "" {
+			p.summary.DataAddedPacked = ParseSize(m[2])
+		}
+		p.found = true
+	} else if m := summaryProcessedRe.FindStringSubmatch(line); m != nil {
+		p.summary.TotalFilesProcessed = atoi(m[1])
+		p.summary.TotalBytesProcessed = ParseSize(m[2])
+		p.found = true
+	} else if m := summarySnapshotRe.FindStringSubmatch(line); m != nil {
+		p.summary.SnapshotID = m[1]
+		p.found = true
+	}
+}
+
+// result returns the parsed summary, or nil if restic printed none
+func (p *backupSummaryParser) result() *BackupSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.buf.Len() > 0 {
+		p.parseLine(p.buf.String())
+		p.buf.Reset()
+	}
+	if !p.found {
+		return nil
+	}
+	summary := p.summary
+	return &summary
+}
+
+// ParseSize converts a human-readable restic size such as "1.234 GiB" to bytes
+func ParseSize(s string) int64 {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+
+	multipliers := map[string]float64{
+		"B":   1,
+		"KiB": 1 << 10,
+		"MiB": 1 << 20,
+		"GiB": 1 << 30,
+		"TiB": 1 << 40,
+		"PiB": 1 << 50,
+		"KB":  1e3,
+		"MB":  1e6,
+		"GB":  1e9,
+		"TB":  1e12,
+		"PB":  1e15,
+	}
+	m, ok := multipliers[fields[1]]
+	if !ok {
+		return 0
+	}
+	return int64(value * m)
+}
+
+// atoi converts a string to int, returning 0 on error
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
 }
@@ -3,6 +3,9 @@ package restic
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -12,33 +15,102 @@ type CopyOptions struct {
 	FromPassword           string
 	FromAWSAccessKeyID     string
 	FromAWSSecretAccessKey string
+	FromEnv                map[string]string // Environment of the source repository
+	FromOptions            []string          // Extended options of the source repository (-o)
 	ToRepository           string
 	ToPassword             string
 	ToAWSAccessKeyID       string
 	ToAWSSecretAccessKey   string
 	Hostname               string
+	Tags                   []string // Comma separated tag lists, as restic --tag
+	Paths                  []string
 	SnapshotIDs            []string
 }
 
-// Copy copies snapshots from source to destination repository
+// Copy copies snapshots from source to destination repository. The executor
+// is the destination; its environment is left unchanged.
+//
+// restic copy runs with a single environment for both repositories. When the
+// two sides need different values of the same variable, such as two S3
+// buckets of different accounts, both are reached through a temporary rclone
+// config with one remote per account.
 func (e *Executor) Copy(opts CopyOptions) error {
-	// Check for cross-account S3 copy (both S3 with different credentials)
-	if isCrossAccountS3(opts.FromRepository, opts.ToRepository, opts.FromAWSAccessKeyID, opts.ToAWSAccessKeyID) {
-		return &CrossAccountS3Error{
-			From: opts.FromRepository,
-			To:   opts.ToRepository,
+	run := e.clone()
+	fromRepo := opts.FromRepository
+
+	// Environment of the source repository
+	source := make(map[string]string, len(opts.FromEnv)+2)
+	for k, v := range opts.FromEnv {
+		source[k] = v
+	}
+	fromKey, fromSecret := "", ""
+	if isS3Repository(opts.FromRepository) {
+		fromKey, fromSecret = opts.FromAWSAccessKeyID, opts.FromAWSSecretAccessKey
+		if fromKey != "" {
+			source["AWS_ACCESS_KEY_ID"] = fromKey
+		}
+		if fromSecret != "" {
+			source["AWS_SECRET_ACCESS_KEY"] = fromSecret
+		}
+	}
+	conflicts := envConflicts(source, run.Env)
+
+	from := newS3Account(opts.FromRepository, fromKey, fromSecret, opts.FromEnv)
+	to := newS3Account(opts.ToRepository, opts.ToAWSAccessKeyID, opts.ToAWSSecretAccessKey, e.Env)
+
+	switch {
+	case isCrossAccountS3(from, to, conflicts):
+		if _, err := exec.LookPath("rclone"); err != nil {
+			return &CrossAccountS3Error{
+				From: opts.FromRepository,
+				To:   opts.ToRepository,
+			}
+		}
+		rcloneConfig, err := writeRcloneConfig(from, to)
+		if err != nil {
+			return fmt.Errorf("failed to create temp rclone config: %w", err)
+		}
+		defer func() { _ = os.Remove(rcloneConfig) }()
+
+		fromRepo = from.rcloneRepository(rcloneFromRemote)
+		run.Repository = to.rcloneRepository(rcloneToRemote)
+		// The credentials of both accounts are in the rclone config
+		for k := range run.Env {
+			if strings.HasPrefix(k, "AWS_") {
+				delete(run.Env, k)
+			}
+		}
+		run.SetEnv(map[string]string{"RCLONE_CONFIG": rcloneConfig})
+		if !slices.Contains(run.isolated, "AWS_") {
+			run.isolated = append(run.isolated, "AWS_")
+		}
+	case len(conflicts) > 0:
+		return fmt.Errorf("source and destination need different values of %s, but restic copy uses one environment for both; use an rclone remote for one of them",
+			strings.Join(conflicts, ", "))
+	default:
+		run.SetEnv(opts.FromEnv)
+		run.SetAWSCredentials(fromKey, fromSecret)
+	}
+
+	// restic applies -o options to both repositories: add those of the source,
+	// which must not need another value of an option of the destination
+	if conflicts := optionConflicts(opts.FromOptions, run.Options); len(conflicts) > 0 {
+		return fmt.Errorf("source and destination need different values of the extended option %s, but restic copy uses the same options for both",
+			strings.Join(conflicts, ", "))
+	}
+	for _, opt := range opts.FromOptions {
+		if !slices.Contains(run.Options, opt) {
+			run.Options = append(run.Options, opt)
 		}
 	}
 
 	args := []string{"copy"}
 
 	// Add source repository
-	args = append(args, "--from-repo", opts.FromRepository)
+	args = append(args, "--from-repo", fromRepo)
 
-	// Add hostname filter
-	if opts.Hostname != "" {
-		args = append(args, "--host", opts.Hostname)
-	}
+	// Add hostname, tag and path filters
+	args = append(args, SnapshotFilter{Tags: opts.Tags, Host: opts.Hostname, Paths: opts.Paths}.args()...)
 
 	// Add specific snapshots if specified
 	args = append(args, opts.SnapshotIDs...)
@@ -53,31 +125,38 @@ func (e *Executor) Copy(opts CopyOptions) error {
 		args = append(args, "--from-password-file", tmpFile)
 	}
 
-	// Handle AWS credentials based on repository types
-	fromIsS3 := strings.HasPrefix(opts.FromRepository, "s3:")
-	toIsS3 := strings.HasPrefix(opts.ToRepository, "s3:")
+	return run.Run(args...)
+}
 
-	if fromIsS3 && toIsS3 {
-		// Both S3 - they must use the same credentials (checked above)
-		// Use source credentials
-		if opts.FromAWSAccessKeyID != "" {
-			e.Env["AWS_ACCESS_KEY_ID"] = opts.FromAWSAccessKeyID
-		}
-		if opts.FromAWSSecretAccessKey != "" {
-			e.Env["AWS_SECRET_ACCESS_KEY"] = opts.FromAWSSecretAccessKey
-		}
-	} else if fromIsS3 {
-		// Only source is S3 - use source credentials
-		if opts.FromAWSAccessKeyID != "" {
-			e.Env["AWS_ACCESS_KEY_ID"] = opts.FromAWSAccessKeyID
-		}
-		if opts.FromAWSSecretAccessKey != "" {
-			e.Env["AWS_SECRET_ACCESS_KEY"] = opts.FromAWSSecretAccessKey
+// envConflicts returns the sorted names set to different values in a and b
+func envConflicts(a, b map[string]string) []string {
+	var conflicts []string
+	for k, v := range a {
+		if other, ok := b[k]; ok && other != v {
+			conflicts = append(conflicts, k)
 		}
 	}
-	// Note: If only destination is S3, executor already has the credentials
+	sort.Strings(conflicts)
+	return conflicts
+}
 
-	return e.Run(args...)
+// optionConflicts returns the sorted keys of the extended options (key=value)
+// set to different values in a and b
+func optionConflicts(a, b []string) []string {
+	values := make(map[string]string, len(b))
+	for _, opt := range b {
+		k, v, _ := strings.Cut(opt, "=")
+		values[k] = v
+	}
+	var conflicts []string
+	for _, opt := range a {
+		k, v, _ := strings.Cut(opt, "=")
+		if other, ok := values[k]; ok && other != v && !slices.Contains(conflicts, k) {
+			conflicts = append(conflicts, k)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
 }
 
 // createTempPasswordFile creates a temporary file with the password
@@ -110,22 +189,30 @@ Source:      %s
 Destination: %s
 
 Restic's copy command cannot handle S3 buckets with different credentials.
+resticm copies between them through rclone, generating a temporary rclone
+remote for each account, but rclone was not found in PATH.
 
-💡 Solution: Use rclone as an intermediary:
-   1. Configure rclone with both S3 accounts
-   2. Use 'rclone:remote:bucket/path' as repository URL
-   3. Restic will use rclone for the copy operation
+💡 Solution: Install rclone (https://rclone.org/install/)
 
 See: https://restic.readthedocs.io/en/latest/030_preparing_a_new_repo.html#other-services-via-rclone
 `, e.From, e.To)
 }
 
-// isCrossAccountS3 checks if the copy is between S3 buckets with different credentials
-func isCrossAccountS3(fromRepo, toRepo, fromKey, toKey string) bool {
-	if !isS3Repository(fromRepo) || !isS3Repository(toRepo) {
+// isCrossAccountS3 checks if the copy is between S3 buckets of different
+// accounts, or whose AWS settings otherwise conflict
+func isCrossAccountS3(from, to *s3Account, conflicts []string) bool {
+	if from == nil || to == nil {
 		return false
 	}
-	return fromKey != "" && toKey != "" && fromKey != toKey
+	if from.identity() != "" && to.identity() != "" && from.identity() != to.identity() {
+		return true
+	}
+	for _, k := range conflicts {
+		if strings.HasPrefix(k, "AWS_") {
+			return true
+		}
+	}
+	return false
 }
 
 // isS3Repository checks if a repository URL is an S3 repository
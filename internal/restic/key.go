@@ -0,0 +1,83 @@
+package restic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Key is a key of a restic repository: one of the passwords opening it
+type Key struct {
+	ID       string `json:"id"`
+	UserName string `json:"userName"`
+	HostName string `json:"hostName"`
+	Created  string `json:"created"` // Local time, as printed by restic
+	Current  bool   `json:"current"` // The key opened with the executor password
+}
+
+// KeyOptions contains options for adding a key
+type KeyOptions struct {
+	User string // Username recorded in the key (default: current user)
+	Host string // Hostname recorded in the key (default: current host)
+}
+
+// ListKeys returns the keys of the repository
+func (e *Executor) ListKeys() ([]Key, error) {
+	output, err := e.RunWithOutput("key", "list", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse key list: %w", err)
+	}
+	return keys, nil
+}
+
+// CurrentKey returns the key opened with the executor password
+func (e *Executor) CurrentKey() (*Key, error) {
+	keys, err := e.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if keys[i].Current {
+			return &keys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("current key not found in key list")
+}
+
+// AddKey adds a key with the given password to the repository. The password
+// is passed through a temporary file, never on the command line.
+func (e *Executor) AddKey(password string, opts KeyOptions) error {
+	tmpFile, err := createTempPasswordFile(password)
+	if err != nil {
+		return fmt.Errorf("failed to create temp password file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	args := []string{"key", "add", "--new-password-file", tmpFile}
+	if opts.User != "" {
+		args = append(args, "--user", opts.User)
+	}
+	if opts.Host != "" {
+		args = append(args, "--host", opts.Host)
+	}
+	return e.Run(args...)
+}
+
+// RemoveKey removes a key by ID; restic refuses to remove the current key
+func (e *Executor) RemoveKey(id string) error {
+	return e.Run("key", "remove", id)
+}
+
+// WithPassword returns a copy of the executor opening the repository with
+// another password
+func (e *Executor) WithPassword(password string) *Executor {
+	c := e.clone()
+	c.Password = password
+	return c
+}
@@ -0,0 +1,154 @@
+package restic
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Names of the rclone remotes of a cross-account copy
+const (
+	rcloneFromRemote = "resticm-from"
+	rcloneToRemote   = "resticm-to"
+)
+
+// s3Account is an S3 repository with the credentials to reach it
+type s3Account struct {
+	Endpoint string // Host, with the scheme when the URL has one
+	Path     string // Bucket and prefix
+
+	KeyID           string
+	Secret          string
+	SessionToken    string
+	Profile         string
+	CredentialsFile string
+	Region          string
+}
+
+// newS3Account parses an s3: repository URL and collects its credentials
+// from the explicit keys and the repository environment. It returns nil for
+// other repositories.
+func newS3Account(repository, keyID, secret string, env map[string]string) *s3Account {
+	if !isS3Repository(repository) {
+		return nil
+	}
+	rest := strings.TrimPrefix(repository, "s3:")
+	scheme := ""
+	if i := strings.Index(rest, "://"); i >= 0 {
+		scheme, rest = rest[:i+3], rest[i+3:]
+	}
+	host, path, _ := strings.Cut(rest, "/")
+
+	a := &s3Account{
+		Endpoint:        scheme + host,
+		Path:            path,
+		KeyID:           firstNonEmpty(keyID, env["AWS_ACCESS_KEY_ID"]),
+		Secret:          firstNonEmpty(secret, env["AWS_SECRET_ACCESS_KEY"]),
+		SessionToken:    env["AWS_SESSION_TOKEN"],
+		Profile:         env["AWS_PROFILE"],
+		CredentialsFile: env["AWS_SHARED_CREDENTIALS_FILE"],
+		Region:          firstNonEmpty(env["AWS_DEFAULT_REGION"], env["AWS_REGION"], awsRegion(host)),
+	}
+	return a
+}
+
+// identity identifies the account: its access key, else its profile. It is
+// empty when the credentials come from the inherited environment.
+func (a *s3Account) identity() string {
+	if a.KeyID != "" {
+		return "key:" + a.KeyID
+	}
+	if a.Profile != "" {
+		return "profile:" + a.Profile
+	}
+	return ""
+}
+
+// isAWS reports whether the endpoint is Amazon S3
+func (a *s3Account) isAWS() bool {
+	host := a.Endpoint
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	return strings.HasSuffix(host, ".amazonaws.com")
+}
+
+// rcloneRemote returns the rclone config section defining the account
+func (a *s3Account) rcloneRemote(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\ntype = s3\n", name)
+	if a.isAWS() {
+		b.WriteString("provider = AWS\n")
+	} else {
+		b.WriteString("provider = Other\n")
+	}
+	if a.KeyID != "" {
+		b.WriteString("env_auth = false\n")
+		fmt.Fprintf(&b, "access_key_id = %s\n", a.KeyID)
+		fmt.Fprintf(&b, "secret_access_key = %s\n", a.Secret)
+		if a.SessionToken != "" {
+			fmt.Fprintf(&b, "session_token = %s\n", a.SessionToken)
+		}
+	} else {
+		// Profile or instance credentials, resolved by rclone
+		b.WriteString("env_auth = true\n")
+		if a.Profile != "" {
+			fmt.Fprintf(&b, "profile = %s\n", a.Profile)
+		}
+		if a.CredentialsFile != "" {
+			fmt.Fprintf(&b, "shared_credentials_file = %s\n", a.CredentialsFile)
+		}
+	}
+	if a.Region != "" {
+		fmt.Fprintf(&b, "region = %s\n", a.Region)
+	}
+	fmt.Fprintf(&b, "endpoint = %s\n\n", a.Endpoint)
+	return b.String()
+}
+
+// rcloneRepository returns the repository URL through the rclone remote
+func (a *s3Account) rcloneRepository(remote string) string {
+	return "rclone:" + remote + ":" + a.Path
+}
+
+// writeRcloneConfig writes a temporary rclone config, readable by the owner
+// only, defining the source and destination remotes of a copy
+func writeRcloneConfig(from, to *s3Account) (string, error) {
+	tmpFile, err := os.CreateTemp("", "resticm-rclone-*.conf")
+	if err != nil {
+		return "", err
+	}
+	config := from.rcloneRemote(rcloneFromRemote) + to.rcloneRemote(rcloneToRemote)
+	if _, err := tmpFile.WriteString(config); err != nil {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+		return "", err
+	}
+	_ = tmpFile.Close()
+	return tmpFile.Name(), nil
+}
+
+// awsRegion returns the region of an Amazon S3 host
+// (s3.eu-west-1.amazonaws.com, s3-eu-west-1.amazonaws.com), if any
+func awsRegion(host string) string {
+	name, ok := strings.CutSuffix(host, ".amazonaws.com")
+	if !ok {
+		return ""
+	}
+	parts := strings.Split(name, ".")
+	region := strings.TrimPrefix(parts[len(parts)-1], "s3-")
+	if region == "s3" {
+		return ""
+	}
+	return region
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package restic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"resticm/internal/redact"
+)
+
+// SnapshotFilter selects snapshots, e.g. the one "latest" refers to
+type SnapshotFilter struct {
+	Tags  []string // Comma separated lists; a snapshot needs all tags of one list
+	Host  string
+	Paths []string // Snapshots must include all these paths
+
+	// Since and Until bound the snapshot time. restic has no such option, so
+	// they are only applied by Match.
+	Since time.Time
+	Until time.Time
+}
+
+// args returns the restic filter arguments
+func (f SnapshotFilter) args() []string {
+	var args []string
+	for _, tag := range f.Tags {
+		args = append(args, "--tag", tag)
+	}
+	if f.Host != "" {
+		args = append(args, "--host", f.Host)
+	}
+	for _, path := range f.Paths {
+		args = append(args, "--path", path)
+	}
+	return args
+}
+
+// RestoreOptions contains options for the restore operation
+type RestoreOptions struct {
+	Target   string
+	Includes []string
+	Filter   SnapshotFilter
+}
+
+// Restore restores a snapshot ("latest" selects the newest matching the filter)
+func (e *Executor) Restore(snapshotID string, opts RestoreOptions) error {
+	args := []string{"restore", snapshotID, "--target", opts.Target}
+	for _, include := range opts.Includes {
+		args = append(args, "--include", include)
+	}
+	args = append(args, opts.Filter.args()...)
+	if e.DryRun {
+		args = append(args, "--dry-run")
+	}
+	return e.Run(args...)
+}
+
+// Dump writes a file from a snapshot to w. The data is written unmodified,
+// only restic's messages on stderr are redacted.
+func (e *Executor) Dump(snapshotID, path string, filter SnapshotFilter, w io.Writer) error {
+	args := append([]string{"dump"}, filter.args()...)
+	args = append(args, snapshotID, path)
+
+	cmd := e.command(args...)
+	cmd.Env = e.buildEnv()
+	cmd.Stdout = w
+	if e.Stderr != nil {
+		stderr := redact.NewWriter(e.Stderr)
+		defer func() { _ = stderr.Flush() }()
+		cmd.Stderr = stderr
+	}
+
+	if e.Verbose {
+		// stdout may carry the dump
+		fmt.Fprintf(os.Stderr, "$ restic %s\n", redact.String(strings.Join(args, " ")))
+	}
+
+	return describeExit(cmd.Run())
+}
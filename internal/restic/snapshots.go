@@ -2,6 +2,7 @@ package restic
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -16,6 +17,87 @@ type Snapshot struct {
 	Username string    `json:"username"`
 	Tags     []string  `json:"tags"`
 	Paths    []string  `json:"paths"`
+	Original string    `json:"original,omitempty"` // Source snapshot ID of a copy
+}
+
+// OriginID identifies the snapshot across repositories: restic copy records
+// the source snapshot as the original of the copy
+func (s Snapshot) OriginID() string {
+	if s.Original != "" {
+		return s.Original
+	}
+	return s.ID
+}
+
+// Match reports whether the snapshot passes the filter, as restic applies
+// it: the host, every path, and all the tags of one of the tag lists
+func (f SnapshotFilter) Match(s Snapshot) bool {
+	if f.Host != "" && s.Hostname != f.Host {
+		return false
+	}
+	if !f.Since.IsZero() && s.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && s.Time.After(f.Until) {
+		return false
+	}
+	for _, path := range f.Paths {
+		if !containsString(s.Paths, path) {
+			return false
+		}
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	for _, list := range f.Tags {
+		all := true
+		for _, tag := range strings.Split(list, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" && !containsString(s.Tags, tag) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the snapshots passing the filter
+func (f SnapshotFilter) Filter(snapshots []Snapshot) []Snapshot {
+	var result []Snapshot
+	for _, s := range snapshots {
+		if f.Match(s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// MissingSnapshots returns the snapshots of src with no copy in dst
+func MissingSnapshots(src, dst []Snapshot) []Snapshot {
+	present := make(map[string]bool, len(dst))
+	for _, s := range dst {
+		present[s.OriginID()] = true
+	}
+	var missing []Snapshot
+	for _, s := range src {
+		if !present[s.OriginID()] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 // Lock represents a restic lock
@@ -116,6 +198,21 @@ func (e *Executor) GetStats() (*Stats, error) {
 	return &stats, nil
 }
 
+// GetRepositorySize returns the size of the data stored in the repository
+func (e *Executor) GetRepositorySize() (int64, error) {
+	output, err := e.RunWithOutput("stats", "--mode", "raw-data", "--json")
+	if err != nil {
+		return 0, err
+	}
+
+	var stats Stats
+	if err := json.Unmarshal([]byte(output), &stats); err != nil {
+		return 0, err
+	}
+
+	return stats.TotalSize, nil
+}
+
 // GetCurrentHostname returns the current hostname
 func GetCurrentHostname() (string, error) {
 	return os.Hostname()
@@ -183,6 +280,72 @@ func (e *Executor) VerifyNoStaleLocks(currentHostname string) (*LockVerification
 	return result, nil
 }
 
+// staleLockAge is the age after which restic considers any lock stale; locks
+// in use are refreshed every 5 minutes
+const staleLockAge = 30 * time.Minute
+
+// StaleOwnLocks splits the locks that "restic unlock" removes into the locks
+// of this host whose process is gone (own), and all others (others): locks
+// older than 30 minutes from other hosts or from live processes
+func StaleOwnLocks(locks []Lock, hostname string, alive func(pid int) bool, now time.Time) (own, others []Lock) {
+	for _, lock := range locks {
+		switch {
+		case lock.Hostname == hostname && !alive(lock.PID):
+			own = append(own, lock)
+		case now.Sub(lock.Time) > staleLockAge:
+			others = append(others, lock)
+		}
+	}
+	return own, others
+}
+
+// RemoveStaleLocks removes the locks of this host whose process is no longer
+// alive and returns them. Other locks are never touched: when "restic unlock"
+// would also remove locks of other hosts, nothing is removed and an error is
+// returned.
+func (e *Executor) RemoveStaleLocks(hostname string, alive func(pid int) bool) ([]Lock, error) {
+	locks, err := e.ListLocks()
+	if err != nil {
+		return nil, err
+	}
+	own, others := StaleOwnLocks(locks, hostname, alive, time.Now())
+	if len(own) == 0 {
+		return nil, nil
+	}
+	if len(others) > 0 {
+		return nil, fmt.Errorf("not removing %d stale lock(s) of this host: restic unlock would also remove %d lock(s) of other hosts", len(own), len(others))
+	}
+
+	if err := e.Run("unlock"); err != nil {
+		return nil, err
+	}
+
+	remaining, err := e.ListLocks()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Lock
+	for _, lock := range own {
+		if !containsLock(remaining, lock) {
+			removed = append(removed, lock)
+		}
+	}
+	if len(removed) < len(own) {
+		return removed, fmt.Errorf("%d stale lock(s) of this host could not be removed", len(own)-len(removed))
+	}
+	return removed, nil
+}
+
+// containsLock reports whether locks contains lock
+func containsLock(locks []Lock, lock Lock) bool {
+	for _, l := range locks {
+		if l.Hostname == lock.Hostname && l.PID == lock.PID && l.Time.Equal(lock.Time) {
+			return true
+		}
+	}
+	return false
+}
+
 // LockVerificationResult contains the result of lock verification
 type LockVerificationResult struct {
 	CurrentHostname string
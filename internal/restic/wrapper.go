@@ -3,11 +3,17 @@ package restic
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"resticm/internal/redact"
 )
 
 // Executor handles restic command execution
@@ -17,11 +23,24 @@ type Executor struct {
 	Env        map[string]string
 	DryRun     bool
 	Verbose    bool
+	NoLock     bool // Pass --no-lock to read-only commands that support it
 	Stdout     io.Writer
 	Stderr     io.Writer
 	CacheDir   string
+	Options    []string // Extended options passed as -o (e.g. sftp.command=...)
+
+	// Bandwidth limits in KiB/s, 0 for no limit
+	LimitUpload   int
+	LimitDownload int
+
+	// isolated lists the provider prefixes of the variables set by SetEnv;
+	// inherited variables with these prefixes are not passed to restic
+	isolated []string
 }
 
+// providerPrefixes group the environment variables of each storage provider
+var providerPrefixes = []string{"AWS_", "B2_", "AZURE_", "GOOGLE_", "OS_", "ST_", "RCLONE_"}
+
 // NewExecutor creates a new restic executor
 func NewExecutor(repository, password string) *Executor {
 	return &Executor{
@@ -43,27 +62,124 @@ func (e *Executor) SetAWSCredentials(accessKeyID, secretAccessKey string) {
 	}
 }
 
+// SetEnv adds the environment of a repository (credentials, region,
+// endpoint, ...). The variables of the same providers inherited from the
+// resticm process are dropped, so an AWS_PROFILE for this repository is not
+// overridden by an AWS_ACCESS_KEY_ID exported for another one.
+func (e *Executor) SetEnv(env map[string]string) {
+	if e.Env == nil {
+		e.Env = make(map[string]string)
+	}
+	for k, v := range env {
+		e.Env[k] = v
+		if prefix := providerPrefix(k); prefix != "" && !slices.Contains(e.isolated, prefix) {
+			e.isolated = append(e.isolated, prefix)
+		}
+	}
+}
+
+// clone returns a copy of the executor whose environment can be changed
+// without affecting e
+func (e *Executor) clone() *Executor {
+	c := *e
+	c.Env = make(map[string]string, len(e.Env))
+	for k, v := range e.Env {
+		c.Env[k] = v
+	}
+	c.isolated = slices.Clone(e.isolated)
+	c.Options = slices.Clone(e.Options)
+	return &c
+}
+
+// providerPrefix returns the provider prefix of a variable name, if any
+func providerPrefix(name string) string {
+	for _, prefix := range providerPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// command builds the restic command with global options applied
+func (e *Executor) command(args ...string) *exec.Cmd {
+	return e.commandContext(context.Background(), args...)
+}
+
+// commandContext builds the restic command with global options applied.
+// When ctx is done restic is interrupted, so it removes its lock, and killed
+// if it has not exited after cancelWaitDelay.
+func (e *Executor) commandContext(ctx context.Context, args ...string) *exec.Cmd {
+	if e.NoLock {
+		args = append([]string{"--no-lock"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "restic", append(e.globalArgs(), args...)...)
+	if ctx.Done() != nil {
+		cmd.Cancel = func() error {
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				return cmd.Process.Kill()
+			}
+			return nil
+		}
+		cmd.WaitDelay = cancelWaitDelay
+	}
+	return cmd
+}
+
+// cancelWaitDelay is how long an interrupted restic may take to exit
+const cancelWaitDelay = 30 * time.Second
+
+// globalArgs returns the global restic flags of the executor: the -o
+// extended options and the bandwidth limits
+func (e *Executor) globalArgs() []string {
+	var args []string
+	for _, opt := range e.Options {
+		args = append(args, "-o", opt)
+	}
+	if e.LimitUpload > 0 {
+		args = append(args, "--limit-upload", strconv.Itoa(e.LimitUpload))
+	}
+	if e.LimitDownload > 0 {
+		args = append(args, "--limit-download", strconv.Itoa(e.LimitDownload))
+	}
+	return args
+}
+
 // Run executes a restic command
 func (e *Executor) Run(args ...string) error {
-	cmd := exec.Command("restic", args...)
+	return e.RunContext(context.Background(), args...)
+}
+
+// RunContext executes a restic command, interrupting it when ctx is done
+func (e *Executor) RunContext(ctx context.Context, args ...string) error {
+	cmd := e.commandContext(ctx, args...)
 	cmd.Env = e.buildEnv()
-	cmd.Stdout = e.Stdout
-	cmd.Stderr = e.Stderr
+	if e.Stdout != nil {
+		stdout := redact.NewWriter(e.Stdout)
+		defer func() { _ = stdout.Flush() }()
+		cmd.Stdout = stdout
+	}
+	if e.Stderr != nil {
+		stderr := redact.NewWriter(e.Stderr)
+		defer func() { _ = stderr.Flush() }()
+		cmd.Stderr = stderr
+	}
 
 	if e.Verbose {
-		fmt.Printf("$ restic %s\n", strings.Join(args, " "))
+		fmt.Printf("$ restic %s\n", redact.String(strings.Join(args, " ")))
 	}
 
-	if err := cmd.Run(); err != nil {
-		// Wrap error with restic exit code description
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			code := exitErr.ExitCode()
-			desc := GetExitCodeDescription(code)
-			return fmt.Errorf("exit status %d (%s)", code, desc)
-		}
-		return err
+	return describeExit(cmd.Run())
+}
+
+// describeExit wraps a restic exit error with the exit code description
+func describeExit(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code := exitErr.ExitCode()
+		desc := GetExitCodeDescription(code)
+		return fmt.Errorf("exit status %d (%s)", code, desc)
 	}
-	return nil
+	return err
 }
 
 // GetExitCodeDescription returns a human-readable description for restic exit codes
@@ -93,7 +209,7 @@ func GetExitCodeDescription(code int) string {
 
 // RunWithOutput executes a restic command and returns the output
 func (e *Executor) RunWithOutput(args ...string) (string, error) {
-	cmd := exec.Command("restic", args...)
+	cmd := e.command(args...)
 	cmd.Env = e.buildEnv()
 
 	var stdout, stderr bytes.Buffer
@@ -101,11 +217,11 @@ func (e *Executor) RunWithOutput(args ...string) (string, error) {
 	cmd.Stderr = &stderr
 
 	if e.Verbose {
-		fmt.Printf("$ restic %s\n", strings.Join(args, " "))
+		fmt.Printf("$ restic %s\n", redact.String(strings.Join(args, " ")))
 	}
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%w: %s", err, stderr.String())
+		return "", fmt.Errorf("%w: %s", err, redact.String(stderr.String()))
 	}
 
 	return stdout.String(), nil
@@ -113,8 +229,10 @@ func (e *Executor) RunWithOutput(args ...string) (string, error) {
 
 // RunWithStreaming executes a restic command with live output
 func (e *Executor) RunWithStreaming(args ...string) error {
-	cmd := exec.Command("restic", args...)
+	cmd := exec.Command("restic", append(e.globalArgs(), args...)...)
 	cmd.Env = e.buildEnv()
+	// Output is passed through untouched: interactive prompts without a
+	// trailing newline would otherwise be held back by line-based redaction
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
@@ -124,7 +242,14 @@ func (e *Executor) RunWithStreaming(args ...string) error {
 
 // buildEnv builds the environment for restic commands
 func (e *Executor) buildEnv() []string {
-	env := os.Environ()
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if prefix := providerPrefix(name); prefix != "" && slices.Contains(e.isolated, prefix) {
+			continue
+		}
+		env = append(env, kv)
+	}
 
 	// Add repository
 	env = append(env, "RESTIC_REPOSITORY="+e.Repository)
@@ -221,5 +346,5 @@ type ResticError struct {
 }
 
 func (e *ResticError) Error() string {
-	return fmt.Sprintf("restic %s failed (exit %d): %s", e.Command, e.ExitCode, e.Stderr)
+	return fmt.Sprintf("restic %s failed (exit %d): %s", e.Command, e.ExitCode, redact.String(e.Stderr))
 }
@@ -2,9 +2,17 @@
 package security
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"resticm/internal/redact"
 )
 
 const (
@@ -12,10 +20,25 @@ const (
 	DefaultLockFile = "/var/lock/resticm.lock"
 )
 
+// ErrLocked is returned when another instance holds the lock
+var ErrLocked = errors.New("another instance is already running")
+
+// lockPollInterval is how often AcquireWait retries a busy lock
+var lockPollInterval = time.Second
+
+// Lock scopes: which runs exclude each other
+const (
+	ScopeGlobal     = "global"     // All runs on the machine
+	ScopeConfig     = "config"     // Runs using the same config file
+	ScopeRepository = "repository" // Runs targeting the same repository
+)
+
 // Lock represents a file lock
 type Lock struct {
-	path string
-	file *os.File
+	path  string
+	file  *os.File
+	scope string
+	key   string // Config file or repository of a scoped lock
 }
 
 // NewLock creates a new lock with the given path
@@ -27,6 +50,32 @@ func NewLock(path string) *Lock {
 	return &Lock{path: path}
 }
 
+// NewScopedLock returns the lock for a scope. key is the config file path
+// (config scope) or the repository (repository scope); the lock file is named
+// after its hash in the directory of the default lock.
+func NewScopedLock(scope, key string) (*Lock, error) {
+	switch scope {
+	case "", ScopeGlobal:
+		l := NewLock("")
+		l.scope = ScopeGlobal
+		return l, nil
+	case ScopeConfig, ScopeRepository:
+		if key == "" {
+			return nil, fmt.Errorf("no %s to scope the lock to", scope)
+		}
+		sum := sha256.Sum256([]byte(key))
+		name := fmt.Sprintf("resticm-%s-%x.lock", scope, sum[:6])
+		return &Lock{path: filepath.Join(filepath.Dir(getDefaultLockPath()), name), scope: scope, key: key}, nil
+	default:
+		return nil, fmt.Errorf("invalid lock scope '%s' (use global, config or repository)", scope)
+	}
+}
+
+// Path returns the lock file path
+func (l *Lock) Path() string {
+	return l.path
+}
+
 // getDefaultLockPath returns the appropriate lock file path
 func getDefaultLockPath() string {
 	// If running as root, use system lock directory
@@ -43,6 +92,104 @@ func getDefaultLockPath() string {
 	return "/tmp/resticm.lock"
 }
 
+// AcquireWait acquires the lock, waiting for the instance holding it to
+// release it. It gives up after timeout; a zero timeout waits forever.
+// onWait, if not nil, is called once with the holder when the lock is busy.
+func (l *Lock) AcquireWait(timeout time.Duration, onWait func(Holder)) error {
+	deadline := time.Now().Add(timeout)
+	waiting := false
+	for {
+		err := l.Acquire()
+		if err == nil || !errors.Is(err, ErrLocked) {
+			return err
+		}
+		if !waiting {
+			waiting = true
+			if onWait != nil {
+				holder, _ := l.Holder()
+				onWait(holder)
+			}
+		}
+		if timeout > 0 && !time.Now().Before(deadline) {
+			if holder, herr := l.Holder(); herr == nil {
+				return fmt.Errorf("timed out after %s waiting for the lock held by %s: %w", timeout, holder, err)
+			}
+			return fmt.Errorf("timed out after %s waiting for the lock: %w", timeout, err)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Holder describes the process holding the lock
+type Holder struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command,omitempty"`
+	Started time.Time `json:"started,omitzero"`
+	Scope   string    `json:"scope,omitempty"` // Empty in lock files of older versions (global)
+	Key     string    `json:"key,omitempty"`   // Config file or repository, credentials redacted
+}
+
+// String describes the holder for messages
+func (h Holder) String() string {
+	if h.PID == 0 {
+		return "an unknown process"
+	}
+	s := fmt.Sprintf("PID %d", h.PID)
+	if h.Command != "" {
+		s += fmt.Sprintf(" (%s", h.Command)
+		if !h.Started.IsZero() {
+			s += ", started " + h.Started.Format("2006-01-02 15:04:05")
+		}
+		s += ")"
+	}
+	return s
+}
+
+// writeHolder writes the PID, command line and start time of this process
+// and the lock scope to the lock file. The PID stays on the first line for
+// older readers.
+func (l *Lock) writeHolder(file *os.File) {
+	_ = file.Truncate(0)
+	_, _ = file.Seek(0, 0)
+	args := append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...)
+	scope := l.scope
+	if scope == "" {
+		scope = ScopeGlobal
+	}
+	_, _ = fmt.Fprintf(file, "%d\ncommand=%s\nstarted=%s\nscope=%s\n",
+		os.Getpid(), strings.Join(args, " "), time.Now().Format(time.RFC3339), scope)
+	if l.key != "" {
+		_, _ = fmt.Fprintf(file, "key=%s\n", redact.String(l.key))
+	}
+}
+
+// Holder returns the process holding the lock as written in the lock file
+func (l *Lock) Holder() (Holder, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return Holder{}, err
+	}
+	var h Holder
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if h.PID, err = strconv.Atoi(strings.TrimSpace(lines[0])); err != nil {
+		return Holder{}, fmt.Errorf("invalid lock file %s: %w", l.path, err)
+	}
+	for _, line := range lines[1:] {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "command":
+			h.Command = value
+		case "started":
+			h.Started, _ = time.Parse(time.RFC3339, value)
+		case "scope":
+			h.Scope = value
+		case "key":
+			h.Key = value
+		}
+	}
+	return h, nil
+}
+
 // ForceUnlock removes a stale lock file
 func (l *Lock) ForceUnlock() error {
 	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
@@ -73,13 +220,58 @@ func (l *Lock) PrintLockInfo() {
 		return
 	}
 
-	pid, err := l.GetPID()
+	holder, err := l.Holder()
 	if err != nil {
 		fmt.Printf("Lock file exists: %s (could not read PID)\n", l.path)
 		return
 	}
 
 	fmt.Printf("⚠️  Lock file: %s\n", l.path)
-	fmt.Printf("   PID: %d\n", pid)
-	fmt.Printf("   Status: %s\n", l.getProcessStatus(pid))
+	fmt.Printf("   PID: %d\n", holder.PID)
+	if holder.Command != "" {
+		fmt.Printf("   Command: %s\n", holder.Command)
+	}
+	if !holder.Started.IsZero() {
+		fmt.Printf("   Started: %s\n", holder.Started.Format("2006-01-02 15:04:05"))
+	}
+	if holder.Key != "" {
+		fmt.Printf("   Scope: %s %s\n", holder.Scope, holder.Key)
+	}
+	fmt.Printf("   Status: %s\n", l.getProcessStatus(holder.PID))
+}
+
+// LockStatus describes a resticm lock file found on the machine
+type LockStatus struct {
+	Path   string `json:"path"`
+	Held   bool   `json:"held"` // A running process holds the lock; false for a stale file
+	Holder Holder `json:"holder"`
+}
+
+// LockDirs returns the directories holding resticm lock files: the system
+// directory and the one of the current user
+func LockDirs() []string {
+	dirs := []string{filepath.Dir(DefaultLockFile)}
+	if dir := filepath.Dir(getDefaultLockPath()); dir != dirs[0] {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// ListLocks returns the resticm lock files in dirs, sorted by path
+func ListLocks(dirs ...string) []LockStatus {
+	var locks []LockStatus
+	for _, dir := range dirs {
+		paths, _ := filepath.Glob(filepath.Join(dir, "resticm*.lock"))
+		sort.Strings(paths)
+		for _, path := range paths {
+			lock := NewLock(path)
+			status := LockStatus{Path: path, Held: lock.IsLocked()}
+			status.Holder, _ = lock.Holder()
+			if status.Holder.Scope == "" {
+				status.Holder.Scope = ScopeGlobal
+			}
+			locks = append(locks, status)
+		}
+	}
+	return locks
 }
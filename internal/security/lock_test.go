@@ -1,10 +1,13 @@
 package security
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLockAcquireRelease(t *testing.T) {
@@ -144,3 +147,115 @@ func TestLockIsLocked(t *testing.T) {
 
 	_ = lock.Release()
 }
+
+func TestLockHolder(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "test.lock")
+	lock := NewLock(lockFile)
+	if err := lock.Acquire(); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer func() { _ = lock.Release() }()
+
+	holder, err := NewLock(lockFile).Holder()
+	if err != nil {
+		t.Fatalf("Holder() error = %v", err)
+	}
+	if holder.PID != os.Getpid() || holder.Command == "" || time.Since(holder.Started) > time.Minute {
+		t.Errorf("Holder() = %+v", holder)
+	}
+	if !strings.HasPrefix(holder.String(), fmt.Sprintf("PID %d (", os.Getpid())) {
+		t.Errorf("String() = %q", holder.String())
+	}
+
+	// Lock files written by older versions only hold the PID
+	oldFile := filepath.Join(t.TempDir(), "old.lock")
+	if err := os.WriteFile(oldFile, []byte("42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if old, err := NewLock(oldFile).Holder(); err != nil || old.PID != 42 || old.String() != "PID 42" {
+		t.Errorf("Holder() = %+v, %v", old, err)
+	}
+}
+
+func TestLockAcquireWait(t *testing.T) {
+	lockPollInterval = 10 * time.Millisecond
+	defer func() { lockPollInterval = time.Second }()
+
+	lockFile := filepath.Join(t.TempDir(), "test.lock")
+	holder := NewLock(lockFile)
+	if err := holder.Acquire(); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// Times out while the lock is held
+	waiter := NewLock(lockFile)
+	err := waiter.AcquireWait(50*time.Millisecond, nil)
+	if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("AcquireWait() error = %v, want a timeout", err)
+	}
+
+	// Gets the lock once the holder releases it
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = holder.Release()
+	}()
+	var waitedFor Holder
+	if err := waiter.AcquireWait(5*time.Second, func(h Holder) { waitedFor = h }); err != nil {
+		t.Fatalf("AcquireWait() error = %v", err)
+	}
+	defer func() { _ = waiter.Release() }()
+	if waitedFor.PID != os.Getpid() {
+		t.Errorf("onWait holder = %+v", waitedFor)
+	}
+	if pid, err := waiter.GetPID(); err != nil || pid != os.Getpid() {
+		t.Errorf("GetPID() = %d, %v", pid, err)
+	}
+}
+
+func TestScopedLocks(t *testing.T) {
+	a, err := NewScopedLock(ScopeRepository, "s3:s3.amazonaws.com/bucket-a")
+	if err != nil {
+		t.Fatalf("NewScopedLock() error = %v", err)
+	}
+	b, _ := NewScopedLock(ScopeRepository, "s3:s3.amazonaws.com/bucket-b")
+	again, _ := NewScopedLock(ScopeRepository, "s3:s3.amazonaws.com/bucket-a")
+	if a.Path() == b.Path() || a.Path() != again.Path() {
+		t.Errorf("paths = %s, %s, %s: want one lock per repository", a.Path(), b.Path(), again.Path())
+	}
+	if !strings.HasPrefix(filepath.Base(a.Path()), "resticm-repository-") {
+		t.Errorf("Path() = %s", a.Path())
+	}
+	if global, _ := NewScopedLock("", ""); global.Path() != NewLock("").Path() {
+		t.Errorf("global lock = %s", global.Path())
+	}
+	if _, err := NewScopedLock("host", "x"); err == nil {
+		t.Error("expected error for an invalid scope")
+	}
+	if _, err := NewScopedLock(ScopeConfig, ""); err == nil {
+		t.Error("expected error for a config scope without config file")
+	}
+}
+
+func TestListLocks(t *testing.T) {
+	dir := t.TempDir()
+	held := &Lock{path: filepath.Join(dir, "resticm-config-1.lock"), scope: ScopeConfig, key: "/etc/resticm/a.yaml"}
+	if err := held.Acquire(); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer func() { _ = held.Release() }()
+	// A file left by a crashed run of an older version
+	if err := os.WriteFile(filepath.Join(dir, "resticm.lock"), []byte("4242\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	locks := ListLocks(dir)
+	if len(locks) != 2 {
+		t.Fatalf("ListLocks() = %+v", locks)
+	}
+	if !locks[0].Held || locks[0].Holder.Scope != ScopeConfig || locks[0].Holder.Key != "/etc/resticm/a.yaml" {
+		t.Errorf("locks[0] = %+v", locks[0])
+	}
+	if locks[1].Held || locks[1].Holder.PID != 4242 || locks[1].Holder.Scope != ScopeGlobal {
+		t.Errorf("locks[1] = %+v", locks[1])
+	}
+}
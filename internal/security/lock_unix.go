@@ -30,20 +30,37 @@ func (l *Lock) Acquire() error {
 	if err != nil {
 		_ = file.Close()
 		if err == syscall.EWOULDBLOCK {
-			return fmt.Errorf("another instance is already running (lock file: %s)", l.path)
+			return fmt.Errorf("%w (lock file: %s)", ErrLocked, l.path)
 		}
 		return fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
-	// Write PID to lock file
-	_ = file.Truncate(0)
-	_, _ = file.Seek(0, 0)
-	_, _ = fmt.Fprintf(file, "%d\n", os.Getpid())
+	// The previous holder removes the file on release; a lock on the removed
+	// file does not exclude a process creating a new one, so start over
+	if !isLockFile(file, l.path) {
+		_ = file.Close()
+		return l.Acquire()
+	}
+
+	l.writeHolder(file)
 
 	l.file = file
 	return nil
 }
 
+// isLockFile reports whether file is still the file at path
+func isLockFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
+
 // Release releases the lock
 func (l *Lock) Release() error {
 	if l.file == nil {
@@ -96,11 +113,22 @@ func (l *Lock) IsLocked() bool {
 
 // getProcessStatus checks if a process with given PID is running
 func (l *Lock) getProcessStatus(pid int) string {
-	process, err := os.FindProcess(pid)
-	if err == nil {
-		if err := process.Signal(syscall.Signal(0)); err == nil {
-			return "Process is running"
-		}
+	if ProcessAlive(pid) {
+		return "Process is running"
 	}
 	return "Process not found (stale lock)"
 }
+
+// ProcessAlive reports whether a process with the given PID is running on
+// this host
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// The same check as restic uses to find stale locks of this host
+	return process.Signal(syscall.Signal(0)) == nil
+}
@@ -38,17 +38,14 @@ func (l *Lock) Acquire() error {
 		0,
 	)
 	if err != nil {
-		return fmt.Errorf("another instance is already running (lock file: %s)", l.path)
+		return fmt.Errorf("%w (lock file: %s)", ErrLocked, l.path)
 	}
 
 	file := os.NewFile(uintptr(handle), l.path)
 
 	fmt.Printf("[DEBUG] Lock acquired successfully\n")
 
-	// Write PID to lock file
-	file.Truncate(0)
-	file.Seek(0, 0)
-	fmt.Fprintf(file, "%d\n", os.Getpid())
+	l.writeHolder(file)
 
 	l.file = file
 	return nil
@@ -94,10 +91,19 @@ func (l *Lock) IsLocked() bool {
 
 // getProcessStatus checks if a process with given PID is running (Windows)
 func (l *Lock) getProcessStatus(pid int) string {
+	if ProcessAlive(pid) {
+		return "Process is running"
+	}
+	return "Process not found (stale lock)"
+}
+
+// ProcessAlive reports whether a process with the given PID is running on
+// this host
+func ProcessAlive(pid int) bool {
 	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION, false, uint32(pid))
 	if err != nil {
-		return "Process not found (stale lock)"
+		return false
 	}
 	windows.CloseHandle(handle)
-	return "Process is running"
+	return true
 }
@@ -0,0 +1,23 @@
+package sources
+
+// Command describes a command whose standard output is backed up, such as
+// slapcat or docker save
+type Command struct {
+	Name     string
+	Argv     []string
+	Filename string   // File name inside the snapshot; empty uses Name
+	Tags     []string // Extra tags besides Tag()
+}
+
+// Tag returns the tag of the snapshots holding the command output
+func (c Command) Tag() string {
+	return CommandTagPrefix + c.Name
+}
+
+// File returns the file name of the output inside the snapshot
+func (c Command) File() string {
+	if c.Filename != "" {
+		return c.Filename
+	}
+	return c.Name
+}
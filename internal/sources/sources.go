@@ -0,0 +1,299 @@
+// Package sources describes backup sources besides directories: database
+// dumps and command output streamed into restic
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"resticm/internal/redact"
+)
+
+// Database types
+const (
+	TypePostgres = "postgres"
+	TypeMySQL    = "mysql"
+	TypeMariaDB  = "mariadb"
+	TypeSQLite   = "sqlite"
+)
+
+// Tag prefixes identifying the snapshots of a source
+const (
+	DatabaseTagPrefix = "db:"
+	CommandTagPrefix  = "cmd:"
+)
+
+// Database describes a database to dump
+type Database struct {
+	Name     string
+	Type     string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	Path     string   // Database file (sqlite)
+	Command  string   // Dump program; empty uses the default for the type
+	Options  []string // Extra arguments for the dump program
+}
+
+// Tag returns the tag of the snapshots holding the database dumps
+func (d Database) Tag() string {
+	return DatabaseTagPrefix + d.Name
+}
+
+// Filename returns the file name of the dump inside the snapshot
+func (d Database) Filename() string {
+	if d.Type == TypeSQLite {
+		return d.Name + ".sqlite3"
+	}
+	return d.Name + ".sql"
+}
+
+// DumpCommand returns the dump program
+func (d Database) DumpCommand() string {
+	if d.Command != "" {
+		return d.Command
+	}
+	switch d.Type {
+	case TypePostgres:
+		return "pg_dump"
+	case TypeMySQL:
+		return "mysqldump"
+	case TypeMariaDB:
+		return "mariadb-dump"
+	default:
+		return "sqlite3"
+	}
+}
+
+// dumpArgs returns the arguments of the dump program
+func (d Database) dumpArgs() []string {
+	var args []string
+	switch d.Type {
+	case TypePostgres:
+		// Never prompt for a password, there is no terminal to answer it
+		args = append(args, "--no-password")
+		if d.Host != "" {
+			args = append(args, "--host", d.Host)
+		}
+		if d.Port != 0 {
+			args = append(args, "--port", strconv.Itoa(d.Port))
+		}
+		if d.User != "" {
+			args = append(args, "--username", d.User)
+		}
+		args = append(args, d.Options...)
+		args = append(args, d.Database)
+	case TypeMySQL, TypeMariaDB:
+		args = append(args, "--single-transaction", "--routines", "--triggers")
+		args = append(args, mysqlConnArgs(d)...)
+		args = append(args, d.Options...)
+		args = append(args, d.Database)
+	}
+	return args
+}
+
+// mysqlConnArgs returns the connection arguments of the MySQL clients
+func mysqlConnArgs(d Database) []string {
+	var args []string
+	if d.Host != "" {
+		args = append(args, "--host", d.Host)
+	}
+	if d.Port != 0 {
+		args = append(args, "--port", strconv.Itoa(d.Port))
+	}
+	if d.User != "" {
+		args = append(args, "--user", d.User)
+	}
+	return args
+}
+
+// dumpEnv returns the environment passing the password to the dump program,
+// so it never appears in the process list
+func (d Database) dumpEnv() []string {
+	env := os.Environ()
+	if d.Password == "" {
+		return env
+	}
+	switch d.Type {
+	case TypePostgres:
+		env = append(env, "PGPASSWORD="+d.Password)
+	case TypeMySQL, TypeMariaDB:
+		env = append(env, "MYSQL_PWD="+d.Password)
+	}
+	return env
+}
+
+// Open starts dumping the database and returns the dump as a stream. Reading
+// the end of the stream returns the dump error instead of io.EOF when the
+// dump program failed. Close releases the stream and stops an unfinished dump.
+func (d Database) Open() (io.ReadCloser, error) {
+	if d.Type == TypeSQLite {
+		return d.openSQLite()
+	}
+
+	cmd := exec.Command(d.DumpCommand(), d.dumpArgs()...)
+	cmd.Env = d.dumpEnv()
+	return startDump(cmd)
+}
+
+// unsafeFileChars matches the characters of a source name not kept in a
+// temporary file name
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// openSQLite copies the database with the online backup API and streams the
+// copy. sqlite3 cannot write a backup to a pipe, so a temporary file is used.
+func (d Database) openSQLite() (io.ReadCloser, error) {
+	if _, err := os.Stat(d.Path); err != nil {
+		return nil, fmt.Errorf("database %s: %w", d.Name, err)
+	}
+
+	tmp, err := os.CreateTemp("", "resticm-"+unsafeFileChars.ReplaceAllString(d.Name, "_")+"-*.sqlite3")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+	// The path is quoted in the .backup dot-command, which has no escapes
+	if strings.Contains(tmpPath, "'") {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("temporary file path %s contains a quote", tmpPath)
+	}
+
+	args := append([]string{"-bail", "-readonly"}, d.Options...)
+	args = append(args, d.Path, ".backup '"+tmpPath+"'")
+	cmd := exec.Command(d.DumpCommand(), args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, dumpError(cmd, err, stderr.String())
+	}
+
+	f, err := os.Open(tmpPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	return &fileStream{File: f}, nil
+}
+
+// fileStream removes its temporary file when closed
+type fileStream struct {
+	*os.File
+}
+
+// Close closes and removes the file
+func (f *fileStream) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+	return err
+}
+
+// dumpStream reads the output of a running dump program
+type dumpStream struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+
+	once sync.Once
+	err  error
+}
+
+// startDump starts cmd and returns its output stream
+func startDump(cmd *exec.Cmd) (*dumpStream, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	s := &dumpStream{cmd: cmd, stdout: stdout, stderr: &bytes.Buffer{}}
+	cmd.Stderr = s.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, dumpError(cmd, err, "")
+	}
+	return s, nil
+}
+
+// Read reads dump output; at the end of the output it waits for the dump
+// program and reports its failure
+func (s *dumpStream) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if err == io.EOF {
+		if werr := s.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// Close stops the dump program if it is still running
+func (s *dumpStream) Close() error {
+	s.once.Do(func() {
+		if s.cmd.ProcessState == nil {
+			_ = s.cmd.Process.Kill()
+		}
+		s.err = s.cmd.Wait()
+	})
+	return nil
+}
+
+// wait waits for the dump program to exit
+func (s *dumpStream) wait() error {
+	s.once.Do(func() {
+		if err := s.cmd.Wait(); err != nil {
+			s.err = dumpError(s.cmd, err, s.stderr.String())
+		}
+	})
+	return s.err
+}
+
+// dumpError describes a failed dump program with the end of its stderr
+func dumpError(cmd *exec.Cmd, err error, stderr string) error {
+	stderr = strings.TrimSpace(redact.String(stderr))
+	if lines := strings.Split(stderr, "\n"); len(lines) > 5 {
+		stderr = strings.Join(lines[len(lines)-5:], "\n")
+	}
+	name := cmd.Args[0]
+	if stderr == "" {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return fmt.Errorf("%s failed: %w: %s", name, err, stderr)
+}
+
+// RestoreCommand returns the shell command loading a dump from stdin back
+// into the database, or "" for sqlite, which is restored by replacing the file
+func (d Database) RestoreCommand() string {
+	var args []string
+	switch d.Type {
+	case TypePostgres:
+		args = []string{"psql", "--single-transaction"}
+		if d.Host != "" {
+			args = append(args, "--host", d.Host)
+		}
+		if d.Port != 0 {
+			args = append(args, "--port", strconv.Itoa(d.Port))
+		}
+		if d.User != "" {
+			args = append(args, "--username", d.User)
+		}
+		args = append(args, "--dbname", d.Database)
+	case TypeMySQL, TypeMariaDB:
+		client := "mysql"
+		if d.Type == TypeMariaDB {
+			client = "mariadb"
+		}
+		args = append([]string{client}, mysqlConnArgs(d)...)
+		args = append(args, d.Database)
+	default:
+		return ""
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,140 @@
+package sources
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeBinary writes an executable shell script to dir and returns its path
+func fakeBinary(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake dump programs require a POSIX shell")
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestDumpArgs(t *testing.T) {
+	pg := Database{Name: "app", Type: TypePostgres, Host: "db", Port: 5433, User: "backup", Database: "app", Options: []string{"--no-owner"}}
+	if got, want := strings.Join(pg.dumpArgs(), " "), "--no-password --host db --port 5433 --username backup --no-owner app"; got != want {
+		t.Errorf("postgres args = %q, want %q", got, want)
+	}
+
+	my := Database{Name: "shop", Type: TypeMariaDB, User: "root", Database: "shop"}
+	if got, want := strings.Join(my.dumpArgs(), " "), "--single-transaction --routines --triggers --user root shop"; got != want {
+		t.Errorf("mariadb args = %q, want %q", got, want)
+	}
+	if my.DumpCommand() != "mariadb-dump" {
+		t.Errorf("DumpCommand() = %q", my.DumpCommand())
+	}
+	if got, want := my.RestoreCommand(), "mariadb --user root shop"; got != want {
+		t.Errorf("RestoreCommand() = %q, want %q", got, want)
+	}
+
+	if pg.Tag() != "db:app" || pg.Filename() != "app.sql" {
+		t.Errorf("Tag() = %q, Filename() = %q", pg.Tag(), pg.Filename())
+	}
+}
+
+func TestOpenStreamsDump(t *testing.T) {
+	dir := t.TempDir()
+	dump := fakeBinary(t, dir, "pg_dump", `echo "-- dump of $PGPASSWORD"
+echo "CREATE TABLE t ();"
+`)
+
+	db := Database{Name: "app", Type: TypePostgres, Database: "app", Password: "s3cret", Command: dump}
+	stream, err := db.Open()
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if want := "-- dump of s3cret\nCREATE TABLE t ();\n"; string(data) != want {
+		t.Errorf("dump = %q, want %q", data, want)
+	}
+}
+
+func TestOpenReportsDumpFailure(t *testing.T) {
+	dir := t.TempDir()
+	dump := fakeBinary(t, dir, "mysqldump", `echo "partial"
+echo "mysqldump: Got error: 1045: Access denied" >&2
+exit 2
+`)
+
+	db := Database{Name: "shop", Type: TypeMySQL, Database: "shop", Command: dump}
+	stream, err := db.Open()
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer stream.Close()
+
+	_, err = io.ReadAll(stream)
+	if err == nil {
+		t.Fatal("expected the dump failure at the end of the stream")
+	}
+	if !strings.Contains(err.Error(), "Access denied") {
+		t.Errorf("error = %v, want the dump stderr", err)
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	dir := t.TempDir()
+	// The last argument is ".backup '<file>'"
+	sqlite := fakeBinary(t, dir, "sqlite3", `for last; do :; done
+dest=$(echo "$last" | sed "s/^.backup '\(.*\)'$/\1/")
+echo "SQLite format 3" > "$dest"
+`)
+	dbPath := filepath.Join(dir, "blog.db")
+	if err := os.WriteFile(dbPath, []byte("db"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	db := Database{Name: "blog", Type: TypeSQLite, Path: dbPath, Command: sqlite}
+	stream, err := db.Open()
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(data) != "SQLite format 3\n" {
+		t.Errorf("copy = %q", data)
+	}
+
+	tmp := stream.(*fileStream).Name()
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temporary copy %s not removed", tmp)
+	}
+
+	// Quotes and slashes of the name stay out of the temporary file name
+	odd := Database{Name: "it's/blog", Type: TypeSQLite, Path: dbPath, Command: sqlite}
+	stream, err = odd.Open()
+	if err != nil {
+		t.Fatalf("Open() of %q error = %v", odd.Name, err)
+	}
+	if name := filepath.Base(stream.(*fileStream).Name()); !strings.HasPrefix(name, "resticm-it_s_blog-") {
+		t.Errorf("temporary file = %s", name)
+	}
+	_ = stream.Close()
+
+	missing := Database{Name: "gone", Type: TypeSQLite, Path: filepath.Join(dir, "missing.db"), Command: sqlite}
+	if _, err := missing.Open(); err == nil {
+		t.Error("expected an error for a missing database file")
+	}
+}
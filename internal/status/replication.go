@@ -0,0 +1,203 @@
+package status
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Snapshot is the part of a restic snapshot compared across repositories
+type Snapshot struct {
+	ID       string
+	Original string // ID of the snapshot it was copied from, if any
+	Hostname string
+	Time     time.Time
+}
+
+// originID identifies a snapshot across copies: restic copy records the
+// source ID as the original of the new snapshot
+func (s Snapshot) originID() string {
+	if s.Original != "" {
+		return s.Original
+	}
+	return s.ID
+}
+
+// ReplicaHost is the replication state of the snapshots of one host
+type ReplicaHost struct {
+	Hostname   string `json:"hostname"`
+	Snapshots  int    `json:"snapshots"` // Snapshots of the host on the primary
+	Replicated int    `json:"replicated"`
+
+	// Missing lists the short IDs of the primary snapshots not on the backend
+	Missing []string `json:"missing"`
+
+	PrimaryLatest    time.Time `json:"primary_latest"`
+	ReplicatedLatest time.Time `json:"replicated_latest,omitempty"`
+
+	// Pending counts the snapshots newer than the newest replicated one, and
+	// Lag is how long the oldest of them has been waiting
+	Pending int           `json:"pending"`
+	Lag     time.Duration `json:"lag_ns"`
+}
+
+// Replica is the replication state of one copy backend
+type Replica struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository"`
+
+	// Error is set when the backend could not be queried
+	Error string `json:"error,omitempty"`
+
+	Hosts  []ReplicaHost `json:"hosts"`
+	Checks []Check       `json:"checks"`
+	State  State         `json:"state"`
+}
+
+// ReplicationReport compares every copy backend with the primary repository
+type ReplicationReport struct {
+	Generated time.Time `json:"generated"`
+
+	// PrimaryError is set when the primary could not be queried
+	PrimaryError string     `json:"primary_error,omitempty"`
+	Backends     []*Replica `json:"backends"`
+	State        State      `json:"state"`
+}
+
+// CompareReplica matches the snapshots of a backend with those of the
+// primary by their original ID and returns the state of each primary host
+func CompareReplica(primary, replica []Snapshot, now time.Time) []ReplicaHost {
+	copied := make(map[string]bool, len(replica))
+	for _, s := range replica {
+		copied[s.originID()] = true
+	}
+
+	byHost := make(map[string][]Snapshot)
+	for _, s := range primary {
+		byHost[s.Hostname] = append(byHost[s.Hostname], s)
+	}
+
+	hosts := make([]ReplicaHost, 0, len(byHost))
+	for hostname, snapshots := range byHost {
+		sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+		h := ReplicaHost{
+			Hostname:      hostname,
+			Snapshots:     len(snapshots),
+			Missing:       []string{},
+			PrimaryLatest: snapshots[len(snapshots)-1].Time,
+		}
+		for _, s := range snapshots {
+			if copied[s.originID()] {
+				h.Replicated++
+				h.ReplicatedLatest = s.Time
+			} else {
+				h.Missing = append(h.Missing, shortID(s.ID))
+			}
+		}
+		// Snapshots older than the newest replicated one were forgotten or
+		// skipped on purpose; only newer ones are still to be copied
+		for _, s := range snapshots {
+			if copied[s.originID()] || !s.Time.After(h.ReplicatedLatest) {
+				continue
+			}
+			if h.Pending == 0 {
+				h.Lag = now.Sub(s.Time)
+			}
+			h.Pending++
+		}
+		hosts = append(hosts, h)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+	return hosts
+}
+
+// Evaluate compares the lag of each host against the thresholds
+func (r *Replica) Evaluate(t Thresholds) {
+	r.Checks = nil
+
+	if r.Error != "" {
+		r.add("repository", Crit, fmt.Sprintf("repository not accessible: %s", r.Error))
+		r.State = Crit
+		return
+	}
+
+	states := []State{OK}
+	for _, h := range r.Hosts {
+		var check Check
+		switch {
+		case h.Pending > 0 && h.Replicated == 0:
+			check = Check{State: ageState(h.Lag, t.ReplicationLagWarn, t.ReplicationLagCrit),
+				Message: fmt.Sprintf("%s: none of %d snapshot(s) replicated, oldest %s old", h.Hostname, h.Snapshots, formatAge(h.Lag))}
+		case h.Pending > 0:
+			check = Check{State: ageState(h.Lag, t.ReplicationLagWarn, t.ReplicationLagCrit),
+				Message: fmt.Sprintf("%s: %d snapshot(s) pending, lagging %s", h.Hostname, h.Pending, formatAge(h.Lag))}
+		case len(h.Missing) > 0:
+			check = Check{State: OK,
+				Message: fmt.Sprintf("%s: up to date, %d older snapshot(s) not replicated", h.Hostname, len(h.Missing))}
+		default:
+			check = Check{State: OK,
+				Message: fmt.Sprintf("%s: all %d snapshot(s) replicated", h.Hostname, h.Snapshots)}
+		}
+		check.Name = "replication_lag:" + h.Hostname
+		r.Checks = append(r.Checks, check)
+		states = append(states, check.State)
+	}
+	r.State = Worst(states...)
+}
+
+// add appends a check result
+func (r *Replica) add(name string, state State, message string) {
+	r.Checks = append(r.Checks, Check{Name: name, State: state, Message: message})
+}
+
+// Evaluate evaluates every backend and sets the overall state. A primary that
+// cannot be queried leaves nothing to compare and is UNKNOWN.
+func (r *ReplicationReport) Evaluate(t Thresholds, now time.Time) {
+	r.Generated = now
+	if r.PrimaryError != "" {
+		r.State = Unknown
+		return
+	}
+	states := make([]State, 0, len(r.Backends))
+	for _, b := range r.Backends {
+		b.Evaluate(t)
+		states = append(states, b.State)
+	}
+	if len(r.Backends) == 0 {
+		states = append(states, Unknown)
+	}
+	r.State = Worst(states...)
+}
+
+// Summary returns a one-line description of the problems found, worst first
+func (r *ReplicationReport) Summary() string {
+	if r.PrimaryError != "" {
+		return fmt.Sprintf("primary not accessible: %s", r.PrimaryError)
+	}
+	var problems []string
+	for _, want := range []State{Crit, Unknown, Warn} {
+		for _, b := range r.Backends {
+			for _, c := range b.Checks {
+				if c.State == want {
+					problems = append(problems, fmt.Sprintf("%s: %s", b.Name, c.Message))
+				}
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return fmt.Sprintf("%d backend(s) in sync", len(r.Backends))
+	}
+	summary := problems[0]
+	if len(problems) > 1 {
+		summary += fmt.Sprintf(" (+%d more)", len(problems)-1)
+	}
+	return summary
+}
+
+// shortID returns the 8 character form of a snapshot ID
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,272 @@
+// Package status evaluates repository health against thresholds
+package status
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// State is a health state using the Nagios/Icinga plugin convention
+type State int
+
+const (
+	OK State = iota
+	Warn
+	Crit
+	Unknown
+)
+
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warn:
+		return "WARN"
+	case Crit:
+		return "CRIT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ExitCode returns the plugin exit code for the state
+func (s State) ExitCode() int {
+	return int(s)
+}
+
+// MarshalText encodes the state as its name
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Worst returns the most severe state. CRIT outranks UNKNOWN, which outranks WARN.
+func Worst(states ...State) State {
+	rank := map[State]int{OK: 0, Warn: 1, Unknown: 2, Crit: 3}
+	worst := OK
+	for _, s := range states {
+		if rank[s] > rank[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
+
+// Thresholds configures when a value turns WARN or CRIT; zero disables a threshold
+type Thresholds struct {
+	SnapshotAgeWarn  time.Duration
+	SnapshotAgeCrit  time.Duration
+	LastRunAgeWarn   time.Duration
+	LastRunAgeCrit   time.Duration
+	DeepCheckAgeWarn time.Duration
+	DeepCheckAgeCrit time.Duration
+	LockAgeWarn      time.Duration
+
+	// Age of the oldest snapshot not yet copied to a backend
+	ReplicationLagWarn time.Duration
+	ReplicationLagCrit time.Duration
+}
+
+// DefaultThresholds returns thresholds suited to daily backups
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		SnapshotAgeWarn: 26 * time.Hour,
+		SnapshotAgeCrit: 50 * time.Hour,
+		LastRunAgeWarn:  26 * time.Hour,
+		LastRunAgeCrit:  50 * time.Hour,
+		LockAgeWarn:     2 * time.Hour,
+
+		ReplicationLagWarn: 26 * time.Hour,
+		ReplicationLagCrit: 50 * time.Hour,
+	}
+}
+
+// HostSnapshot is the latest snapshot of a single host
+type HostSnapshot struct {
+	Hostname string        `json:"hostname"`
+	Latest   time.Time     `json:"latest"`
+	Age      time.Duration `json:"age_ns"`
+	Count    int           `json:"count"`
+}
+
+// Lock describes a repository lock
+type Lock struct {
+	Hostname string        `json:"hostname"`
+	PID      int           `json:"pid"`
+	Age      time.Duration `json:"age_ns"`
+}
+
+// Check is the result of a single health check
+type Check struct {
+	Name    string `json:"name"`
+	State   State  `json:"state"`
+	Message string `json:"message"`
+}
+
+// BackendStatus holds the facts collected for one repository and their evaluation
+type BackendStatus struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository"`
+
+	// Error is set when the repository could not be queried
+	Error string `json:"error,omitempty"`
+
+	Hosts         []HostSnapshot `json:"hosts"`
+	SnapshotCount int            `json:"snapshot_count"`
+
+	// HistoryEnabled reports whether run history is available for LastRun
+	HistoryEnabled bool      `json:"-"`
+	LastRun        time.Time `json:"last_successful_run,omitzero"`
+	LastRunID      string    `json:"last_successful_run_id,omitempty"`
+
+	// DeepCheckEnabled reports whether periodic deep checks are configured
+	DeepCheckEnabled bool      `json:"-"`
+	LastDeepCheck    time.Time `json:"last_deep_check,omitzero"`
+
+	Locks     []Lock `json:"locks"`
+	SizeKnown bool   `json:"-"`
+	Size      int64  `json:"size_bytes,omitempty"`
+
+	Checks []Check `json:"checks"`
+	State  State   `json:"state"`
+}
+
+// Report is the overall health of every repository
+type Report struct {
+	Generated time.Time        `json:"generated"`
+	Backends  []*BackendStatus `json:"backends"`
+	State     State            `json:"state"`
+}
+
+// Evaluate runs every check on the collected facts and sets the backend state
+func (b *BackendStatus) Evaluate(t Thresholds, now time.Time) {
+	b.Checks = nil
+
+	if b.Error != "" {
+		b.add("repository", Crit, fmt.Sprintf("repository not accessible: %s", b.Error))
+		b.State = Crit
+		return
+	}
+
+	// Snapshot age per host
+	if len(b.Hosts) == 0 {
+		b.add("snapshots", Crit, "no snapshots found")
+	}
+	sort.Slice(b.Hosts, func(i, j int) bool { return b.Hosts[i].Hostname < b.Hosts[j].Hostname })
+	for i := range b.Hosts {
+		h := &b.Hosts[i]
+		h.Age = now.Sub(h.Latest)
+		state := ageState(h.Age, t.SnapshotAgeWarn, t.SnapshotAgeCrit)
+		b.add("snapshot_age:"+h.Hostname, state, fmt.Sprintf("latest snapshot of %s is %s old", h.Hostname, formatAge(h.Age)))
+	}
+
+	// Last successful run recorded in history
+	if b.HistoryEnabled {
+		if b.LastRun.IsZero() {
+			b.add("last_run", Warn, "no successful run recorded in history")
+		} else {
+			age := now.Sub(b.LastRun)
+			b.add("last_run", ageState(age, t.LastRunAgeWarn, t.LastRunAgeCrit),
+				fmt.Sprintf("last successful run %s ago", formatAge(age)))
+		}
+	}
+
+	// Last deep check
+	if b.DeepCheckEnabled {
+		if b.LastDeepCheck.IsZero() {
+			b.add("deep_check", Warn, "no deep check recorded")
+		} else {
+			age := now.Sub(b.LastDeepCheck)
+			b.add("deep_check", ageState(age, t.DeepCheckAgeWarn, t.DeepCheckAgeCrit),
+				fmt.Sprintf("last deep check %s ago", formatAge(age)))
+		}
+	}
+
+	// Locks older than the threshold are probably stale
+	stale := 0
+	for _, l := range b.Locks {
+		if t.LockAgeWarn > 0 && l.Age > t.LockAgeWarn {
+			stale++
+		}
+	}
+	switch {
+	case stale > 0:
+		b.add("locks", Warn, fmt.Sprintf("%d lock(s) older than %s", stale, formatAge(t.LockAgeWarn)))
+	case len(b.Locks) > 0:
+		b.add("locks", OK, fmt.Sprintf("%d active lock(s)", len(b.Locks)))
+	default:
+		b.add("locks", OK, "no locks")
+	}
+
+	states := make([]State, 0, len(b.Checks))
+	for _, c := range b.Checks {
+		states = append(states, c.State)
+	}
+	b.State = Worst(states...)
+}
+
+// add appends a check result
+func (b *BackendStatus) add(name string, state State, message string) {
+	b.Checks = append(b.Checks, Check{Name: name, State: state, Message: message})
+}
+
+// Evaluate evaluates every backend and sets the overall state
+func (r *Report) Evaluate(t Thresholds, now time.Time) {
+	r.Generated = now
+	states := make([]State, 0, len(r.Backends))
+	for _, b := range r.Backends {
+		b.Evaluate(t, now)
+		states = append(states, b.State)
+	}
+	if len(r.Backends) == 0 {
+		states = append(states, Unknown)
+	}
+	r.State = Worst(states...)
+}
+
+// Summary returns a one-line description of the problems found, worst first
+func (r *Report) Summary() string {
+	var problems []string
+	for _, want := range []State{Crit, Unknown, Warn} {
+		for _, b := range r.Backends {
+			for _, c := range b.Checks {
+				if c.State == want {
+					problems = append(problems, fmt.Sprintf("%s: %s", b.Name, c.Message))
+				}
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return fmt.Sprintf("%d repositor(y/ies) healthy", len(r.Backends))
+	}
+	summary := problems[0]
+	if len(problems) > 1 {
+		summary += fmt.Sprintf(" (+%d more)", len(problems)-1)
+	}
+	return summary
+}
+
+// ageState compares an age against WARN and CRIT thresholds
+func ageState(age, warn, crit time.Duration) State {
+	switch {
+	case crit > 0 && age > crit:
+		return Crit
+	case warn > 0 && age > warn:
+		return Warn
+	default:
+		return OK
+	}
+}
+
+// formatAge formats an age as days and hours, or minutes for short durations
+func formatAge(d time.Duration) string {
+	if d < time.Hour {
+		return d.Round(time.Minute).String()
+	}
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh", days, hours)
+	}
+	return fmt.Sprintf("%dh", hours)
+}
@@ -0,0 +1,254 @@
+package status
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorst(t *testing.T) {
+	tests := []struct {
+		states []State
+		want   State
+	}{
+		{nil, OK},
+		{[]State{OK, OK}, OK},
+		{[]State{OK, Warn}, Warn},
+		{[]State{Warn, Unknown}, Unknown},
+		{[]State{Unknown, Crit, Warn}, Crit},
+	}
+	for _, tt := range tests {
+		if got := Worst(tt.states...); got != tt.want {
+			t.Errorf("Worst(%v) = %s, want %s", tt.states, got, tt.want)
+		}
+	}
+}
+
+func TestStateExitCode(t *testing.T) {
+	for state, code := range map[State]int{OK: 0, Warn: 1, Crit: 2, Unknown: 3} {
+		if got := state.ExitCode(); got != code {
+			t.Errorf("%s.ExitCode() = %d, want %d", state, got, code)
+		}
+	}
+}
+
+func TestBackendEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	thresholds := DefaultThresholds()
+	thresholds.DeepCheckAgeWarn = 37 * 24 * time.Hour
+	thresholds.DeepCheckAgeCrit = 60 * 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		backend BackendStatus
+		want    State
+	}{
+		{
+			name: "healthy",
+			backend: BackendStatus{
+				Hosts:            []HostSnapshot{{Hostname: "web1", Latest: now.Add(-3 * time.Hour)}},
+				HistoryEnabled:   true,
+				LastRun:          now.Add(-3 * time.Hour),
+				DeepCheckEnabled: true,
+				LastDeepCheck:    now.Add(-10 * 24 * time.Hour),
+			},
+			want: OK,
+		},
+		{
+			name: "stale snapshot",
+			backend: BackendStatus{
+				Hosts: []HostSnapshot{
+					{Hostname: "web1", Latest: now.Add(-3 * time.Hour)},
+					{Hostname: "web2", Latest: now.Add(-30 * time.Hour)},
+				},
+			},
+			want: Warn,
+		},
+		{
+			name: "very old snapshot",
+			backend: BackendStatus{
+				Hosts: []HostSnapshot{{Hostname: "web1", Latest: now.Add(-72 * time.Hour)}},
+			},
+			want: Crit,
+		},
+		{
+			name:    "no snapshots",
+			backend: BackendStatus{},
+			want:    Crit,
+		},
+		{
+			name:    "inaccessible",
+			backend: BackendStatus{Error: "connection refused"},
+			want:    Crit,
+		},
+		{
+			name: "missing history",
+			backend: BackendStatus{
+				Hosts:          []HostSnapshot{{Hostname: "web1", Latest: now.Add(-time.Hour)}},
+				HistoryEnabled: true,
+			},
+			want: Warn,
+		},
+		{
+			name: "overdue deep check",
+			backend: BackendStatus{
+				Hosts:            []HostSnapshot{{Hostname: "web1", Latest: now.Add(-time.Hour)}},
+				DeepCheckEnabled: true,
+				LastDeepCheck:    now.Add(-90 * 24 * time.Hour),
+			},
+			want: Crit,
+		},
+		{
+			name: "stale lock",
+			backend: BackendStatus{
+				Hosts: []HostSnapshot{{Hostname: "web1", Latest: now.Add(-time.Hour)}},
+				Locks: []Lock{{Hostname: "web1", PID: 42, Age: 5 * time.Hour}},
+			},
+			want: Warn,
+		},
+		{
+			name: "fresh lock",
+			backend: BackendStatus{
+				Hosts: []HostSnapshot{{Hostname: "web1", Latest: now.Add(-time.Hour)}},
+				Locks: []Lock{{Hostname: "web1", PID: 42, Age: 10 * time.Minute}},
+			},
+			want: OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backend
+			b.Evaluate(thresholds, now)
+			if b.State != tt.want {
+				t.Errorf("State = %s, want %s (checks: %+v)", b.State, tt.want, b.Checks)
+			}
+		})
+	}
+}
+
+func TestReportEvaluate(t *testing.T) {
+	now := time.Now()
+	report := &Report{Backends: []*BackendStatus{
+		{Name: "primary", Hosts: []HostSnapshot{{Hostname: "web1", Latest: now.Add(-time.Hour)}}},
+		{Name: "offsite", Hosts: []HostSnapshot{{Hostname: "web1", Latest: now.Add(-30 * time.Hour)}}},
+	}}
+	report.Evaluate(DefaultThresholds(), now)
+
+	if report.State != Warn {
+		t.Errorf("State = %s, want WARN", report.State)
+	}
+	if !strings.HasPrefix(report.Summary(), "offsite: latest snapshot of web1") {
+		t.Errorf("Summary() = %q", report.Summary())
+	}
+
+	empty := &Report{}
+	empty.Evaluate(DefaultThresholds(), now)
+	if empty.State != Unknown {
+		t.Errorf("empty report State = %s, want UNKNOWN", empty.State)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	report := &Report{Backends: []*BackendStatus{{Name: "primary", Error: "boom"}}}
+	report.Evaluate(DefaultThresholds(), time.Now())
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"state":"CRIT"`) {
+		t.Errorf("JSON does not contain state name: %s", data)
+	}
+	for _, key := range []string{"last_successful_run", "last_deep_check"} {
+		if strings.Contains(string(data), `"`+key+`"`) {
+			t.Errorf("JSON contains the unset %s: %s", key, data)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * time.Minute: "30m0s",
+		5 * time.Hour:    "5h",
+		50 * time.Hour:   "2d2h",
+	}
+	for d, want := range tests {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestCompareReplica(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	primary := []Snapshot{
+		{ID: "aaaaaaaa1111", Hostname: "web1", Time: now.Add(-4 * day)},
+		{ID: "bbbbbbbb2222", Hostname: "web1", Time: now.Add(-3 * day)},
+		{ID: "cccccccc3333", Hostname: "web1", Time: now.Add(-2 * day)},
+		{ID: "dddddddd4444", Hostname: "web1", Time: now.Add(-1 * day)},
+		{ID: "eeeeeeee5555", Hostname: "db1", Time: now.Add(-time.Hour)},
+		// Copied to the primary from elsewhere: matched by its original
+		{ID: "ffffffff6666", Original: "0000000099", Hostname: "db1", Time: now.Add(-2 * time.Hour)},
+	}
+	replica := []Snapshot{
+		{ID: "r1", Original: "bbbbbbbb2222", Hostname: "web1"},
+		{ID: "r2", Original: "0000000099", Hostname: "db1"},
+		{ID: "r3", Original: "eeeeeeee5555", Hostname: "db1"},
+		{ID: "r4", Original: "not-on-primary", Hostname: "old"},
+	}
+
+	hosts := CompareReplica(primary, replica, now)
+	if len(hosts) != 2 || hosts[0].Hostname != "db1" || hosts[1].Hostname != "web1" {
+		t.Fatalf("hosts = %+v", hosts)
+	}
+
+	db1 := hosts[0]
+	if db1.Replicated != 2 || db1.Pending != 0 || db1.Lag != 0 || len(db1.Missing) != 0 {
+		t.Errorf("db1 = %+v, want fully replicated", db1)
+	}
+
+	// aaaa is older than the newest replicated snapshot: missing, not pending
+	web1 := hosts[1]
+	if web1.Replicated != 1 || web1.Pending != 2 || web1.Lag != 2*day {
+		t.Errorf("web1 = %+v, want 2 pending lagging 2 days", web1)
+	}
+	if strings.Join(web1.Missing, ",") != "aaaaaaaa,cccccccc,dddddddd" {
+		t.Errorf("Missing = %v", web1.Missing)
+	}
+	if !web1.ReplicatedLatest.Equal(now.Add(-3 * day)) {
+		t.Errorf("ReplicatedLatest = %s", web1.ReplicatedLatest)
+	}
+
+	// Nothing replicated: the lag is the age of the oldest snapshot
+	hosts = CompareReplica(primary[:2], nil, now)
+	if hosts[0].Pending != 2 || hosts[0].Lag != 4*day {
+		t.Errorf("empty replica = %+v", hosts[0])
+	}
+}
+
+func TestReplicationReportEvaluate(t *testing.T) {
+	now := time.Now()
+	report := &ReplicationReport{Backends: []*Replica{
+		{Name: "offsite1", Hosts: []ReplicaHost{{Hostname: "web1", Snapshots: 3, Replicated: 3}}},
+		{Name: "offsite2", Hosts: []ReplicaHost{{Hostname: "web1", Snapshots: 3, Replicated: 2, Pending: 1, Lag: 30 * time.Hour}}},
+		{Name: "offsite3", Error: "boom"},
+	}}
+	report.Evaluate(DefaultThresholds(), now)
+
+	states := []State{report.Backends[0].State, report.Backends[1].State, report.Backends[2].State}
+	if states[0] != OK || states[1] != Warn || states[2] != Crit || report.State != Crit {
+		t.Errorf("states = %v, overall %s", states, report.State)
+	}
+	if !strings.HasPrefix(report.Summary(), "offsite3: repository not accessible") {
+		t.Errorf("Summary() = %q", report.Summary())
+	}
+
+	failed := &ReplicationReport{PrimaryError: "wrong password"}
+	failed.Evaluate(DefaultThresholds(), now)
+	if failed.State != Unknown {
+		t.Errorf("State = %s, want UNKNOWN without primary", failed.State)
+	}
+}